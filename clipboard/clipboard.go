@@ -0,0 +1,129 @@
+// Package clipboard copies text to and from the system clipboard, which
+// backs the + and * registers. The clipboard is reached through a Provider:
+// an external command such as xclip or pbcopy, or OSC 52 escape sequences,
+// which reach the clipboard of the terminal fuji is running in, even over
+// SSH.
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Selection is the clipboard which text is copied to, or pasted from
+type Selection uint8
+
+const (
+	// Clipboard is the clipboard used by copy and paste, the + register
+	Clipboard Selection = iota
+	// Primary is the X11 primary selection, the text most recently selected,
+	// which is the * register. Providers without a primary selection use
+	// the clipboard.
+	Primary
+)
+
+// Provider copies text to and from a system clipboard
+type Provider interface {
+	Copy(sel Selection, s string) error
+	Paste(sel Selection) (string, error)
+}
+
+var (
+	mu        sync.Mutex
+	providers = make(map[string]Provider)
+	// detected is the provider chosen by "auto", once it has been detected
+	detected Provider
+)
+
+// Add adds a new provider, which can be chosen by name
+func Add(name string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[name] = p
+}
+
+// Names returns the names of the providers which can be chosen, including
+// "auto", which detects the best provider to use
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := []string{"auto"}
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// Lookup returns the provider called name. "auto" returns the provider
+// detected for the environment fuji is running in.
+func Lookup(name string) (Provider, error) {
+	if name == "auto" {
+		return Detect(), nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown clipboard provider: %s", name)
+	}
+	return p, nil
+}
+
+// Detect returns the best provider for the environment fuji is running in.
+// Over SSH, only OSC 52 reaches the clipboard of the user's own machine.
+// Otherwise, a command for the platform's clipboard is used if one is
+// installed.
+func Detect() Provider {
+	mu.Lock()
+	defer mu.Unlock()
+	if detected == nil {
+		detected = providers[detect()]
+	}
+	return detected
+}
+
+// detect returns the name of the best provider
+func detect() string {
+	installed := func(command string) bool {
+		_, err := exec.LookPath(command)
+		return err == nil
+	}
+	switch {
+	case os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "":
+		return "osc52"
+	case runtime.GOOS == "darwin" && installed("pbcopy"):
+		return "pbcopy"
+	case os.Getenv("WAYLAND_DISPLAY") != "" && installed("wl-copy"):
+		return "wl-copy"
+	case os.Getenv("DISPLAY") != "" && installed("xclip"):
+		return "xclip"
+	case os.Getenv("DISPLAY") != "" && installed("xsel"):
+		return "xsel"
+	}
+	return "osc52"
+}
+
+func init() {
+	Add("osc52", &OSC52{Writer: os.Stdout})
+	Add("xclip", &Command{
+		CopyArgs:  [2][]string{{"xclip", "-i", "-selection", "clipboard"}, {"xclip", "-i", "-selection", "primary"}},
+		PasteArgs: [2][]string{{"xclip", "-o", "-selection", "clipboard"}, {"xclip", "-o", "-selection", "primary"}},
+	})
+	Add("xsel", &Command{
+		CopyArgs:  [2][]string{{"xsel", "-i", "-b"}, {"xsel", "-i", "-p"}},
+		PasteArgs: [2][]string{{"xsel", "-o", "-b"}, {"xsel", "-o", "-p"}},
+	})
+	Add("wl-copy", &Command{
+		CopyArgs:  [2][]string{{"wl-copy"}, {"wl-copy", "--primary"}},
+		PasteArgs: [2][]string{{"wl-paste", "--no-newline"}, {"wl-paste", "--no-newline", "--primary"}},
+	})
+	Add("pbcopy", &Command{
+		CopyArgs:  [2][]string{{"pbcopy"}, {"pbcopy"}},
+		PasteArgs: [2][]string{{"pbpaste"}, {"pbpaste"}},
+	})
+}
@@ -0,0 +1,40 @@
+package clipboard
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSC52(t *testing.T) {
+	defer os.Setenv("TMUX", os.Getenv("TMUX"))
+	os.Setenv("TMUX", "")
+	var out bytes.Buffer
+	o := &OSC52{Writer: &out}
+	assert.NoError(t, o.Copy(Clipboard, "hello"))
+	assert.Equal(t, "\x1b]52;c;aGVsbG8=\a", out.String())
+	s, err := o.Paste(Clipboard)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", s)
+
+	out.Reset()
+	os.Setenv("TMUX", "/tmp/tmux")
+	assert.NoError(t, o.Copy(Primary, "hi"))
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;p;aGk=\a\x1b\\", out.String())
+}
+
+func TestLookup(t *testing.T) {
+	Add("fake", &Fake{})
+	p, err := Lookup("fake")
+	assert.NoError(t, err)
+	assert.IsType(t, &Fake{}, p)
+	assert.Contains(t, Names(), "xclip")
+	assert.Equal(t, "auto", Names()[0])
+	_, err = Lookup("nope")
+	assert.Error(t, err)
+	p, err = Lookup("auto")
+	assert.NoError(t, err)
+	assert.NotNil(t, p)
+}
@@ -0,0 +1,100 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Command is a provider which runs external commands, such as xclip, to copy
+// and paste. The commands are given for each Selection. Copy commands read
+// the text from stdin, and paste commands write it to stdout.
+type Command struct {
+	CopyArgs, PasteArgs [2][]string
+}
+
+// Copy copies s to the clipboard sel
+func (c *Command) Copy(sel Selection, s string) error {
+	args := c.CopyArgs[sel]
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(s)
+	// Output isn't read, since xclip and xsel stay running in the
+	// background to serve the text, with their output open
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	return nil
+}
+
+// Paste returns the text in the clipboard sel
+func (c *Command) Paste(sel Selection) (string, error) {
+	args := c.PasteArgs[sel]
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", args[0], err)
+	}
+	return string(out), nil
+}
+
+// OSC52 is a provider which copies text to the clipboard of the terminal,
+// using the OSC 52 escape sequence, which most terminals support, and which
+// works over SSH. tcell can't send escape sequences of its own, so they are
+// written to the terminal tcell draws on, like the bracketed paste markers.
+// Terminals don't let programs read their clipboard, so Paste returns the
+// last text copied.
+type OSC52 struct {
+	Writer io.Writer
+
+	mu     sync.Mutex
+	copied [2]string
+}
+
+// Copy copies s to the clipboard sel
+func (o *OSC52) Copy(sel Selection, s string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.copied[sel] = s
+	target := "c"
+	if sel == Primary {
+		target = "p"
+	}
+	seq := fmt.Sprintf("\x1b]52;%s;%s\a", target, base64.StdEncoding.EncodeToString([]byte(s)))
+	if os.Getenv("TMUX") != "" {
+		// tmux passes sequences wrapped like this on to the terminal
+		seq = "\x1bPtmux;" + strings.Replace(seq, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+	}
+	_, err := io.WriteString(o.Writer, seq)
+	return err
+}
+
+// Paste returns the last text copied to the clipboard sel
+func (o *OSC52) Paste(sel Selection) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.copied[sel], nil
+}
+
+// Fake is a provider which keeps the clipboard in memory, for tests
+type Fake struct {
+	Text [2]string
+	// Err, if set, is returned by Copy and Paste
+	Err error
+}
+
+// Copy copies s to the clipboard sel
+func (f *Fake) Copy(sel Selection, s string) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Text[sel] = s
+	return nil
+}
+
+// Paste returns the text in the clipboard sel
+func (f *Fake) Paste(sel Selection) (string, error) {
+	return f.Text[sel], f.Err
+}
@@ -2,28 +2,164 @@ package main
 
 import (
 	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
 	"github.com/jamesroutley/fuji/commands"
 	"github.com/jamesroutley/fuji/editarea"
 )
 
 func registerNormalModeCommands() {
-	editarea.AddNormalModeCommand("j", commands.MoveCursorDown)
-	editarea.AddNormalModeCommand("k", commands.MoveCursorUp)
-	editarea.AddNormalModeCommand("h", commands.MoveCursorLeft)
-	editarea.AddNormalModeCommand("l", commands.MoveCursorRight)
 	editarea.AddNormalModeCommand("Q", commands.Quit)
 	editarea.AddNormalModeCommand("i", commands.Insert)
 	editarea.AddNormalModeCommand("a", commands.Append)
-	editarea.AddNormalModeCommand("W", commands.Save)
+	editarea.AddNormalModeCommand("o", commands.OpenLineBelow)
+	editarea.AddNormalModeCommand("O", commands.OpenLineAbove)
 	editarea.AddNormalModeCommand("x", commands.Delete)
 	editarea.AddNormalModeCommand("u", commands.Undo)
-	editarea.AddNormalModeCommand("R", commands.Redo)
-	editarea.AddNormalModeCommand("^", commands.JmpToLineStart)
-	editarea.AddNormalModeCommand("$", commands.JmpToLineEnd)
-	editarea.AddNormalModeCommand("b", commands.JmpToWordStart)
-	editarea.AddNormalModeCommand("w", commands.JmpToWordEnd)
-	editarea.AddNormalModeCommand("{", commands.JmpToParagraphStart)
-	editarea.AddNormalModeCommand("}", commands.JmpToParagraphEnd)
+	editarea.AddNormalModeCommand("<C-r>", commands.Redo)
+	editarea.AddNormalModeCommand("R", commands.Replace)
+	editarea.AddNormalModeCommand("r", commands.ReplaceChar)
+	editarea.AddNormalModeCommand(":", commands.CommandMode)
+	editarea.AddNormalModeCommand("gj", commands.MoveCursorDisplayDown)
+	editarea.AddNormalModeCommand("gk", commands.MoveCursorDisplayUp)
+	editarea.AddNormalModeCommand("zh", commands.ScrollLeft)
+	editarea.AddNormalModeCommand("zl", commands.ScrollRight)
+	editarea.AddNormalModeCommand("%", commands.JumpToMatch)
+	editarea.AddNormalModeCommand("v", commands.Visual)
+	editarea.AddNormalModeCommand("V", commands.VisualLine)
+	editarea.AddNormalModeCommand("<C-v>", commands.VisualBlock)
+	editarea.AddNormalModeCommand("p", commands.Put)
+	editarea.AddNormalModeCommand("P", commands.PutBefore)
+	editarea.AddNormalModeCommand("<C-p>", commands.PutOlder)
+	editarea.AddNormalModeCommand("<C-n>", commands.PutNewer)
+	editarea.AddNormalModeCommand("q", commands.Record)
+	editarea.AddNormalModeCommand("@", commands.Replay)
+	editarea.AddNormalModeCommand(".", commands.Repeat)
+	editarea.AddNormalModeCommand("m", commands.SetMark)
+	editarea.AddNormalModeCommand("<C-o>", commands.JumpOlder)
+	editarea.AddNormalModeCommand("<C-i>", commands.JumpNewer)
+	editarea.AddNormalModeCommand("g;", commands.ChangeOlder)
+	editarea.AddNormalModeCommand("g,", commands.ChangeNewer)
+	editarea.AddNormalModeCommand("ds", commands.DeleteSurrounding)
+	editarea.AddNormalModeCommand("cs", commands.ChangeSurrounding)
+	editarea.AddNormalModeCommand("~", commands.ToggleCase)
+	editarea.AddNormalModeCommand("<C-a>", commands.Increment)
+	editarea.AddNormalModeCommand("<C-x>", commands.Decrement)
+	editarea.AddNormalModeCommand("J", commands.Join)
+	editarea.AddNormalModeCommand("gJ", commands.JoinWithoutSpaces)
+}
+
+func registerVisualModeCommands() {
+	editarea.AddVisualModeCommand("v", commands.Visual)
+	editarea.AddVisualModeCommand("V", commands.VisualLine)
+	editarea.AddVisualModeCommand("<C-v>", commands.VisualBlock)
+	editarea.AddVisualModeCommand("o", commands.SwapSelectionEnds)
+	editarea.AddVisualModeCommand("p", commands.VisualPut)
+	editarea.AddVisualModeCommand("P", commands.VisualPut)
+	editarea.AddVisualModeCommand("r", commands.VisualReplace)
+	editarea.AddVisualModeCommand("S", commands.VisualSurround)
+	editarea.AddVisualModeCommand("~", commands.VisualToggleCase)
+	editarea.AddVisualModeCommand("u", commands.VisualLowerCase)
+	editarea.AddVisualModeCommand("U", commands.VisualUpperCase)
+	editarea.AddVisualModeCommand("<C-a>", commands.VisualIncrement)
+	editarea.AddVisualModeCommand("<C-x>", commands.VisualDecrement)
+	editarea.AddVisualModeCommand("J", commands.VisualJoin)
+	editarea.AddVisualModeCommand("gJ", commands.VisualJoinWithoutSpaces)
+}
+
+func registerOperators() {
+	editarea.AddOperator("d", commands.DeleteOperator)
+	editarea.AddOperator("c", commands.ChangeOperator)
+	editarea.AddOperator("y", commands.YankOperator)
+	editarea.AddOperator("=", commands.ReindentOperator)
+	editarea.AddOperator("gc", commands.CommentOperator)
+	editarea.AddOperator("ys", commands.SurroundOperator)
+	editarea.AddOperator("g~", commands.ToggleCaseOperator)
+	editarea.AddOperator("gu", commands.LowerCaseOperator)
+	editarea.AddOperator("gU", commands.UpperCaseOperator)
+	editarea.AddOperator("gq", commands.ReflowOperator)
+}
+
+func registerTextObjects() {
+	editarea.AddTextObject("iw", commands.InnerWord)
+	editarea.AddTextObject("aw", commands.AroundWord)
+	editarea.AddTextObject("iW", commands.InnerBigWord)
+	editarea.AddTextObject("aW", commands.AroundBigWord)
+	editarea.AddTextObject("is", commands.InnerSentence)
+	editarea.AddTextObject("as", commands.AroundSentence)
+	editarea.AddTextObject("ip", commands.InnerParagraph)
+	editarea.AddTextObject("ap", commands.AroundParagraph)
+	for _, q := range "\"'`" {
+		editarea.AddTextObject("i"+string(q), commands.QuoteObject(q, false))
+		editarea.AddTextObject("a"+string(q), commands.QuoteObject(q, true))
+	}
+	brackets := []struct {
+		open, close rune
+		aliases     string
+	}{{'(', ')', "b"}, {'{', '}', "B"}, {'[', ']', ""}, {'<', '>', ""}}
+	for _, b := range brackets {
+		for _, key := range string(b.open) + string(b.close) + b.aliases {
+			editarea.AddTextObject("i"+string(key), commands.BracketObject(b.open, b.close, false))
+			editarea.AddTextObject("a"+string(key), commands.BracketObject(b.open, b.close, true))
+		}
+	}
+	editarea.AddTextObject("it", commands.InnerTag)
+	editarea.AddTextObject("at", commands.AroundTag)
+
+	// Objects which understand Go source
+	editarea.AddTextObject("if", commands.InnerGoFunction)
+	editarea.AddTextObject("af", commands.AroundGoFunction)
+	editarea.AddTextObject("ik", commands.InnerGoBlock)
+	editarea.AddTextObject("ak", commands.AroundGoBlock)
+	editarea.AddTextObject("ia", commands.InnerGoArgument)
+	editarea.AddTextObject("aa", commands.AroundGoArgument)
+	editarea.AddTextObject("ic", commands.InnerGoComment)
+	editarea.AddTextObject("ac", commands.AroundGoComment)
+}
+
+func registerMotions() {
+	add := func(keys string, move func(*editarea.EditArea) (area.Point, bool), kind editarea.MotionKind) {
+		editarea.AddMotion(keys, editarea.Motion{Move: move, Kind: kind})
+	}
+	add("h", commands.Left, editarea.Exclusive)
+	add("l", commands.Right, editarea.Exclusive)
+	add("j", commands.Down, editarea.Linewise)
+	add("k", commands.Up, editarea.Linewise)
+	add("0", commands.LineStart, editarea.Exclusive)
+	add("^", commands.LineFirstNonBlank, editarea.Exclusive)
+	add("_", commands.LineFirstNonBlankDown, editarea.Linewise)
+	add("w", commands.WordForward, editarea.Exclusive)
+	add("W", commands.BigWordForward, editarea.Exclusive)
+	add("e", commands.WordEnd, editarea.Inclusive)
+	add("E", commands.BigWordEnd, editarea.Inclusive)
+	add("b", commands.WordBackward, editarea.Exclusive)
+	add("B", commands.BigWordBackward, editarea.Exclusive)
+	add("ge", commands.WordEndBackward, editarea.Inclusive)
+	add("gE", commands.BigWordEndBackward, editarea.Inclusive)
+	editarea.AddMotion("$", editarea.Motion{Move: commands.LineEnd, Kind: editarea.Inclusive, LineEnd: true})
+
+	// Jumps add where the cursor was to the jump list
+	jump := func(keys string, move func(*editarea.EditArea) (area.Point, bool), kind editarea.MotionKind) {
+		editarea.AddMotion(keys, editarea.Motion{Move: move, Kind: kind, Jump: true})
+	}
+	jump("gg", commands.GotoFirstLine, editarea.Linewise)
+	jump("G", commands.GotoLastLine, editarea.Linewise)
+	jump("H", commands.WindowTop, editarea.Linewise)
+	jump("M", commands.WindowMiddle, editarea.Linewise)
+	jump("L", commands.WindowBottom, editarea.Linewise)
+	jump("{", commands.ParagraphBackward, editarea.Exclusive)
+	jump("}", commands.ParagraphForward, editarea.Exclusive)
+	editarea.AddMotion("`", editarea.Motion{Move: commands.GotoMark, Kind: editarea.Exclusive, TakesChar: true, Jump: true})
+	editarea.AddMotion("'", editarea.Motion{Move: commands.GotoMarkLine, Kind: editarea.Linewise, TakesChar: true, Jump: true})
+
+	find := func(keys string, move func(*editarea.EditArea) (area.Point, bool), kind editarea.MotionKind) {
+		editarea.AddMotion(keys, editarea.Motion{Move: move, Kind: kind, TakesChar: true})
+	}
+	find("f", commands.FindForward, editarea.Inclusive)
+	find("F", commands.FindBackward, editarea.Exclusive)
+	find("t", commands.TillForward, editarea.Inclusive)
+	find("T", commands.TillBackward, editarea.Exclusive)
+	editarea.AddMotion(";", editarea.Motion{Move: commands.RepeatFind, KindOf: commands.RepeatFindKind(false)})
+	editarea.AddMotion(",", editarea.Motion{Move: commands.RepeatFindReversed, KindOf: commands.RepeatFindKind(true)})
 }
 
 func registerInsertModeCommands() {
@@ -35,4 +171,37 @@ func registerInsertModeCommands() {
 	editarea.AddInsertModeCommand(tcell.KeyUp, commands.MoveCursorUp)
 	editarea.AddInsertModeCommand(tcell.KeyLeft, commands.MoveCursorLeft)
 	editarea.AddInsertModeCommand(tcell.KeyRight, commands.MoveCursorRight)
+	editarea.AddInsertModeCommand(tcell.KeyDelete, commands.DeleteForward)
+	editarea.AddInsertModeCommand(tcell.KeyHome, commands.Home)
+	editarea.AddInsertModeCommand(tcell.KeyEnd, commands.End)
+	editarea.AddInsertModeCommand(tcell.KeyPgUp, commands.PageUp)
+	editarea.AddInsertModeCommand(tcell.KeyPgDn, commands.PageDown)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlW, commands.DeleteWordBackward)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlU, commands.DeleteToLineStart)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlR, commands.InsertRegister)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlO, commands.NormalModeOnce)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlT, commands.Indent)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlD, commands.Dedent)
+	for _, mod := range []tcell.ModMask{tcell.ModCtrl, tcell.ModAlt} {
+		editarea.AddInsertModeChord(editarea.Chord{Key: tcell.KeyLeft, Modifiers: mod}, commands.WordLeft)
+		editarea.AddInsertModeChord(editarea.Chord{Key: tcell.KeyRight, Modifiers: mod}, commands.WordRight)
+	}
+}
+
+func registerExCommands() {
+	editarea.AddExCommand("w", commands.Write)
+	editarea.AddExCommand("write", commands.Write)
+	editarea.AddExCommand("jobs", commands.Jobs)
+	editarea.AddExCommand("run", commands.Run)
+	editarea.AddExCommand("frames", commands.Frames)
+	editarea.AddExCommand("colorscheme", commands.Colorscheme)
+	editarea.AddExCommand("set", commands.Set)
+	editarea.AddExCommand("reg", commands.Registers)
+	editarea.AddExCommand("registers", commands.Registers)
+	editarea.AddExCommand("display", commands.Registers)
+	editarea.AddExCommand("norm", commands.Normal)
+	editarea.AddExCommand("normal", commands.Normal)
+	editarea.AddExCommand("sort", commands.Sort)
+	editarea.AddExCommand("align", commands.Align)
+	editarea.AddExCommand("reflow", commands.Reflow)
 }
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoPairs(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		source   string
+		keys     string
+		want     string
+	}{
+		{"pairing a bracket", "test.go", "", "i(x<Esc>", "(x)"},
+		{"stepping over the closer", "test.go", "", "i(x)y<Esc>", "(x)y"},
+		{"pairing quotes", "test.go", "", `i"a"b<Esc>`, `"a"b`},
+		{"not pairing before a word", "test.go", "x", "i(<Esc>", "(x"},
+		{"not pairing apostrophes", "test.go", "", "idon't<Esc>", "don't"},
+		{"not pairing in strings", "test.go", `x := "ab"`, "fbi(<Esc>", `x := "a(b"`},
+		{"not pairing in comments", "test.go", "// ", "A(<Esc>", "// ("},
+		{"backspacing an empty pair", "test.go", "", "i(<BS>x<Esc>", "x"},
+		{"backspacing inside a pair", "test.go", "", "i(a<BS>x<Esc>", "(x)"},
+		{"opening a block", "test.go", "func f()", "A {<CR>x<Esc>", "func f() {\n\tx\n}"},
+		{"filetype pairs", "test.go", "", "i`a<Esc>", "`a`"},
+		{"no single quotes in Rust", "test.rs", "", "i'a<Esc>", "'a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditAreaForFile(tt.filename, tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+
+	e := newTestEditAreaForFile("test.go", "", area.Point{X: 0, Y: 0})
+	assert.NoError(t, e.SetOptions("autopairs="))
+	typeNamedKeys(e, "i(<Esc>")
+	assert.Equal(t, "(", e.Text().String())
+	assert.Error(t, e.SetOptions("autopairs=ab"))
+}
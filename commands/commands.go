@@ -5,6 +5,10 @@ import (
 	"os"
 
 	"github.com/jamesroutley/fuji/editarea"
+	"github.com/jamesroutley/fuji/editor"
+	"github.com/jamesroutley/fuji/jobs"
+	"github.com/jamesroutley/fuji/pane"
+	"github.com/jamesroutley/fuji/theme"
 )
 
 // MoveCursorUp moves the cursor up
@@ -19,8 +23,34 @@ func MoveCursorLeft(e *editarea.EditArea) { e.CursorLeft() }
 // MoveCursorRight moves the cursor right
 func MoveCursorRight(e *editarea.EditArea) { e.CursorRight() }
 
-// Quit quits the editor
+// MoveCursorDisplayUp moves the cursor up a row of the display
+func MoveCursorDisplayUp(e *editarea.EditArea) { e.DisplayUp() }
+
+// MoveCursorDisplayDown moves the cursor down a row of the display
+func MoveCursorDisplayDown(e *editarea.EditArea) { e.DisplayDown() }
+
+// ScrollLeft scrolls the text left, when lines aren't wrapped
+func ScrollLeft(e *editarea.EditArea) { e.ScrollLeft() }
+
+// ScrollRight scrolls the text right, when lines aren't wrapped
+func ScrollRight(e *editarea.EditArea) { e.ScrollRight() }
+
+// JumpToMatch moves the cursor to the matching bracket or tag
+func JumpToMatch(e *editarea.EditArea) { e.JumpToMatch() }
+
+// quitWithoutMarks is set when the marks couldn't be saved by the last Quit,
+// so that quitting again quits without them
+var quitWithoutMarks bool
+
+// Quit quits the editor, saving the marks for the next session. If the marks
+// can't be saved, the error is shown instead, and quitting again quits
+// without saving them.
 func Quit(e *editarea.EditArea) {
+	if err := editarea.SaveMarks(); err != nil && !quitWithoutMarks {
+		quitWithoutMarks = true
+		e.SetMessage("cannot save marks: %s (quit again to quit anyway)", err)
+		return
+	}
 	// Set cursor to 0, 0 to avoid clear screen on quit.
 	// e.screen.ShowCursor(0, 0)
 	// e.screen.Show()
@@ -47,101 +77,65 @@ func Backspace(e *editarea.EditArea) {
 	e.Backspace()
 }
 
-// JmpToLineEnd moves cursor to line end
-func JmpToLineEnd(e *editarea.EditArea) {
-	for !e.CursorAtLineEnd() {
-		e.CursorRight()
-	}
-}
+// Save saves the file being edited
+func Save(e *editarea.EditArea) { e.Save() }
 
-// JmpToLineStart moves the cursor to line start
-func JmpToLineStart(e *editarea.EditArea) {
-	for !e.CursorAtLineStart() {
-		e.CursorLeft()
-	}
-}
+// LineBreak inserts a line break
+func LineBreak(e *editarea.EditArea) { e.LineBreak() }
 
-// JmpToWordStart moves the cursor to the beginning of the word
-// TODO: this won't stop at non-space chars like . or (
-func JmpToWordStart(e *editarea.EditArea) {
-	// This is a hack to allow the command to be run repeatedly to jump back
-	// multiple words
-	e.CursorLeft()
-	e.CursorLeft()
-	for e.Peek() != ' ' {
-		if e.CursorAtLineStart() {
-			return
-		}
-		e.CursorLeft()
-	}
-	e.CursorRight()
-}
+// Undo undoes the last action
+func Undo(e *editarea.EditArea) { e.Undo() }
 
-// JmpToWordEnd moves the cursor the the beginning of the next word
-func JmpToWordEnd(e *editarea.EditArea) {
-	for e.Peek() != ' ' {
-		if e.CursorAtTextEnd() && e.CursorAtLineEnd() {
-			return
-		}
-		e.CursorRight()
-	}
-	e.CursorRight()
+// Redo undoes the last undo
+func Redo(e *editarea.EditArea) { e.Redo() }
+
+// CommandMode switches the EditArea into command mode
+func CommandMode(e *editarea.EditArea) { e.EnterCommandMode() }
+
+// Write saves the file being edited
+func Write(e *editarea.EditArea, r editarea.Range, args string) error {
+	e.Save()
+	return nil
 }
 
-// atEmptyLine returns a bool indicating whether the line that the cursor is at
-// is empty.
-func atEmptyLine(e *editarea.EditArea) bool {
-	return e.CursorAtLineStart() && e.CursorAtLineEnd()
+// Jobs opens a pane listing the background jobs
+func Jobs(e *editarea.EditArea, r editarea.Range, args string) error {
+	editor.Open(pane.NewJobsPane())
+	return nil
 }
 
-// JmpToParagraphEnd moves the cursor to the next empty line
-func JmpToParagraphEnd(e *editarea.EditArea) {
-	// If we are already on an empty line, skip to the next non-empty line
-	for atEmptyLine(e) {
-		if e.CursorAtTextEnd() {
-			return
-		}
-		e.CursorDown()
+// Run runs a command line as a background job, reporting its exit status
+// when it finishes
+func Run(e *editarea.EditArea, r editarea.Range, args string) error {
+	var j *jobs.Job
+	exit := func(err error) {
+		e.SetMessage("[%d] %s: %s", j.ID, j.Name, j.Status())
 	}
-
-	for !atEmptyLine(e) {
-		if e.CursorAtTextEnd() {
-			return
-		}
-		e.CursorDown()
+	j, err := jobs.StartLine(args, jobs.Handlers{Exit: exit})
+	if err != nil {
+		return err
 	}
+	e.SetMessage("[%d] %s", j.ID, j.Name)
+	return nil
 }
 
-// JmpToParagraphStart moves the cursor to the previous empty line
-func JmpToParagraphStart(e *editarea.EditArea) {
-	// If we are already on an empty line, skip to the next non-empty line
-	for atEmptyLine(e) {
-		if e.CursorAtTextStart() {
-			return
-		}
-		e.CursorUp()
-	}
+// Frames reports how long the editor is taking to draw frames
+func Frames(e *editarea.EditArea, r editarea.Range, args string) error {
+	e.SetMessage("%s", editor.Frames())
+	return nil
+}
 
-	for !atEmptyLine(e) {
-		if e.CursorAtTextStart() {
-			return
-		}
-		e.CursorUp()
+// Colorscheme switches to the colour scheme named by args, or reports the
+// current colour scheme if args is empty
+func Colorscheme(e *editarea.EditArea, r editarea.Range, args string) error {
+	if args == "" {
+		e.SetMessage("%s", theme.Current().Name)
+		return nil
 	}
-
+	return theme.Set(args)
 }
 
-// Save saves the file being edited
-func Save(e *editarea.EditArea) { e.Save() }
-
-// LineBreak inserts a line break
-func LineBreak(e *editarea.EditArea) { e.LineBreak() }
-
-// Delete deletes the character under the cursor
-func Delete(e *editarea.EditArea) { e.Delete() }
-
-// Undo undoes the last action
-func Undo(e *editarea.EditArea) { e.Undo() }
-
-// Redo undoes the last undo
-func Redo(e *editarea.EditArea) { e.Redo() }
+// Set changes the options given in args, like vim's :set
+func Set(e *editarea.EditArea, r editarea.Range, args string) error {
+	return e.SetOptions(args)
+}
@@ -0,0 +1,14 @@
+package commands
+
+import "github.com/jamesroutley/fuji/editarea"
+
+// CommentOperator comments out the lines in r, or uncomments them if they
+// are all commented out, and moves to the first non-blank rune of the first
+// line
+func CommentOperator(e *editarea.EditArea, r editarea.Region) {
+	if err := e.ToggleComment(r.Start.Y, r.End.Y); err != nil {
+		e.SetMessage("%s", err)
+		return
+	}
+	e.SetCursor(firstNonBlank(e.Text(), r.Start.Y))
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddOperator("gc", CommentOperator)
+}
+
+func TestComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		source   string
+		keys     string
+		want     string
+	}{
+		{"a line", "test.go", "x := 1", "gcc", "// x := 1"},
+		{"uncommenting a line", "test.go", "\t// x := 1", "gcc", "\tx := 1"},
+		{"a motion", "test.go", "a\nb\nc", "gcj", "// a\n// b\nc"},
+		{"lining up markers", "test.go", "\tif x {\n\t\ty\n\t}", "gcG", "\t// if x {\n\t// \ty\n\t// }"},
+		{"skipping blank lines", "test.py", "a\n\nb", "gcG", "# a\n\n# b"},
+		{"commenting partly commented lines", "test.py", "# a\nb", "gcj", "# # a\n# b"},
+		{"uncommenting lines", "test.py", "# a\n#b", "gcj", "a\nb"},
+		{"visual mode", "test.go", "a\nb\nc", "jVjgc", "a\n// b\n// c"},
+		{"block comments", "test.html", "<p>", "gcc", "<!-- <p> -->"},
+		{"uncommenting block comments", "test.html", "<!-- <p> -->", "gcc", "<p>"},
+		{"the lexer's comments", "test.ml", "let x = 1", "gcc", "(* let x = 1 *)"},
+		{"repeating", "test.go", "a\nb", "gccj.", "// a\n// b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditAreaForFile(tt.filename, tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+
+	e := newTestEditAreaForFile("test.unknown", "a", area.Point{X: 0, Y: 0})
+	typeNamedKeys(e, "gcc")
+	assert.Equal(t, "a", e.Text().String())
+}
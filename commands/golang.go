@@ -0,0 +1,296 @@
+package commands
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/jamesroutley/fuji/text"
+)
+
+// goFile is a Go source file parsed for the Go text objects
+type goFile struct {
+	t    *text.Text
+	fset *token.FileSet
+	file *ast.File
+	// cursor is the offset of the cursor in bytes
+	cursor int
+}
+
+// parseGo parses the text of e as Go source. ok is false if e isn't editing
+// a Go file, or it can't be parsed at all. Files with syntax errors are
+// parsed as far as possible, so objects can be used while editing.
+func parseGo(e *editarea.EditArea) (g goFile, ok bool) {
+	if filepath.Ext(e.Filename) != ".go" {
+		return g, false
+	}
+	g.t = e.Text()
+	g.fset = token.NewFileSet()
+	g.file, _ = parser.ParseFile(g.fset, e.Filename, g.t.String(), parser.ParseComments)
+	if g.file == nil {
+		return g, false
+	}
+	p := cursor(e)
+	for row := 0; row < p.Y; row++ {
+		g.cursor += len(g.t.Line(row).String()) + 1
+	}
+	g.cursor += len(string(lineRunes(g.t, p.Y)[:p.X]))
+	return g, true
+}
+
+// offset returns the byte offset of pos
+func (g goFile) offset(pos token.Pos) int {
+	return g.fset.Position(pos).Offset
+}
+
+// contains returns whether the cursor is in the node n
+func (g goFile) contains(n ast.Node) bool {
+	return g.offset(n.Pos()) <= g.cursor && g.cursor < g.offset(n.End())
+}
+
+// point returns the position of pos in the text
+func (g goFile) point(pos token.Pos) area.Point {
+	position := g.fset.Position(pos)
+	row := position.Line - 1
+	if row >= g.t.Length() {
+		row = g.t.Length() - 1
+		return area.Point{X: g.t.LineLength(row), Y: row}
+	}
+	s := g.t.Line(row).String()
+	col := position.Column - 1
+	if col > len(s) {
+		col = len(s)
+	}
+	return area.Point{X: utf8.RuneCountInString(s[:col]), Y: row}
+}
+
+// region returns the region from start up to end
+func (g goFile) region(start, end token.Pos) editarea.Region {
+	return editarea.Region{Start: g.point(start), End: g.point(end)}
+}
+
+// lines returns the linewise region of the rows from start to end
+func (g goFile) lines(start, end token.Pos) editarea.Region {
+	return editarea.Region{Start: g.point(start), End: g.point(end - 1), Linewise: true}
+}
+
+// inner returns the region between the braces of block
+func (g goFile) inner(block *ast.BlockStmt) editarea.Region {
+	f := flatten(g.t)
+	return innerRegion(f, f.offset(g.point(block.Lbrace)), f.offset(g.point(block.Rbrace)))
+}
+
+// innermost returns the innermost node which contains the cursor and for
+// which match returns true
+func (g goFile) innermost(match func(n ast.Node) bool) ast.Node {
+	var found ast.Node
+	ast.Inspect(g.file, func(n ast.Node) bool {
+		if n == nil || !g.contains(n) {
+			return n == g.file
+		}
+		if match(n) {
+			found = n
+		}
+		return true
+	})
+	return found
+}
+
+// goFunctionObject returns a text object for the Go function or function
+// literal around the cursor. The object is the function's body, or if around
+// is set, the whole function and its doc comment.
+func goFunctionObject(around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		g, ok := parseGo(e)
+		if !ok {
+			return editarea.Region{}, false
+		}
+		n := g.innermost(func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				return n.Body != nil
+			case *ast.FuncLit:
+				return true
+			}
+			return false
+		})
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if !around {
+				return g.inner(n.Body), true
+			}
+			start := n.Pos()
+			if n.Doc != nil {
+				start = n.Doc.Pos()
+			}
+			return g.lines(start, n.End()), true
+		case *ast.FuncLit:
+			if !around {
+				return g.inner(n.Body), true
+			}
+			return g.region(n.Pos(), n.End()), true
+		}
+		return editarea.Region{}, false
+	}
+}
+
+// blockBody returns the block of the statement n which the cursor is in, or
+// nil if n isn't a statement with a block
+func (g goFile) blockBody(n ast.Node) *ast.BlockStmt {
+	switch n := n.(type) {
+	case *ast.IfStmt:
+		if block, ok := n.Else.(*ast.BlockStmt); ok && g.contains(block) {
+			return block
+		}
+		return n.Body
+	case *ast.ForStmt:
+		return n.Body
+	case *ast.RangeStmt:
+		return n.Body
+	case *ast.SwitchStmt:
+		return n.Body
+	case *ast.TypeSwitchStmt:
+		return n.Body
+	case *ast.SelectStmt:
+		return n.Body
+	}
+	return nil
+}
+
+// goBlockObject returns a text object for the Go if, for, switch or select
+// statement around the cursor. The object is the statement's block, or the
+// whole statement if around is set.
+func goBlockObject(around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		g, ok := parseGo(e)
+		if !ok {
+			return editarea.Region{}, false
+		}
+		n := g.innermost(func(n ast.Node) bool { return g.blockBody(n) != nil })
+		if n == nil {
+			return editarea.Region{}, false
+		}
+		if !around {
+			return g.inner(g.blockBody(n)), true
+		}
+		return g.lines(n.Pos(), n.End()), true
+	}
+}
+
+// goArgumentObject returns a text object for the argument of a Go function
+// call, or parameter of a function, around the cursor. If around is set, the
+// object includes the separator after the argument or, for the last
+// argument, before it.
+func goArgumentObject(around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		g, ok := parseGo(e)
+		if !ok {
+			return editarea.Region{}, false
+		}
+		// args returns the arguments of n, if it has any around the cursor
+		args := func(n ast.Node) []ast.Node {
+			var args []ast.Node
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if g.offset(n.Lparen) >= g.cursor {
+					return nil
+				}
+				for _, arg := range n.Args {
+					args = append(args, arg)
+				}
+			case *ast.FuncType:
+				for _, fields := range []*ast.FieldList{n.Params, n.Results} {
+					if fields == nil || !fields.Opening.IsValid() || !g.contains(fields) {
+						continue
+					}
+					for _, field := range fields.List {
+						args = append(args, field)
+					}
+				}
+			}
+			return args
+		}
+		n := g.innermost(func(n ast.Node) bool { return len(args(n)) > 0 })
+		if n == nil {
+			return editarea.Region{}, false
+		}
+		list := args(n)
+		i := 0
+		// The cursor may be on the separator before an argument
+		for i < len(list)-1 && g.offset(list[i].End()) <= g.cursor {
+			i++
+		}
+		start, end := list[i].Pos(), list[i].End()
+		if around {
+			switch {
+			case i+1 < len(list):
+				end = list[i+1].Pos()
+			case i > 0:
+				start = list[i-1].End()
+			}
+		}
+		return g.region(start, end), true
+	}
+}
+
+// goCommentObject returns a text object for the Go comment around the
+// cursor, including the following lines of a comment which spans lines. The
+// object is the text of the comment, or if around is set, the whole comment,
+// which is selected linewise if it is on lines of its own.
+func goCommentObject(around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		g, ok := parseGo(e)
+		if !ok {
+			return editarea.Region{}, false
+		}
+		for _, group := range g.file.Comments {
+			if !g.contains(group) {
+				continue
+			}
+			if !around {
+				first, last := group.List[0], group.List[len(group.List)-1]
+				start, end := g.point(first.Pos()), g.point(last.End())
+				start.X += 2
+				if strings.HasPrefix(first.Text, "/*") {
+					end.X -= 2
+				}
+				if runes := lineRunes(g.t, start.Y); start.X < len(runes) && runes[start.X] == ' ' {
+					start.X++
+				}
+				return editarea.Region{Start: start, End: end}, true
+			}
+			start := g.point(group.Pos())
+			before := string(lineRunes(g.t, start.Y)[:start.X])
+			if strings.TrimSpace(before) == "" {
+				return g.lines(group.Pos(), group.End()), true
+			}
+			return g.region(group.Pos(), group.End()), true
+		}
+		return editarea.Region{}, false
+	}
+}
+
+var (
+	// InnerGoFunction selects the body of the Go function around the cursor
+	InnerGoFunction = goFunctionObject(false)
+	// AroundGoFunction selects the Go function around the cursor
+	AroundGoFunction = goFunctionObject(true)
+	// InnerGoBlock selects the block of the Go statement around the cursor
+	InnerGoBlock = goBlockObject(false)
+	// AroundGoBlock selects the Go statement with a block around the cursor
+	AroundGoBlock = goBlockObject(true)
+	// InnerGoArgument selects the Go argument around the cursor
+	InnerGoArgument = goArgumentObject(false)
+	// AroundGoArgument selects the Go argument around the cursor and its
+	// separator
+	AroundGoArgument = goArgumentObject(true)
+	// InnerGoComment selects the text of the Go comment around the cursor
+	InnerGoComment = goCommentObject(false)
+	// AroundGoComment selects the Go comment around the cursor
+	AroundGoComment = goCommentObject(true)
+)
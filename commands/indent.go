@@ -0,0 +1,16 @@
+package commands
+
+import "github.com/jamesroutley/fuji/editarea"
+
+// OpenLineBelow opens a new line below the cursor's and enters insert mode
+func OpenLineBelow(e *editarea.EditArea) { e.OpenLine(false) }
+
+// OpenLineAbove opens a new line above the cursor's and enters insert mode
+func OpenLineAbove(e *editarea.EditArea) { e.OpenLine(true) }
+
+// ReindentOperator reindents the lines in r, and moves to the first
+// non-blank rune of the first line
+func ReindentOperator(e *editarea.EditArea, r editarea.Region) {
+	e.Reindent(r.Start.Y, r.End.Y)
+	e.SetCursor(firstNonBlank(e.Text(), r.Start.Y))
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddNormalModeCommand("a", Append)
+	editarea.AddInsertModeCommand(tcell.KeyEnter, LineBreak)
+	editarea.AddNormalModeCommand("o", OpenLineBelow)
+	editarea.AddNormalModeCommand("O", OpenLineAbove)
+	editarea.AddOperator("=", ReindentOperator)
+}
+
+func TestAutoIndent(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		source   string
+		keys     string
+		want     string
+	}{
+		{"copying the indentation", "test.txt", "\tfoo", "A<CR>bar<Esc>", "\tfoo\n\tbar"},
+		{"replacing whitespace after the cursor", "test.txt", "\tfoo  bar", "fbi<CR><Esc>", "\tfoo\n\tbar"},
+		{"clearing unused indentation", "test.txt", "\tfoo", "A<CR><CR>bar<Esc>", "\tfoo\n\n\tbar"},
+		{"clearing indentation when leaving insert mode", "test.txt", "\tfoo", "A<CR><Esc>", "\tfoo\n"},
+		{"indenting after a bracket", "test.go", "func f() {", "A<CR>x<CR>}<Esc>", "func f() {\n\tx\n}"},
+		{"lining up closing brackets", "test.go", "f(a,", "A<CR>b)<CR>c<Esc>", "f(a,\n\tb)\nc"},
+		{"breaking between brackets", "test.go", "\tf()", "f(a<CR>x<Esc>", "\tf(\n\t\tx\n\t)"},
+		{"ignoring brackets in strings", "test.go", `x := "{"`, "A<CR>y<Esc>", "x := \"{\"\ny"},
+		{"indenting after a colon", "test.py", "if x:", "A<CR>y<Esc>", "if x:\n    y"},
+		{"dedenting after return", "test.py", "def f():\n    return 1", "jA<CR>x<Esc>", "def f():\n    return 1\nx"},
+		{"opening a line below", "test.go", "if x {\n}", "ox<Esc>", "if x {\n\tx\n}"},
+		{"opening a line above a closing bracket", "test.go", "if x {\n}", "jOx<Esc>", "if x {\n\tx\n}"},
+		{"opening a line above", "test.go", "\tx", "Oy<Esc>", "\ty\n\tx"},
+		{"reindenting", "test.go", "func f() {\nif x {\n  y()\n    }\n\n  }", "=G", "func f() {\n\tif x {\n\t\ty()\n\t}\n\n}"},
+		{"reindenting a line", "test.go", "{\n      x", "j==", "{\n\tx"},
+		{"reindenting keeps Python dedents", "test.py", "if x:\ny\nz", "=G", "if x:\n    y\nz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditAreaForFile(tt.filename, tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+
+	e := newTestEditAreaForFile("test.go", "{", area.Point{X: 0, Y: 0})
+	assert.NoError(t, e.SetOptions("noautoindent"))
+	typeNamedKeys(e, "A<CR>x<Esc>")
+	assert.Equal(t, "{\nx", e.Text().String())
+}
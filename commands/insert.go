@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+)
+
+// deleteBackTo deletes the text from p up to the cursor. At the start of a
+// line, it joins the line to the one above instead.
+func deleteBackTo(e *editarea.EditArea, p area.Point) {
+	c := e.Cursor()
+	if c.X == 0 {
+		e.Backspace()
+		return
+	}
+	if p.Y < c.Y {
+		p = area.Point{X: 0, Y: c.Y}
+	}
+	e.ReplaceRegion(editarea.Region{Start: p, End: c}, "")
+}
+
+// DeleteWordBackward deletes the word before the cursor, and any whitespace
+// between it and the cursor
+func DeleteWordBackward(e *editarea.EditArea) {
+	p, _ := wordBackward(e.Text(), e.Cursor(), false)
+	deleteBackTo(e, p)
+}
+
+// DeleteToLineStart deletes the text before the cursor on its line, up to
+// the indentation. If the cursor is in the indentation, it deletes that too.
+func DeleteToLineStart(e *editarea.EditArea) {
+	c := e.Cursor()
+	p := area.Point{Y: c.Y}
+	for _, r := range lineRunes(e.Text(), c.Y) {
+		if r != ' ' && r != '\t' {
+			break
+		}
+		p.X++
+	}
+	if c.X <= p.X {
+		p.X = 0
+	}
+	deleteBackTo(e, p)
+}
+
+// DeleteForward deletes the rune under the cursor. At the end of a line, it
+// joins the line below to it.
+func DeleteForward(e *editarea.EditArea) {
+	c := e.Cursor()
+	if c.X < e.Text().LineLength(c.Y) {
+		e.Delete()
+		return
+	}
+	if c.Y+1 < e.Text().Length() {
+		e.ReplaceRegion(editarea.Region{Start: c, End: area.Point{X: 0, Y: c.Y + 1}}, "")
+	}
+}
+
+// InsertRegister inserts the text of the register typed next
+func InsertRegister(e *editarea.EditArea) {
+	e.ReadChar(func(e *editarea.EditArea) {
+		r, _ := e.Register(e.Char())
+		e.Paste(r.Text)
+	})
+}
+
+// NormalModeOnce runs one normal mode command, then returns to insert mode
+func NormalModeOnce(e *editarea.EditArea) { e.NormalModeOnce() }
+
+// Indent indents the cursor's line by a shiftwidth
+func Indent(e *editarea.EditArea) { e.ShiftLine(e.Cursor().Y, 1) }
+
+// Dedent dedents the cursor's line by a shiftwidth
+func Dedent(e *editarea.EditArea) { e.ShiftLine(e.Cursor().Y, -1) }
+
+// Home moves the cursor to the start of its line
+func Home(e *editarea.EditArea) { e.SetCursor(area.Point{X: 0, Y: e.Cursor().Y}) }
+
+// End moves the cursor to the end of its line, after its last rune
+func End(e *editarea.EditArea) {
+	row := e.Cursor().Y
+	e.SetCursor(area.Point{X: e.Text().LineLength(row), Y: row})
+}
+
+// pageRows returns the number of rows of text displayed
+func pageRows(e *editarea.EditArea) int {
+	first, last := e.VisibleRows()
+	if rows := last - first + 1; rows > 1 {
+		return rows
+	}
+	return 1
+}
+
+// PageUp moves the cursor up a page
+func PageUp(e *editarea.EditArea) {
+	c := e.Cursor()
+	e.SetCursor(area.Point{X: c.X, Y: c.Y - pageRows(e)})
+}
+
+// PageDown moves the cursor down a page
+func PageDown(e *editarea.EditArea) {
+	c := e.Cursor()
+	e.SetCursor(area.Point{X: c.X, Y: c.Y + pageRows(e)})
+}
+
+// WordLeft moves the cursor to the start of the word before it
+func WordLeft(e *editarea.EditArea) {
+	if p, ok := wordBackward(e.Text(), e.Cursor(), false); ok {
+		e.SetCursor(p)
+	}
+}
+
+// WordRight moves the cursor to the start of the next word
+func WordRight(e *editarea.EditArea) {
+	if p, ok := wordForward(e.Text(), cursor(e), false); ok {
+		e.SetCursor(p)
+	}
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddInsertModeCommand(tcell.KeyBackspace2, Backspace)
+	editarea.AddInsertModeCommand(tcell.KeyDelete, DeleteForward)
+	editarea.AddInsertModeCommand(tcell.KeyHome, Home)
+	editarea.AddInsertModeCommand(tcell.KeyEnd, End)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlW, DeleteWordBackward)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlU, DeleteToLineStart)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlR, InsertRegister)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlO, NormalModeOnce)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlT, Indent)
+	editarea.AddInsertModeCommand(tcell.KeyCtrlD, Dedent)
+	editarea.AddInsertModeCommand(tcell.KeyLeft, MoveCursorLeft)
+	editarea.AddInsertModeChord(editarea.Chord{Key: tcell.KeyLeft, Modifiers: tcell.ModCtrl}, WordLeft)
+	editarea.AddInsertModeChord(editarea.Chord{Key: tcell.KeyRight, Modifiers: tcell.ModAlt}, WordRight)
+	editarea.AddNormalModeCommand("A", func(e *editarea.EditArea) {
+		e.Mode = editarea.ModeInsert
+		e.SetCursor(area.Point{X: e.Text().LineLength(e.Cursor().Y), Y: e.Cursor().Y})
+	})
+}
+
+func TestInsertModeKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		keys   string
+		want   string
+	}{
+		{"deleting a word", "foo bar  ", "A<C-w>x<Esc>", "foo x"},
+		{"deleting words", "foo.bar", "A<C-w><C-w>x<Esc>", "foox"},
+		{"deleting a word at the start of a line", "a\nb", "jix<Left><C-w><Esc>", "axb"},
+		{"deleting to the start of the line", "  foo bar", "A<C-u>x<Esc>", "  x"},
+		{"deleting the indentation", "  foo", "A<C-u><C-u>x<Esc>", "x"},
+		{"deleting forwards", "ab\ncd", "i<Del><End><Del><Esc>", "bcd"},
+		{"deleting forwards on non-ASCII lines", "héllo\nwörld", "i<End><Del><Esc>", "héllowörld"},
+		{"deleting forwards after a put", "aaa\nbbb\nccc\nddd", "yyPjjji<Del><Esc>", "aaa\naaa\nbbb\ncc\nddd"},
+		{"deleting backwards after a put", "aaa\nbbb\nccc\nddd", "yyPjjjA<BS><Esc>", "aaa\naaa\nbbb\ncc\nddd"},
+		{"moving to the end of a non-ASCII line", "héllo wörld", "i<End>x<Esc>", "héllo wörldx"},
+		{"moving home", "abc", "A<Home>x<Esc>", "xabc"},
+		{"inserting a register", "abc", `"ayiwA <C-r>a<Esc>`, "abc abc"},
+		{"running a normal mode command", "abc\ndef", "i<C-o>jx<Esc>", "abc\nxdef"},
+		{"running an operator", "abc def", "A<C-o>dbx<Esc>", "abc xf"},
+		{"indenting", "foo", "A<C-t><C-t>x<Esc>", "\t\tfoox"},
+		{"dedenting", "\t\t foo", "i<C-d>x<Esc>", "\t\txfoo"},
+		{"moving by words", "foo bar baz", "A<C-Left><C-Left>x<M-Right>y<Esc>", "foo xbar ybaz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+			assert.Equal(t, editarea.ModeNormal, e.Mode)
+		})
+	}
+}
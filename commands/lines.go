@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+)
+
+// rangeLines returns the lines in r
+func rangeLines(e *editarea.EditArea, r editarea.Range) []string {
+	var lines []string
+	for row := r.Start; row <= r.End; row++ {
+		lines = append(lines, e.Text().Line(row).String())
+	}
+	return lines
+}
+
+// orWholeText returns r, or every line of the text if no range was given
+func orWholeText(e *editarea.EditArea, r editarea.Range) editarea.Range {
+	if r.Given {
+		return r
+	}
+	return editarea.Range{Start: 0, End: e.Text().Length() - 1}
+}
+
+// replaceRange replaces the lines in r with lines, as a single edit
+func replaceRange(e *editarea.EditArea, r editarea.Range, lines []string) {
+	e.ReplaceRegion(editarea.Region{
+		Start:    area.Point{X: 0, Y: r.Start},
+		End:      area.Point{X: 0, Y: r.End},
+		Linewise: true,
+	}, strings.Join(lines, "\n")+"\n")
+}
+
+// sortOptions are the options of :sort
+type sortOptions struct {
+	reverse, numeric, ignoreCase, unique bool
+	// pattern, if set, chooses the part of each line to sort by: the text
+	// after its first match, or the match itself if byMatch is set
+	pattern *regexp.Regexp
+	byMatch bool
+}
+
+// parseSortOptions parses the arguments of :sort. Flags are single letters,
+// and the pattern is written between a pair of any other character, usually
+// slashes. A backslash before the closing character includes it in the
+// pattern.
+func parseSortOptions(args string) (sortOptions, error) {
+	var o sortOptions
+	if strings.HasPrefix(args, "!") {
+		o.reverse, args = true, args[1:]
+	}
+	for args != "" {
+		c, size := utf8.DecodeRuneInString(args)
+		args = args[size:]
+		switch {
+		case unicode.IsSpace(c):
+		case c == 'n':
+			o.numeric = true
+		case c == 'i':
+			o.ignoreCase = true
+		case c == 'u':
+			o.unique = true
+		case c == 'r':
+			o.byMatch = true
+		case unicode.IsLetter(c):
+			return o, fmt.Errorf("invalid argument: %c", c)
+		default:
+			var pattern strings.Builder
+			closed := false
+			for args != "" && !closed {
+				r, size := utf8.DecodeRuneInString(args)
+				args = args[size:]
+				switch {
+				case r == c:
+					closed = true
+				case r == '\\' && strings.HasPrefix(args, string(c)):
+					pattern.WriteRune(c)
+					args = args[utf8.RuneLen(c):]
+				default:
+					pattern.WriteRune(r)
+				}
+			}
+			re, err := regexp.Compile(pattern.String())
+			if err != nil {
+				return o, err
+			}
+			o.pattern = re
+		}
+	}
+	return o, nil
+}
+
+// sortKey is the part of a line which :sort sorts it by
+type sortKey struct {
+	text string
+	// number is the first number in text, for numeric sorts, and hasNumber
+	// whether there is one
+	number    int64
+	hasNumber bool
+}
+
+// numberInText matches a decimal number, for numeric sorts
+var numberInText = regexp.MustCompile(`-?\d+`)
+
+// key returns the key which l is sorted by, and whether it has one. Lines
+// which don't match the pattern have no key.
+func (o sortOptions) key(l string) (k sortKey, ok bool) {
+	k.text = l
+	if o.pattern != nil {
+		m := o.pattern.FindStringIndex(l)
+		if m == nil {
+			return k, false
+		}
+		k.text = l[m[1]:]
+		if o.byMatch {
+			k.text = l[m[0]:m[1]]
+		}
+	}
+	if o.ignoreCase {
+		k.text = strings.ToLower(k.text)
+	}
+	if o.numeric {
+		if n := numberInText.FindString(k.text); n != "" {
+			k.number, _ = strconv.ParseInt(n, 10, 64)
+			k.hasNumber = true
+		}
+	}
+	return k, true
+}
+
+// compare returns whether a sorts before b, and whether they are equal
+func (o sortOptions) compare(a, b sortKey) (less, equal bool) {
+	if o.numeric {
+		if a.hasNumber != b.hasNumber {
+			// Lines without a number sort first
+			return b.hasNumber, false
+		}
+		return a.number < b.number, a.number == b.number
+	}
+	return a.text < b.text, a.text == b.text
+}
+
+// Sort sorts the lines in r, like vim's :sort. The arguments are flags and a
+// pattern: ! reverses the order, n sorts by the first decimal number in each
+// line, i ignores case, and u keeps only the first of a run of equal lines.
+// A pattern, like /\d+:/, sorts by the text after its first match in each
+// line, or by the match if the r flag is given. Lines which don't match the
+// pattern are kept in their order before the sorted lines. Like vim, every
+// line is sorted if no range is given.
+func Sort(e *editarea.EditArea, r editarea.Range, args string) error {
+	o, err := parseSortOptions(args)
+	if err != nil {
+		return err
+	}
+	r = orWholeText(e, r)
+	type keyed struct {
+		line string
+		key  sortKey
+	}
+	var unmatched []string
+	var lines []keyed
+	for _, l := range rangeLines(e, r) {
+		if k, ok := o.key(l); ok {
+			lines = append(lines, keyed{l, k})
+		} else {
+			unmatched = append(unmatched, l)
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		a, b := lines[i].key, lines[j].key
+		if o.reverse {
+			a, b = b, a
+		}
+		less, _ := o.compare(a, b)
+		return less
+	})
+	sorted := unmatched
+	for i, l := range lines {
+		if o.unique && i > 0 {
+			if _, equal := o.compare(lines[i-1].key, l.key); equal {
+				continue
+			}
+		}
+		sorted = append(sorted, l.line)
+	}
+	replaceRange(e, r, sorted)
+	return nil
+}
+
+// trailingDelimiter returns whether delim ends the text before it, like a
+// comma, so it is written straight after the text with no space
+func trailingDelimiter(delim string) bool {
+	return delim == "," || delim == ":" || delim == ";"
+}
+
+// Align lines up the columns of the lines in r which are separated by the
+// delimiter given as the argument, like "=" or "|", by padding each column
+// to the width of its widest cell. A space is put either side of each
+// delimiter, except before a comma, colon or semicolon, which stay after the
+// text they end. Lines without the delimiter are left as they are. Every
+// line is aligned if no range is given.
+func Align(e *editarea.EditArea, r editarea.Range, args string) error {
+	delim := args
+	if delim == "" {
+		return fmt.Errorf("argument required")
+	}
+	r = orWholeText(e, r)
+	lines := rangeLines(e, r)
+	cells := make([][]string, len(lines))
+	var widths []int
+	for i, l := range lines {
+		if !strings.Contains(l, delim) {
+			continue
+		}
+		cells[i] = strings.Split(l, delim)
+		for j, cell := range cells[i] {
+			// The first cell keeps its indentation
+			cell = strings.TrimRightFunc(cell, unicode.IsSpace)
+			if j > 0 {
+				cell = strings.TrimLeftFunc(cell, unicode.IsSpace)
+			}
+			cells[i][j] = cell
+			if j == len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := utf8.RuneCountInString(cell); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+	trailing := trailingDelimiter(delim)
+	for i, row := range cells {
+		if row == nil {
+			continue
+		}
+		var b strings.Builder
+		for j, cell := range row {
+			last := j == len(row)-1
+			if last {
+				b.WriteString(cell)
+				break
+			}
+			padding := strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell))
+			if trailing {
+				b.WriteString(cell + delim + padding + " ")
+			} else {
+				b.WriteString(cell + padding + " " + delim + " ")
+			}
+		}
+		lines[i] = strings.TrimRightFunc(b.String(), unicode.IsSpace)
+	}
+	replaceRange(e, r, lines)
+	return nil
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddExCommand("sort", Sort)
+	editarea.AddExCommand("align", Align)
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   string
+		want   string
+	}{
+		{"sorting", "c\na\nb", "%sort", "a\nb\nc"},
+		{"reversing", "c\na\nb", "%sort!", "c\nb\na"},
+		{"a range", "c\nb\na", "1,2sort", "b\nc\na"},
+		{"without a range", "c\nb\na", "sort", "a\nb\nc"},
+		{"numbers", "x10\nx9\ny\nx-1", "%sort n", "y\nx-1\nx9\nx10"},
+		{"ignoring case", "b\nA\na\nB", "%sort i", "A\na\nb\nB"},
+		{"unique lines", "b\na\nb\na", "%sort u", "a\nb"},
+		{"unique ignoring case", "a\nA\nb", "%sort ui", "a\nb"},
+		{"after a pattern", "x3 b\ny1 c\nz2 a", `%sort /\d /`, "z2 a\nx3 b\ny1 c"},
+		{"by a pattern", "x3 b\ny1 c\nz2 a", `%sort /\d/ r`, "y1 c\nz2 a\nx3 b"},
+		{"unmatched lines", "b1\nz\na2", `%sort /\d/ r`, "z\nb1\na2"},
+		{"escaped delimiters", "a/2\nb/1", `%sort /\// n`, "b/1\na/2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			assert.NoError(t, e.RunCommandLine(tt.line))
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+
+	e := newTestEditArea("a", area.Point{X: 0, Y: 0})
+	assert.Error(t, e.RunCommandLine("sort x"))
+	assert.Error(t, e.RunCommandLine("sort /(/"))
+}
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   string
+		want   string
+	}{
+		{"equals", "a = 1\nfoo = 2", "%align =", "a   = 1\nfoo = 2"},
+		{"keeping indentation", "\ta=1\n\tfoo=2", "%align =", "\ta   = 1\n\tfoo = 2"},
+		{"without a range", "a = 1\nfoo = 2", "align =", "a   = 1\nfoo = 2"},
+		{"many columns", "a|bb|c\naaa|b|c", "%align |", "a   | bb | c\naaa | b  | c"},
+		{"colons", "a: 1\nfoo: 2", "%align :", "a:   1\nfoo: 2"},
+		{"lines without the delimiter", "a = 1\n}\nfoo = 2", "%align =", "a   = 1\n}\nfoo = 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			assert.NoError(t, e.RunCommandLine(tt.line))
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+
+	e := newTestEditArea("a", area.Point{X: 0, Y: 0})
+	assert.Error(t, e.RunCommandLine("align"))
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+)
+
+// Record starts recording keys into the register typed next, or stops
+// recording if keys are already being recorded
+func Record(e *editarea.EditArea) {
+	if e.Recording() != 0 {
+		e.StopRecording()
+		return
+	}
+	e.ReadChar(func(e *editarea.EditArea) {
+		if err := e.StartRecording(e.Char()); err != nil {
+			e.SetMessage("%s", err)
+		}
+	})
+}
+
+// Replay replays the keys in the register typed next
+func Replay(e *editarea.EditArea) {
+	e.ReadChar(func(e *editarea.EditArea) {
+		if err := e.ReplayMacro(e.Char(), e.Count()); err != nil {
+			e.SetMessage("%s", err)
+		}
+	})
+}
+
+// Normal runs its arguments as normal mode keys on each line in r, starting
+// at the start of the line. Lines added or deleted by the keys are allowed
+// for, so ":%normal dd" deletes every line.
+func Normal(e *editarea.EditArea, r editarea.Range, args string) error {
+	end := r.End
+	for row := r.Start; row <= end && row < e.Text().Length(); row++ {
+		length := e.Text().Length()
+		e.SetCursor(area.Point{X: 0, Y: row})
+		if err := e.RunKeys(args); err != nil {
+			return err
+		}
+		added := e.Text().Length() - length
+		row += added
+		end += added
+	}
+	return nil
+}
+
+// Repeat repeats the last change, with the count typed before it, if any,
+// instead of the change's own
+func Repeat(e *editarea.EditArea) {
+	count := 0
+	if e.HasCount() {
+		count = e.Count()
+	}
+	if err := e.RepeatChange(count); err != nil {
+		e.SetMessage("%s", err)
+	}
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddNormalModeCommand("i", Insert)
+	editarea.AddNormalModeCommand("u", Undo)
+	editarea.AddNormalModeCommand("q", Record)
+	editarea.AddNormalModeCommand("@", Replay)
+	editarea.AddInsertModeCommand(tcell.KeyESC, NormalMode)
+	editarea.AddNormalModeCommand(".", Repeat)
+	editarea.AddNormalModeCommand("gX", func(e *editarea.EditArea) { e.Paste("X") })
+	editarea.AddExCommand("normal", Normal)
+}
+
+// typeNamedKeys sends keys to e, which are written as they are in macros,
+// like "i<Esc>"
+func typeNamedKeys(e *editarea.EditArea, keys string) {
+	for _, ev := range editarea.ParseKeys(keys) {
+		e.HandleEvent(ev)
+	}
+}
+
+func TestMacros(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		keys   string
+		want   string
+	}{
+		{"recording", "ab\ncd\nef", "qaxjq", "b\ncd\nef"},
+		{"replaying", "ab\ncd\nef", "qaxjq@a", "b\nd\nef"},
+		{"replaying the last macro", "ab\ncd\nef", "qaxjq@a@@", "b\nd\nf"},
+		{"replaying with a count", "abcd", "qaxq2@a", "d"},
+		{"inserting", "a\nb", "qai-<Esc>jq@a", "-a\n-b"},
+		{"recursive macros", "ab\ncd\nef\ngh", "qaqqaxj@aq@a", "b\nd\nf\nh"},
+		{"appending to a macro", "abcd", "qaxqqAxq@a", ""},
+		{"stopping when a motion fails", "ab\ncd", "qajxq5@a", "ab\nd"},
+		{"editing a macro as text", "x\nabc", `"byyj@b`, "x\nbc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+			assert.Equal(t, editarea.ModeNormal, e.Mode)
+		})
+	}
+
+	e := newTestEditArea("a", area.Point{X: 0, Y: 0})
+	typeNamedKeys(e, "qai<lt><Esc>q")
+	r, _ := e.Register('a')
+	assert.Equal(t, "i<lt><Esc>", r.Text)
+	assert.Equal(t, rune(0), e.Recording())
+}
+
+func TestNormal(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"one line", "normal x", "b\nbc\ncd\nde"},
+		{"a range", "2,3normal x", "ab\nc\nd\nde"},
+		{"deleting lines", "%normal dd", ""},
+		{"adding lines", "1,2normal yyp", "ab\nab\nbc\nbc\ncd\nde"},
+		{"ending in insert mode", "%normal i-", "-ab\n-bc\n-cd\n-de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea("ab\nbc\ncd\nde", area.Point{X: 0, Y: 0})
+			assert.NoError(t, e.RunCommandLine(tt.line))
+			assert.Equal(t, tt.want, e.Text().String())
+			assert.Equal(t, editarea.ModeNormal, e.Mode)
+		})
+	}
+}
+
+func TestMacroIsOneUndoStep(t *testing.T) {
+	e := newTestEditArea("ab\ncd\nef", area.Point{X: 0, Y: 0})
+	a := area.Area{End: area.Point{X: 80, Y: 10}}
+	assert.NoError(t, e.SetRegister('q', editarea.Register{Text: "xj"}))
+	typeNamedKeys(e, "3@q")
+	e.Draw(a)
+	assert.Equal(t, "b\nd\nf", e.Text().String())
+	typeNamedKeys(e, "u")
+	assert.Equal(t, "ab\ncd\nef", e.Text().String())
+
+	assert.NoError(t, e.RunCommandLine("%normal @q"))
+	e.Draw(a)
+	typeNamedKeys(e, "u")
+	assert.Equal(t, "ab\ncd\nef", e.Text().String())
+}
+
+func TestUndoWithoutDrawing(t *testing.T) {
+	e := newTestEditArea("abcd", area.Point{X: 0, Y: 0})
+	typeNamedKeys(e, "xxu")
+	assert.Equal(t, "bcd", e.Text().String())
+	typeNamedKeys(e, "u")
+	assert.Equal(t, "abcd", e.Text().String())
+
+	typeNamedKeys(e, "ixy<CR>z<Esc>u")
+	assert.Equal(t, "abcd", e.Text().String())
+}
+
+func TestRepeat(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		keys   string
+		want   string
+	}{
+		{"x", "abcd", "x.", "cd"},
+		{"a count", "abcdefg", "2x.", "efg"},
+		{"a new count", "abcdefg", "2x3.", "fg"},
+		{"an operator and motion", "a b c d", "dw.", "c d"},
+		{"an operator with counts", "a b c d e f g", "2dw.", "e f g"},
+		{"a linewise operator", "a\nb\nc", "dd.", "c"},
+		{"a find motion", "a.b.c", "df..", "c"},
+		{"inserted text", "a\nb", "i-<Esc>j0.", "-a\n-b"},
+		{"a change", "foo bar", "cwx<Esc>w.", "x x"},
+		{"a register", "a b c", `"ayl"axw.`, "  c"},
+		{"a plugin command", "a", "gX.", "XXa"},
+		{"visual mode", "abcdef", "vld.", "ef"},
+		{"visual line mode", "a\nb\nc\nd", "Vjd.", ""},
+		{"not undo", "abc", "xu.", "bc"},
+		{"after a macro", "abc\nd", "qaxjq@ak.", "c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+)
+
+// SetMark sets the mark typed next at the cursor
+func SetMark(e *editarea.EditArea) {
+	e.ReadChar(func(e *editarea.EditArea) {
+		if err := e.SetMark(e.Char()); err != nil {
+			e.SetMessage("%s", err)
+		}
+	})
+}
+
+// GotoMark moves to the mark typed after the motion's keys. A global mark in
+// another file opens that file instead.
+func GotoMark(e *editarea.EditArea) (area.Point, bool) {
+	p, ok, err := e.JumpToMark(e.Char())
+	if err != nil {
+		e.SetMessage("%s", err)
+	}
+	return p, ok
+}
+
+// GotoMarkLine moves to the first non-blank rune of the line of the mark
+// typed after the motion's keys
+func GotoMarkLine(e *editarea.EditArea) (area.Point, bool) {
+	p, ok := GotoMark(e)
+	if !ok {
+		return p, false
+	}
+	return firstNonBlank(e.Text(), p.Y), true
+}
+
+// JumpOlder moves back through the jump list
+func JumpOlder(e *editarea.EditArea) {
+	if err := e.JumpOlder(e.Count()); err != nil {
+		e.SetMessage("%s", err)
+	}
+}
+
+// JumpNewer moves forward through the jump list
+func JumpNewer(e *editarea.EditArea) {
+	if err := e.JumpNewer(e.Count()); err != nil {
+		e.SetMessage("%s", err)
+	}
+}
+
+// ChangeOlder moves back through the change list
+func ChangeOlder(e *editarea.EditArea) {
+	if err := e.ChangeOlder(e.Count()); err != nil {
+		e.SetMessage("%s", err)
+	}
+}
+
+// ChangeNewer moves forward through the change list
+func ChangeNewer(e *editarea.EditArea) {
+	if err := e.ChangeNewer(e.Count()); err != nil {
+		e.SetMessage("%s", err)
+	}
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	// Don't read or write the marks saved by the editor
+	editarea.SetMarksPath("")
+	editarea.AddNormalModeCommand("m", SetMark)
+	editarea.AddMotion("`", editarea.Motion{Move: GotoMark, Kind: editarea.Exclusive, TakesChar: true, Jump: true})
+	editarea.AddMotion("'", editarea.Motion{Move: GotoMarkLine, Kind: editarea.Linewise, TakesChar: true, Jump: true})
+	editarea.AddNormalModeCommand("<C-o>", JumpOlder)
+	editarea.AddNormalModeCommand("<C-i>", JumpNewer)
+	editarea.AddNormalModeCommand("g;", ChangeOlder)
+	editarea.AddNormalModeCommand("g,", ChangeNewer)
+}
+
+func TestMarks(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		keys   string
+		want   area.Point
+		text   string
+	}{
+		{"jumping to a mark", "ab\n  cd\nef", "jlmaG`a", area.Point{X: 1, Y: 1}, ""},
+		{"jumping to a mark's line", "ab\n  cd\nef", "jlmaG'a", area.Point{X: 2, Y: 1}, ""},
+		{"jumping back", "ab\ncd\nef", "lG``", area.Point{X: 1, Y: 0}, ""},
+		{"deleting to a mark", "ab\ncd\nef", "majd'a", area.Point{X: 0, Y: 0}, "ef"},
+		{"marks follow their lines", "ab\ncd\nef", "jmakddG'a", area.Point{X: 0, Y: 0}, "cd\nef"},
+		{"the jump list", "a\nb\nc\nd", "G<C-o>", area.Point{X: 0, Y: 0}, ""},
+		{"going forward through the jump list", "a\nb\nc\nd", "3GG<C-o><C-o><C-i>", area.Point{X: 0, Y: 2}, ""},
+		{"the change list", "ab\ncd\nef", "xGxggg;g;", area.Point{X: 0, Y: 0}, ""},
+		{"going forward through the change list", "ab\ncd\nef", "xGxggg;g;g,", area.Point{X: 0, Y: 2}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Cursor())
+			if tt.text != "" {
+				assert.Equal(t, tt.text, e.Text().String())
+			}
+		})
+	}
+}
@@ -0,0 +1,544 @@
+package commands
+
+import (
+	"unicode"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/jamesroutley/fuji/text"
+)
+
+// lineRunes returns the runes of row of t
+func lineRunes(t *text.Text, row int) []rune {
+	return []rune(t.Line(row).String())
+}
+
+// charAt returns the rune at p in t, or '\n' at the end of a line
+func charAt(t *text.Text, p area.Point) rune {
+	runes := lineRunes(t, p.Y)
+	if p.X >= len(runes) {
+		return '\n'
+	}
+	return runes[p.X]
+}
+
+// next returns the position after p in t. The end of each line but the last
+// is a position, holding a newline. ok is false at the end of the text.
+func next(t *text.Text, p area.Point) (area.Point, bool) {
+	length := t.LineLength(p.Y)
+	switch {
+	case p.X+1 < length:
+		return area.Point{X: p.X + 1, Y: p.Y}, true
+	case p.X+1 == length && p.Y+1 < t.Length():
+		return area.Point{X: p.X + 1, Y: p.Y}, true
+	case p.Y+1 < t.Length():
+		return area.Point{X: 0, Y: p.Y + 1}, true
+	}
+	return p, false
+}
+
+// prev returns the position before p in t. ok is false at the start of the
+// text.
+func prev(t *text.Text, p area.Point) (area.Point, bool) {
+	switch {
+	case p.X > 0:
+		return area.Point{X: p.X - 1, Y: p.Y}, true
+	case p.Y > 0:
+		return area.Point{X: t.LineLength(p.Y - 1), Y: p.Y - 1}, true
+	}
+	return p, false
+}
+
+// emptyLine returns whether p is on an empty line
+func emptyLine(t *text.Text, p area.Point) bool {
+	return t.LineLength(p.Y) == 0
+}
+
+// class returns the class of r for word motions. Words are runs of runes of
+// the same class, other than whitespace, which is class 0. If bigWord is
+// true, all non-whitespace runes are in the same class, as for W.
+func class(r rune, bigWord bool) int {
+	switch {
+	case r == '\n' || unicode.IsSpace(r):
+		return 0
+	case bigWord:
+		return 1
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		// Like vim, ideographs aren't grouped with other letters
+		return 3
+	case r == '_' || unicode.In(r, unicode.Letter, unicode.Digit, unicode.Mark):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// cursor returns the position of the cursor, moved onto the last rune of its
+// line if it is past it
+func cursor(e *editarea.EditArea) area.Point {
+	p := e.Cursor()
+	if length := e.Text().LineLength(p.Y); p.X >= length {
+		p.X = length - 1
+	}
+	if p.X < 0 {
+		p.X = 0
+	}
+	return p
+}
+
+// repeat applies move count times from the cursor. It fails if the first
+// move fails, and stops early if a later move fails.
+func repeat(e *editarea.EditArea, move func(t *text.Text, p area.Point) (area.Point, bool)) (area.Point, bool) {
+	p, ok := move(e.Text(), cursor(e))
+	if !ok {
+		return p, false
+	}
+	for i := 1; i < e.Count(); i++ {
+		if p, ok = move(e.Text(), p); !ok {
+			break
+		}
+	}
+	return p, true
+}
+
+// wordForward returns the start of the word after p. If there isn't one, it
+// returns the end of the text, after its last rune.
+func wordForward(t *text.Text, p area.Point, bigWord bool) (area.Point, bool) {
+	last := t.Length() - 1
+	end := area.Point{X: t.LineLength(last), Y: last}
+	if p == end {
+		return p, false
+	}
+	start := class(charAt(t, p), bigWord)
+	q, ok := next(t, p)
+	if !ok {
+		return end, true
+	}
+	if start != 0 {
+		for class(charAt(t, q), bigWord) == start {
+			if q, ok = next(t, q); !ok {
+				return end, true
+			}
+		}
+	}
+	for class(charAt(t, q), bigWord) == 0 {
+		// Empty lines count as words
+		if q.X == 0 && emptyLine(t, q) {
+			return q, true
+		}
+		if q, ok = next(t, q); !ok {
+			return end, true
+		}
+	}
+	return q, true
+}
+
+// wordEnd returns the end of the word after p
+func wordEnd(t *text.Text, p area.Point, bigWord bool) (area.Point, bool) {
+	q, ok := next(t, p)
+	if !ok {
+		return p, false
+	}
+	for class(charAt(t, q), bigWord) == 0 {
+		if q, ok = next(t, q); !ok {
+			return q, true
+		}
+	}
+	c := class(charAt(t, q), bigWord)
+	for {
+		r, ok := next(t, q)
+		if !ok || class(charAt(t, r), bigWord) != c {
+			return q, true
+		}
+		q = r
+	}
+}
+
+// wordBackward returns the start of the word before p
+func wordBackward(t *text.Text, p area.Point, bigWord bool) (area.Point, bool) {
+	q, ok := prev(t, p)
+	if !ok {
+		return p, false
+	}
+	for class(charAt(t, q), bigWord) == 0 {
+		if q.X == 0 && emptyLine(t, q) {
+			return q, true
+		}
+		if q, ok = prev(t, q); !ok {
+			return q, true
+		}
+	}
+	c := class(charAt(t, q), bigWord)
+	for {
+		r, ok := prev(t, q)
+		if !ok || class(charAt(t, r), bigWord) != c {
+			return q, true
+		}
+		q = r
+	}
+}
+
+// wordEndBackward returns the end of the word before p
+func wordEndBackward(t *text.Text, p area.Point, bigWord bool) (area.Point, bool) {
+	start := class(charAt(t, p), bigWord)
+	q, ok := prev(t, p)
+	if !ok {
+		return p, false
+	}
+	if start != 0 {
+		for class(charAt(t, q), bigWord) == start {
+			if q, ok = prev(t, q); !ok {
+				return q, true
+			}
+		}
+	}
+	for class(charAt(t, q), bigWord) == 0 {
+		if q.X == 0 && emptyLine(t, q) {
+			return q, true
+		}
+		if q, ok = prev(t, q); !ok {
+			return q, true
+		}
+	}
+	return q, true
+}
+
+// words returns a motion which moves count times with move
+func words(move func(*text.Text, area.Point, bool) (area.Point, bool), bigWord bool) func(*editarea.EditArea) (area.Point, bool) {
+	return func(e *editarea.EditArea) (area.Point, bool) {
+		return repeat(e, func(t *text.Text, p area.Point) (area.Point, bool) {
+			return move(t, p, bigWord)
+		})
+	}
+}
+
+var (
+	// WordForward moves to the start of the next word
+	WordForward = wordsForward(false)
+	// BigWordForward moves to the start of the next WORD
+	BigWordForward = wordsForward(true)
+	// WordEnd moves to the end of the word
+	WordEnd = words(wordEnd, false)
+	// BigWordEnd moves to the end of the WORD
+	BigWordEnd = words(wordEnd, true)
+	// WordBackward moves to the start of the word
+	WordBackward = words(wordBackward, false)
+	// BigWordBackward moves to the start of the WORD
+	BigWordBackward = words(wordBackward, true)
+	// WordEndBackward moves to the end of the previous word
+	WordEndBackward = words(wordEndBackward, false)
+	// BigWordEndBackward moves to the end of the previous WORD
+	BigWordEndBackward = words(wordEndBackward, true)
+)
+
+// wordsForward returns a motion which moves count words, or WORDs if bigWord
+// is set, forward. Like vim, after c on a word it stops at the end of the
+// word instead, so that cw doesn't change the whitespace after the word.
+func wordsForward(bigWord bool) func(*editarea.EditArea) (area.Point, bool) {
+	return func(e *editarea.EditArea) (area.Point, bool) {
+		t, start := e.Text(), cursor(e)
+		if e.Operator() == "c" && class(charAt(t, start), bigWord) != 0 {
+			p := start
+			for i := 0; i < e.Count(); i++ {
+				if next, ok := next(t, p); i == 0 && (!ok || class(charAt(t, next), bigWord) != class(charAt(t, p), bigWord)) {
+					// The cursor is already at the end of the first word
+					continue
+				}
+				q, ok := wordEnd(t, p, bigWord)
+				if !ok {
+					break
+				}
+				p = q
+			}
+			return area.Point{X: p.X + 1, Y: p.Y}, true
+		}
+		p, ok := repeat(e, func(t *text.Text, p area.Point) (area.Point, bool) {
+			return wordForward(t, p, bigWord)
+		})
+		if length := t.LineLength(p.Y); e.Operator() == "" && length > 0 && p.X >= length {
+			// There's no next word, so the cursor stops on the last rune
+			p.X = length - 1
+		}
+		return p, ok && p != start
+	}
+}
+
+// find is a search for a character on the cursor's line, made by f, F, t or
+// T
+type find struct {
+	forward bool
+	// till stops before the character, rather than on it
+	till bool
+	char rune
+}
+
+// lastFind is the last search made by f, F, t or T, which is repeated by ;
+// and ,
+var lastFind *find
+
+// findChar returns the position found by searching count times for f.
+// repeated is true when the search is repeated by ; or ,.
+func findChar(e *editarea.EditArea, f find, repeated bool) (area.Point, bool) {
+	p := cursor(e)
+	runes := lineRunes(e.Text(), p.Y)
+	dir := 1
+	if !f.forward {
+		dir = -1
+	}
+	x := p.X
+	if f.till && repeated {
+		// Don't get stuck on the character next to the one found last time
+		x += dir
+	}
+	for i := 0; i < e.Count(); i++ {
+		x += dir
+		for x >= 0 && x < len(runes) && runes[x] != f.char {
+			x += dir
+		}
+		if x < 0 || x >= len(runes) {
+			return p, false
+		}
+	}
+	if f.till {
+		x -= dir
+	}
+	return area.Point{X: x, Y: p.Y}, true
+}
+
+// finder returns a motion which searches for the character typed after it
+func finder(forward, till bool) func(*editarea.EditArea) (area.Point, bool) {
+	return func(e *editarea.EditArea) (area.Point, bool) {
+		f := find{forward: forward, till: till, char: e.Char()}
+		lastFind = &f
+		return findChar(e, f, false)
+	}
+}
+
+var (
+	// FindForward moves to the next occurrence of a character on the line
+	FindForward = finder(true, false)
+	// FindBackward moves to the previous occurrence of a character on the line
+	FindBackward = finder(false, false)
+	// TillForward moves to just before the next occurrence of a character
+	TillForward = finder(true, true)
+	// TillBackward moves to just after the previous occurrence of a character
+	TillBackward = finder(false, true)
+)
+
+// RepeatFind repeats the last f, F, t or T
+func RepeatFind(e *editarea.EditArea) (area.Point, bool) {
+	if lastFind == nil {
+		return e.Cursor(), false
+	}
+	return findChar(e, *lastFind, true)
+}
+
+// RepeatFindReversed repeats the last f, F, t or T in the opposite direction
+func RepeatFindReversed(e *editarea.EditArea) (area.Point, bool) {
+	if lastFind == nil {
+		return e.Cursor(), false
+	}
+	f := *lastFind
+	f.forward = !f.forward
+	return findChar(e, f, true)
+}
+
+// RepeatFindKind returns the kind of the motion made by ; or ,. Like f and
+// F, searches forwards are inclusive and searches backwards exclusive.
+func RepeatFindKind(reversed bool) func(*editarea.EditArea) editarea.MotionKind {
+	return func(e *editarea.EditArea) editarea.MotionKind {
+		if lastFind != nil && lastFind.forward != reversed {
+			return editarea.Inclusive
+		}
+		return editarea.Exclusive
+	}
+}
+
+// firstNonBlank returns the position of the first non-blank rune on row
+func firstNonBlank(t *text.Text, row int) area.Point {
+	runes := lineRunes(t, row)
+	x := 0
+	for x < len(runes)-1 && unicode.IsSpace(runes[x]) {
+		x++
+	}
+	return area.Point{X: x, Y: row}
+}
+
+// clampRow returns row, moved into t if it is outside it
+func clampRow(t *text.Text, row int) int {
+	if row >= t.Length() {
+		row = t.Length() - 1
+	}
+	if row < 0 {
+		row = 0
+	}
+	return row
+}
+
+// Left moves count runes left on the line
+func Left(e *editarea.EditArea) (area.Point, bool) {
+	p := cursor(e)
+	if p.X == 0 {
+		return p, false
+	}
+	p.X -= e.Count()
+	if p.X < 0 {
+		p.X = 0
+	}
+	return p, true
+}
+
+// Right moves count runes right on the line. After an operator, it can
+// move past the last rune, so that dl deletes it.
+func Right(e *editarea.EditArea) (area.Point, bool) {
+	p := cursor(e)
+	last := e.Text().LineLength(p.Y) - 1
+	if e.Operator() != "" {
+		last++
+	}
+	if p.X >= last {
+		return p, false
+	}
+	p.X += e.Count()
+	if p.X > last {
+		p.X = last
+	}
+	return p, true
+}
+
+// Down moves count lines down, keeping the column
+func Down(e *editarea.EditArea) (area.Point, bool) {
+	p := e.Cursor()
+	if p.Y >= e.Text().Length()-1 {
+		return p, false
+	}
+	p.Y = clampRow(e.Text(), p.Y+e.Count())
+	return p, true
+}
+
+// Up moves count lines up, keeping the column
+func Up(e *editarea.EditArea) (area.Point, bool) {
+	p := e.Cursor()
+	if p.Y == 0 {
+		return p, false
+	}
+	p.Y = clampRow(e.Text(), p.Y-e.Count())
+	return p, true
+}
+
+// LineStart moves to the start of the line
+func LineStart(e *editarea.EditArea) (area.Point, bool) {
+	return area.Point{X: 0, Y: e.Cursor().Y}, true
+}
+
+// LineFirstNonBlank moves to the first non-blank rune of the line
+func LineFirstNonBlank(e *editarea.EditArea) (area.Point, bool) {
+	return firstNonBlank(e.Text(), e.Cursor().Y), true
+}
+
+// LineFirstNonBlankDown moves to the first non-blank rune of the line count
+// - 1 lines down
+func LineFirstNonBlankDown(e *editarea.EditArea) (area.Point, bool) {
+	row := clampRow(e.Text(), e.Cursor().Y+e.Count()-1)
+	return firstNonBlank(e.Text(), row), true
+}
+
+// LineEnd moves to the end of the line count - 1 lines down
+func LineEnd(e *editarea.EditArea) (area.Point, bool) {
+	row := clampRow(e.Text(), e.Cursor().Y+e.Count()-1)
+	x := e.Text().LineLength(row) - 1
+	if x < 0 {
+		x = 0
+	}
+	return area.Point{X: x, Y: row}, true
+}
+
+// GotoFirstLine moves to the first line, or to line count if there is one
+func GotoFirstLine(e *editarea.EditArea) (area.Point, bool) {
+	row := 0
+	if e.HasCount() {
+		row = clampRow(e.Text(), e.Count()-1)
+	}
+	return firstNonBlank(e.Text(), row), true
+}
+
+// GotoLastLine moves to the last line, or to line count if there is one
+func GotoLastLine(e *editarea.EditArea) (area.Point, bool) {
+	row := e.Text().Length() - 1
+	if e.HasCount() {
+		row = clampRow(e.Text(), e.Count()-1)
+	}
+	return firstNonBlank(e.Text(), row), true
+}
+
+// WindowTop moves to the line count - 1 lines below the top of the display,
+// keeping scrolloff lines above the cursor unless the top of the text is
+// displayed
+func WindowTop(e *editarea.EditArea) (area.Point, bool) {
+	first, _ := e.VisibleRows()
+	row := first + e.Count() - 1
+	if first > 0 && row < first+e.ScrollOff() {
+		row = first + e.ScrollOff()
+	}
+	return firstNonBlank(e.Text(), clampRow(e.Text(), row)), true
+}
+
+// WindowMiddle moves to the line in the middle of the display
+func WindowMiddle(e *editarea.EditArea) (area.Point, bool) {
+	first, last := e.VisibleRows()
+	return firstNonBlank(e.Text(), first+(last-first)/2), true
+}
+
+// WindowBottom moves to the line count - 1 lines above the bottom of the
+// display, keeping scrolloff lines below the cursor unless the bottom of the
+// text is displayed
+func WindowBottom(e *editarea.EditArea) (area.Point, bool) {
+	_, last := e.VisibleRows()
+	row := last - e.Count() + 1
+	if last < e.Text().Length()-1 && row > last-e.ScrollOff() {
+		row = last - e.ScrollOff()
+	}
+	return firstNonBlank(e.Text(), clampRow(e.Text(), row)), true
+}
+
+// ParagraphForward moves to the next empty line after a paragraph
+func ParagraphForward(e *editarea.EditArea) (area.Point, bool) {
+	t := e.Text()
+	row := e.Cursor().Y
+	if row >= t.Length()-1 {
+		return e.Cursor(), false
+	}
+	for i := 0; i < e.Count() && row < t.Length()-1; i++ {
+		// Skip empty lines, then the paragraph
+		for row < t.Length()-1 && t.LineLength(row) == 0 {
+			row++
+		}
+		for row < t.Length()-1 && t.LineLength(row) != 0 {
+			row++
+		}
+	}
+	x := 0
+	if t.LineLength(row) != 0 {
+		// There's no empty line after the paragraph, so go to the end
+		x = t.LineLength(row) - 1
+	}
+	return area.Point{X: x, Y: row}, true
+}
+
+// ParagraphBackward moves to the previous empty line before a paragraph
+func ParagraphBackward(e *editarea.EditArea) (area.Point, bool) {
+	t := e.Text()
+	row := e.Cursor().Y
+	if row == 0 {
+		return e.Cursor(), false
+	}
+	for i := 0; i < e.Count() && row > 0; i++ {
+		for row > 0 && t.LineLength(row) == 0 {
+			row--
+		}
+		for row > 0 && t.LineLength(row) != 0 {
+			row--
+		}
+	}
+	return area.Point{X: 0, Y: row}, true
+}
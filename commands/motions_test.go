@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	motions := []struct {
+		keys string
+		move func(*editarea.EditArea) (area.Point, bool)
+		kind editarea.MotionKind
+	}{
+		{"h", Left, editarea.Exclusive}, {"l", Right, editarea.Exclusive},
+		{"j", Down, editarea.Linewise}, {"k", Up, editarea.Linewise},
+		{"0", LineStart, editarea.Exclusive}, {"^", LineFirstNonBlank, editarea.Exclusive},
+		{"_", LineFirstNonBlankDown, editarea.Linewise}, {"$", LineEnd, editarea.Inclusive},
+		{"w", WordForward, editarea.Exclusive}, {"W", BigWordForward, editarea.Exclusive},
+		{"e", WordEnd, editarea.Inclusive}, {"E", BigWordEnd, editarea.Inclusive},
+		{"b", WordBackward, editarea.Exclusive}, {"B", BigWordBackward, editarea.Exclusive},
+		{"ge", WordEndBackward, editarea.Inclusive}, {"gE", BigWordEndBackward, editarea.Inclusive},
+		{"gg", GotoFirstLine, editarea.Linewise}, {"G", GotoLastLine, editarea.Linewise},
+		{"H", WindowTop, editarea.Linewise}, {"M", WindowMiddle, editarea.Linewise},
+		{"L", WindowBottom, editarea.Linewise},
+		{"{", ParagraphBackward, editarea.Exclusive}, {"}", ParagraphForward, editarea.Exclusive},
+	}
+	jumps := map[string]bool{"gg": true, "G": true, "H": true, "M": true, "L": true, "{": true, "}": true}
+	for _, m := range motions {
+		editarea.AddMotion(m.keys, editarea.Motion{Move: m.move, Kind: m.kind, Jump: jumps[m.keys], LineEnd: m.keys == "$"})
+	}
+	editarea.AddMotion("f", editarea.Motion{Move: FindForward, Kind: editarea.Inclusive, TakesChar: true})
+	editarea.AddMotion("F", editarea.Motion{Move: FindBackward, Kind: editarea.Exclusive, TakesChar: true})
+	editarea.AddMotion("t", editarea.Motion{Move: TillForward, Kind: editarea.Inclusive, TakesChar: true})
+	editarea.AddMotion("T", editarea.Motion{Move: TillBackward, Kind: editarea.Exclusive, TakesChar: true})
+	editarea.AddMotion(";", editarea.Motion{Move: RepeatFind, KindOf: RepeatFindKind(false)})
+	editarea.AddMotion(",", editarea.Motion{Move: RepeatFindReversed, KindOf: RepeatFindKind(true)})
+}
+
+// newTestEditArea returns an EditArea containing source, with the cursor at
+// start
+func newTestEditArea(source string, start area.Point) *editarea.EditArea {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		panic(err)
+	}
+	screen.SetSize(80, 25)
+	e := editarea.New(screen, "test.txt", bytes.NewBufferString(source))
+	e.Draw(area.Area{End: area.Point{X: 80, Y: 10}})
+	e.SetCursor(start)
+	return e
+}
+
+// typeKeys sends keys to e, one rune at a time
+func typeKeys(e *editarea.EditArea, keys string) {
+	for _, r := range keys {
+		e.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+}
+
+func TestMotions(t *testing.T) {
+	source := "foo.bar(baz) qux\n  x_y, 世界abc\n\nlast  word"
+	tests := []struct {
+		name  string
+		start area.Point
+		keys  string
+		want  area.Point
+	}{
+		{"w stops at punctuation", area.Point{X: 0, Y: 0}, "w", area.Point{X: 3, Y: 0}},
+		{"w with a count", area.Point{X: 0, Y: 0}, "3w", area.Point{X: 7, Y: 0}},
+		{"w crosses lines", area.Point{X: 13, Y: 0}, "w", area.Point{X: 2, Y: 1}},
+		{"w stops at empty lines", area.Point{X: 12, Y: 1}, "w", area.Point{X: 0, Y: 2}},
+		{"w separates ideographs", area.Point{X: 7, Y: 1}, "w", area.Point{X: 9, Y: 1}},
+		{"w from the last word of a line", area.Point{X: 9, Y: 1}, "w", area.Point{X: 0, Y: 2}},
+		{"w at the end of the text", area.Point{X: 6, Y: 3}, "w", area.Point{X: 9, Y: 3}},
+		{"W skips punctuation", area.Point{X: 0, Y: 0}, "W", area.Point{X: 13, Y: 0}},
+		{"e", area.Point{X: 0, Y: 0}, "e", area.Point{X: 2, Y: 0}},
+		{"e from the end of a word", area.Point{X: 2, Y: 0}, "e", area.Point{X: 3, Y: 0}},
+		{"E", area.Point{X: 0, Y: 0}, "E", area.Point{X: 11, Y: 0}},
+		{"b", area.Point{X: 5, Y: 0}, "b", area.Point{X: 4, Y: 0}},
+		{"b crosses lines", area.Point{X: 2, Y: 1}, "b", area.Point{X: 13, Y: 0}},
+		{"b stops at empty lines", area.Point{X: 0, Y: 3}, "b", area.Point{X: 0, Y: 2}},
+		{"B", area.Point{X: 13, Y: 0}, "B", area.Point{X: 0, Y: 0}},
+		{"ge", area.Point{X: 5, Y: 0}, "ge", area.Point{X: 3, Y: 0}},
+		{"gE", area.Point{X: 13, Y: 0}, "gE", area.Point{X: 11, Y: 0}},
+		{"f", area.Point{X: 0, Y: 0}, "fa", area.Point{X: 5, Y: 0}},
+		{"f with a count", area.Point{X: 0, Y: 0}, "2fa", area.Point{X: 9, Y: 0}},
+		{"f without a match", area.Point{X: 0, Y: 0}, "fy", area.Point{X: 0, Y: 0}},
+		{"t", area.Point{X: 0, Y: 0}, "ta", area.Point{X: 4, Y: 0}},
+		{"F", area.Point{X: 15, Y: 0}, "Fo", area.Point{X: 2, Y: 0}},
+		{"T", area.Point{X: 15, Y: 0}, "To", area.Point{X: 3, Y: 0}},
+		{"; repeats f", area.Point{X: 0, Y: 0}, "fa;", area.Point{X: 9, Y: 0}},
+		{", reverses f", area.Point{X: 0, Y: 0}, "2fa,", area.Point{X: 5, Y: 0}},
+		{"; doesn't get stuck after t", area.Point{X: 0, Y: 0}, "ta;", area.Point{X: 8, Y: 0}},
+		{"0", area.Point{X: 4, Y: 1}, "0", area.Point{X: 0, Y: 1}},
+		{"^", area.Point{X: 6, Y: 1}, "^", area.Point{X: 2, Y: 1}},
+		{"_ with a count", area.Point{X: 6, Y: 0}, "2_", area.Point{X: 2, Y: 1}},
+		{"$", area.Point{X: 0, Y: 0}, "$", area.Point{X: 15, Y: 0}},
+		{"$ counts runes", area.Point{X: 0, Y: 1}, "$", area.Point{X: 11, Y: 1}},
+		{"gg", area.Point{X: 4, Y: 3}, "gg", area.Point{X: 0, Y: 0}},
+		{"gg with a count", area.Point{X: 4, Y: 3}, "2gg", area.Point{X: 2, Y: 1}},
+		{"G", area.Point{X: 4, Y: 0}, "G", area.Point{X: 0, Y: 3}},
+		{"G with a count", area.Point{X: 4, Y: 0}, "2G", area.Point{X: 2, Y: 1}},
+		{"H", area.Point{X: 4, Y: 3}, "H", area.Point{X: 0, Y: 0}},
+		{"L", area.Point{X: 4, Y: 0}, "L", area.Point{X: 0, Y: 3}},
+		{"M", area.Point{X: 4, Y: 0}, "M", area.Point{X: 2, Y: 1}},
+		{"}", area.Point{X: 4, Y: 0}, "}", area.Point{X: 0, Y: 2}},
+		{"{", area.Point{X: 4, Y: 3}, "{", area.Point{X: 0, Y: 2}},
+		{"l stops at the end of the line", area.Point{X: 14, Y: 0}, "5l", area.Point{X: 15, Y: 0}},
+		{"l counts runes", area.Point{X: 9, Y: 1}, "5l", area.Point{X: 11, Y: 1}},
+		{"h stops at the start of the line", area.Point{X: 2, Y: 1}, "5h", area.Point{X: 0, Y: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(source, tt.start)
+			typeKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Cursor())
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package commands
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/jamesroutley/fuji/text"
+)
+
+// flatText is a text flattened into a single slice of runes, with a newline
+// at the end of each line but the last, so that it can be searched across
+// lines
+type flatText struct {
+	runes []rune
+	// starts holds the offset of the start of each row
+	starts []int
+}
+
+// flatten returns t as a flatText
+func flatten(t *text.Text) flatText {
+	var f flatText
+	for row := 0; row < t.Length(); row++ {
+		if row > 0 {
+			f.runes = append(f.runes, '\n')
+		}
+		f.starts = append(f.starts, len(f.runes))
+		f.runes = append(f.runes, lineRunes(t, row)...)
+	}
+	return f
+}
+
+// offset returns the offset of p in f
+func (f flatText) offset(p area.Point) int {
+	return f.starts[p.Y] + p.X
+}
+
+// point returns the position of the offset i in f
+func (f flatText) point(i int) area.Point {
+	row := sort.SearchInts(f.starts, i+1) - 1
+	return area.Point{X: i - f.starts[row], Y: row}
+}
+
+// region returns the region of f from offset start up to end
+func (f flatText) region(start, end int) editarea.Region {
+	return editarea.Region{Start: f.point(start), End: f.point(end)}
+}
+
+// isLineBlank returns whether row of t contains only whitespace
+func isLineBlank(t *text.Text, row int) bool {
+	return strings.TrimSpace(t.Line(row).String()) == ""
+}
+
+// withSpace extends [start, end) of runes to include the whitespace after it
+// or, if there isn't any, the whitespace before it, as objects starting with
+// "a" do
+func withSpace(runes []rune, start, end int) (int, int) {
+	isSpace := func(i int) bool { return runes[i] == ' ' || runes[i] == '\t' }
+	if end < len(runes) && isSpace(end) {
+		for end < len(runes) && isSpace(end) {
+			end++
+		}
+		return start, end
+	}
+	for start > 0 && isSpace(start-1) {
+		start--
+	}
+	return start, end
+}
+
+// wordObject returns a text object for the word, or WORD if bigWord is set,
+// under the cursor. The object includes the whitespace around the word if
+// around is set.
+func wordObject(bigWord, around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		p := cursor(e)
+		runes := lineRunes(e.Text(), p.Y)
+		if len(runes) == 0 {
+			return editarea.Region{}, false
+		}
+		// run returns the end of the run of runes of the same class starting
+		// at i
+		run := func(i int) int {
+			c := class(runes[i], bigWord)
+			for i < len(runes) && class(runes[i], bigWord) == c {
+				i++
+			}
+			return i
+		}
+		c := class(runes[p.X], bigWord)
+		start := p.X
+		for start > 0 && class(runes[start-1], bigWord) == c {
+			start--
+		}
+		end := run(p.X)
+		for i := 1; i < e.Count() && end < len(runes); i++ {
+			end = run(end)
+		}
+		if around {
+			if c == 0 {
+				// On whitespace, the word after it is included
+				if end < len(runes) {
+					end = run(end)
+				}
+			} else {
+				start, end = withSpace(runes, start, end)
+			}
+		}
+		return editarea.Region{Start: area.Point{X: start, Y: p.Y}, End: area.Point{X: end, Y: p.Y}}, true
+	}
+}
+
+var (
+	// InnerWord selects the word under the cursor
+	InnerWord = wordObject(false, false)
+	// AroundWord selects the word under the cursor and the whitespace after it
+	AroundWord = wordObject(false, true)
+	// InnerBigWord selects the WORD under the cursor
+	InnerBigWord = wordObject(true, false)
+	// AroundBigWord selects the WORD under the cursor and the whitespace after
+	// it
+	AroundBigWord = wordObject(true, true)
+)
+
+// sentenceEnd matches the end of a sentence: a full stop, question mark or
+// exclamation mark, optionally followed by closing brackets and quotes, and
+// then whitespace
+var sentenceEnd = regexp.MustCompile(`[.!?][)\]"']*\s+`)
+
+// sentenceObject returns a text object for the sentence under the cursor.
+// Sentences don't continue past the paragraph they are in.
+func sentenceObject(around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		t := e.Text()
+		p := cursor(e)
+		if isLineBlank(t, p.Y) {
+			return editarea.Region{}, false
+		}
+		first, last := p.Y, p.Y
+		for first > 0 && !isLineBlank(t, first-1) {
+			first--
+		}
+		for last < t.Length()-1 && !isLineBlank(t, last+1) {
+			last++
+		}
+		f := flatten(t)
+		start, end := f.starts[first], f.starts[last]+t.LineLength(last)
+		paragraph := string(f.runes[start:end])
+
+		// Each sentence runs from its start to the start of the next one,
+		// including the whitespace between them
+		starts := []int{start}
+		ends := []int{}
+		for _, m := range sentenceEnd.FindAllStringIndex(paragraph, -1) {
+			body := start + len([]rune(paragraph[:m[0]])) + 1
+			next := start + len([]rune(paragraph[:m[1]]))
+			for body < next && strings.ContainsRune(`)]"'`, f.runes[body]) {
+				body++
+			}
+			ends = append(ends, body)
+			starts = append(starts, next)
+		}
+		ends = append(ends, end)
+		if starts[len(starts)-1] == end {
+			// The paragraph ends with whitespace
+			starts = starts[:len(starts)-1]
+			ends = ends[:len(ends)-1]
+		}
+
+		cur := f.offset(p)
+		i := sort.SearchInts(starts, cur+1) - 1
+		j := i + e.Count() - 1
+		if j >= len(starts) {
+			j = len(starts) - 1
+		}
+		from, to := starts[i], ends[j]
+		if around {
+			if j+1 < len(starts) {
+				to = starts[j+1]
+			} else {
+				for from > start && unicode.IsSpace(f.runes[from-1]) {
+					from--
+				}
+			}
+		}
+		return f.region(from, to), true
+	}
+}
+
+var (
+	// InnerSentence selects the sentence under the cursor
+	InnerSentence = sentenceObject(false)
+	// AroundSentence selects the sentence under the cursor and the whitespace
+	// after it
+	AroundSentence = sentenceObject(true)
+)
+
+// paragraphObject returns a text object for the paragraph, or run of blank
+// lines, under the cursor. Paragraphs are selected linewise.
+func paragraphObject(around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		t := e.Text()
+		row := e.Cursor().Y
+		// extend returns the last row of the run of rows with the same
+		// blankness starting at row
+		extend := func(row int) int {
+			blank := isLineBlank(t, row)
+			for row+1 < t.Length() && isLineBlank(t, row+1) == blank {
+				row++
+			}
+			return row
+		}
+		start := row
+		for start > 0 && isLineBlank(t, start-1) == isLineBlank(t, row) {
+			start--
+		}
+		end := extend(row)
+		for i := 1; i < e.Count() && end+1 < t.Length(); i++ {
+			end = extend(end + 1)
+		}
+		if around {
+			switch {
+			case end+1 < t.Length():
+				end = extend(end + 1)
+			case !isLineBlank(t, start):
+				for start > 0 && isLineBlank(t, start-1) {
+					start--
+				}
+			}
+		}
+		return editarea.Region{
+			Start:    area.Point{X: 0, Y: start},
+			End:      area.Point{X: 0, Y: end},
+			Linewise: true,
+		}, true
+	}
+}
+
+var (
+	// InnerParagraph selects the paragraph under the cursor
+	InnerParagraph = paragraphObject(false)
+	// AroundParagraph selects the paragraph under the cursor and the blank
+	// lines after it
+	AroundParagraph = paragraphObject(true)
+)
+
+// QuoteObject returns a text object for the text quoted with q around the
+// cursor, on the cursor's line. If the cursor isn't in a quote, the next
+// quote on the line is used. The object includes the quotes and the
+// whitespace after them if around is set.
+func QuoteObject(q rune, around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		p := cursor(e)
+		runes := lineRunes(e.Text(), p.Y)
+		var quotes []int
+		for i, r := range runes {
+			if r == q && (i == 0 || runes[i-1] != '\\') {
+				quotes = append(quotes, i)
+			}
+		}
+		// Quotes are paired from the start of the line
+		for i := 0; i+1 < len(quotes); i += 2 {
+			open, close := quotes[i], quotes[i+1]
+			if close < p.X {
+				continue
+			}
+			start, end := open+1, close
+			if around {
+				start, end = withSpace(runes, open, close+1)
+			}
+			return editarea.Region{Start: area.Point{X: start, Y: p.Y}, End: area.Point{X: end, Y: p.Y}}, true
+		}
+		return editarea.Region{}, false
+	}
+}
+
+// enclosingBracket returns the offset of the open bracket of the pair which
+// contains the offset i in runes, or -1 if there isn't one
+func enclosingBracket(runes []rune, open, close rune, i int) int {
+	if i < len(runes) && runes[i] == open {
+		return i
+	}
+	depth := 0
+	for i--; i >= 0; i-- {
+		switch runes[i] {
+		case close:
+			depth++
+		case open:
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// matchingBracket returns the offset of the close bracket which matches the
+// open bracket at i in runes, or -1 if there isn't one
+func matchingBracket(runes []rune, open, close rune, i int) int {
+	depth := 0
+	for i++; i < len(runes); i++ {
+		switch runes[i] {
+		case open:
+			depth++
+		case close:
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// innerRegion returns the region between the brackets at the offsets open
+// and close in f. If the brackets are at the end and start of their lines,
+// the lines between them are selected linewise, like vim.
+func innerRegion(f flatText, open, close int) editarea.Region {
+	start, end := open+1, close
+	if start < end && f.runes[start] == '\n' {
+		lineStart := end
+		for lineStart > start && f.runes[lineStart-1] != '\n' {
+			lineStart--
+		}
+		if strings.TrimSpace(string(f.runes[lineStart:end])) == "" {
+			first, last := f.point(start+1).Y, f.point(lineStart).Y-1
+			if last >= first {
+				return editarea.Region{
+					Start:    area.Point{X: 0, Y: first},
+					End:      area.Point{X: 0, Y: last},
+					Linewise: true,
+				}
+			}
+			return f.region(start+1, start+1)
+		}
+	}
+	return f.region(start, end)
+}
+
+// BracketObject returns a text object for the text between the brackets open
+// and close around the cursor, which may span lines. A count selects the
+// count-th enclosing pair. The object includes the brackets if around is
+// set.
+func BracketObject(open, close rune, around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		f := flatten(e.Text())
+		start := enclosingBracket(f.runes, open, close, f.offset(cursor(e)))
+		for i := 1; i < e.Count() && start >= 0; i++ {
+			start = enclosingBracket(f.runes, open, close, start-1)
+		}
+		if start < 0 {
+			return editarea.Region{}, false
+		}
+		end := matchingBracket(f.runes, open, close, start)
+		if end < 0 {
+			return editarea.Region{}, false
+		}
+		if around {
+			return f.region(start, end+1), true
+		}
+		return innerRegion(f, start, end), true
+	}
+}
+
+// tagPattern matches an HTML or XML tag, capturing whether it is a closing
+// tag, its name, and whether it is self-closing
+var tagPattern = regexp.MustCompile(`<(/?)([A-Za-z][\w:.-]*)[^<>]*?(/?)>`)
+
+// element is a matching pair of HTML or XML tags. The offsets of the tags
+// are in runes, and their ends are exclusive.
+type element struct {
+	openStart, openEnd   int
+	closeStart, closeEnd int
+}
+
+// elements returns the elements in s, which is made of runes
+func elements(s string, runes []rune) []element {
+	// runeOffset converts a byte offset in s to an offset in runes
+	runeOffset := func(i int) int {
+		return len([]rune(s[:i]))
+	}
+	type openTag struct {
+		name       string
+		start, end int
+	}
+	var open []openTag
+	var elems []element
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(s, -1) {
+		closing, name, selfClosing := m[3] > m[2], s[m[4]:m[5]], m[7] > m[6]
+		switch {
+		case selfClosing:
+		case !closing:
+			open = append(open, openTag{name, runeOffset(m[0]), runeOffset(m[1])})
+		default:
+			// Unclosed tags inside the element, like <br>, are skipped
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].name != name {
+					continue
+				}
+				elems = append(elems, element{
+					openStart: open[i].start, openEnd: open[i].end,
+					closeStart: runeOffset(m[0]), closeEnd: runeOffset(m[1]),
+				})
+				open = open[:i]
+				break
+			}
+		}
+	}
+	return elems
+}
+
+// tagObject returns a text object for the element around the cursor. A count
+// selects the count-th enclosing element. The object includes the tags if
+// around is set.
+func tagObject(around bool) editarea.TextObject {
+	return func(e *editarea.EditArea) (editarea.Region, bool) {
+		f := flatten(e.Text())
+		cur := f.offset(cursor(e))
+		var enclosing []element
+		for _, el := range elements(string(f.runes), f.runes) {
+			if el.openStart <= cur && cur < el.closeEnd {
+				enclosing = append(enclosing, el)
+			}
+		}
+		// Elements are found in the order they close, so inner elements
+		// come first
+		if len(enclosing) < e.Count() {
+			return editarea.Region{}, false
+		}
+		el := enclosing[e.Count()-1]
+		if around {
+			return f.region(el.openStart, el.closeEnd), true
+		}
+		return f.region(el.openEnd, el.closeStart), true
+	}
+}
+
+var (
+	// InnerTag selects the contents of the element around the cursor
+	InnerTag = tagObject(false)
+	// AroundTag selects the element around the cursor, including its tags
+	AroundTag = tagObject(true)
+)
+
+// DeleteOperator deletes the text in r, copying it into a register
+func DeleteOperator(e *editarea.EditArea, r editarea.Region) {
+	e.StoreDeleted(r)
+	e.ReplaceRegion(r, "")
+	if r.Linewise {
+		e.SetCursor(firstNonBlank(e.Text(), e.Cursor().Y))
+	}
+}
+
+// ChangeOperator deletes the text in r, copying it into a register, and
+// switches into insert mode. The rows of a linewise region are replaced with
+// an empty line.
+func ChangeOperator(e *editarea.EditArea, r editarea.Region) {
+	e.StoreDeleted(r)
+	if r.Linewise {
+		e.ReplaceRegion(r, "\n")
+	} else {
+		e.ReplaceRegion(r, "")
+	}
+	e.Mode = editarea.ModeInsert
+}
+
+// visual switches into visual mode selecting text of the given kind, or back
+// to normal mode if that kind of text is already being selected
+func visual(e *editarea.EditArea, kind editarea.SelectionKind) {
+	if e.Mode == editarea.ModeVisual && e.SelectionKind() == kind {
+		e.ExitVisualMode()
+		return
+	}
+	e.EnterVisualMode(kind)
+}
+
+// Visual switches into visual mode, or back to normal mode if characters are
+// already being selected
+func Visual(e *editarea.EditArea) { visual(e, editarea.SelectCharacters) }
+
+// VisualLine switches into visual mode selecting whole lines, or back to
+// normal mode if lines are already being selected
+func VisualLine(e *editarea.EditArea) { visual(e, editarea.SelectLines) }
+
+// VisualBlock switches into visual mode selecting a block, or back to normal
+// mode if a block is already being selected
+func VisualBlock(e *editarea.EditArea) { visual(e, editarea.SelectBlock) }
+
+// SwapSelectionEnds moves the cursor to the other end of the selection
+func SwapSelectionEnds(e *editarea.EditArea) { e.SwapSelectionEnds() }
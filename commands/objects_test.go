@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddOperator("d", DeleteOperator)
+	editarea.AddOperator("c", ChangeOperator)
+	editarea.AddNormalModeCommand("v", Visual)
+	editarea.AddNormalModeCommand("V", VisualLine)
+	objects := map[string]editarea.TextObject{
+		"iw": InnerWord, "aw": AroundWord, "iW": InnerBigWord, "aW": AroundBigWord,
+		"is": InnerSentence, "as": AroundSentence, "ip": InnerParagraph, "ap": AroundParagraph,
+		`i"`: QuoteObject('"', false), `a"`: QuoteObject('"', true),
+		"i(": BracketObject('(', ')', false), "a(": BracketObject('(', ')', true),
+		"i{": BracketObject('{', '}', false), "a{": BracketObject('{', '}', true),
+		"it": InnerTag, "at": AroundTag,
+		"if": InnerGoFunction, "af": AroundGoFunction, "ik": InnerGoBlock, "ak": AroundGoBlock,
+		"ia": InnerGoArgument, "aa": AroundGoArgument, "ic": InnerGoComment, "ac": AroundGoComment,
+	}
+	for keys, obj := range objects {
+		editarea.AddTextObject(keys, obj)
+	}
+}
+
+// newTestEditAreaForFile returns an EditArea editing filename, which contains
+// source, with the cursor at start
+func newTestEditAreaForFile(filename, source string, start area.Point) *editarea.EditArea {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		panic(err)
+	}
+	screen.SetSize(80, 25)
+	e := editarea.New(screen, filename, bytes.NewBufferString(source))
+	e.SetCursor(start)
+	return e
+}
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		start  area.Point
+		keys   string
+		want   string
+	}{
+		{"dw", "foo bar baz", area.Point{X: 0, Y: 0}, "dw", "bar baz"},
+		{"dw with counts", "a b c d e f g", area.Point{X: 0, Y: 0}, "2d2w", "e f g"},
+		{"dw on the last word of a line", "foo bar\nbaz", area.Point{X: 4, Y: 0}, "dw", "foo \nbaz"},
+		{"dw on the last word", "foo bar", area.Point{X: 4, Y: 0}, "dw", "foo "},
+		{"cw stops at the end of the word", "foo bar", area.Point{X: 0, Y: 0}, "cw", " bar"},
+		{"de", "foo bar", area.Point{X: 1, Y: 0}, "de", "f bar"},
+		{"dl on the last rune", "foo", area.Point{X: 2, Y: 0}, "dl", "fo"},
+		{"d$", "foo bar", area.Point{X: 3, Y: 0}, "d$", "foo"},
+		{"d$ on non-ASCII text", "héllo wörld", area.Point{X: 6, Y: 0}, "d$", "héllo "},
+		{"x at the end of non-ASCII text", "déjà vu", area.Point{X: 0, Y: 0}, "$x", "déjà v"},
+		{"dfx", "foo.bar", area.Point{X: 0, Y: 0}, "df.", "bar"},
+		{"dd", "a\nb\nc", area.Point{X: 0, Y: 1}, "dd", "a\nc"},
+		{"dd with a count", "a\nb\nc", area.Point{X: 0, Y: 0}, "2dd", "c"},
+		{"dd on every line", "a\nb", area.Point{X: 0, Y: 0}, "5dd", ""},
+		{"dj", "a\nb\nc", area.Point{X: 0, Y: 0}, "dj", "c"},
+		{"cc", "a\nb\nc", area.Point{X: 0, Y: 1}, "cc", "a\n\nc"},
+		{"diw", "foo bar baz", area.Point{X: 5, Y: 0}, "diw", "foo  baz"},
+		{"daw", "foo bar baz", area.Point{X: 5, Y: 0}, "daw", "foo baz"},
+		{"daw at the end of a line", "foo bar", area.Point{X: 5, Y: 0}, "daw", "foo"},
+		{"diW", "a foo.bar b", area.Point{X: 3, Y: 0}, "diW", "a  b"},
+		{"dis", "One two. Three four. Five.", area.Point{X: 10, Y: 0}, "dis", "One two.  Five."},
+		{"das", "One two. Three four. Five.", area.Point{X: 10, Y: 0}, "das", "One two. Five."},
+		{"dis on the last sentence", "One. Two.", area.Point{X: 6, Y: 0}, "dis", "One. "},
+		{"dip", "a\nb\n\nc", area.Point{X: 0, Y: 0}, "dip", "\nc"},
+		{"dap", "a\nb\n\nc", area.Point{X: 0, Y: 0}, "dap", "c"},
+		{`di"`, `x = "foo bar"`, area.Point{X: 6, Y: 0}, `di"`, `x = ""`},
+		{`da"`, `x = "foo" + y`, area.Point{X: 6, Y: 0}, `da"`, `x = + y`},
+		{`di" before the quote`, `x = "foo"`, area.Point{X: 0, Y: 0}, `di"`, `x = ""`},
+		{"di(", "f(a, (b))", area.Point{X: 2, Y: 0}, "di(", "f()"},
+		{"di( on the inner pair", "f(a, (b))", area.Point{X: 6, Y: 0}, "di(", "f(a, ())"},
+		{"di( with a count", "f(a, (b))", area.Point{X: 6, Y: 0}, "2di(", "f()"},
+		{"da(", "f(a, (b))", area.Point{X: 6, Y: 0}, "da(", "f(a, )"},
+		{"di{ across lines", "if x {\n\ta\n\tb\n}", area.Point{X: 1, Y: 1}, "di{", "if x {\n}"},
+		{"ci( on an empty pair", "f()", area.Point{X: 1, Y: 0}, "ci(", "f()"},
+		{"dit", "<a><b>x</b>y</a>", area.Point{X: 6, Y: 0}, "dit", "<a><b></b>y</a>"},
+		{"dat", "<a><b>x</b>y</a>", area.Point{X: 6, Y: 0}, "dat", "<a>y</a>"},
+		{"dit with a count", "<a><b>x</b>y</a>", area.Point{X: 6, Y: 0}, "2dit", "<a></a>"},
+		{"dit skips unclosed tags", "<p>a<br>b</p>", area.Point{X: 4, Y: 0}, "dit", "<p></p>"},
+		{"d in visual mode", "foo bar", area.Point{X: 1, Y: 0}, "vld", "f bar"},
+		{"d to the end of non-ASCII text in visual mode", "héllo wörld", area.Point{X: 6, Y: 0}, "v$d", "héllo "},
+		{"d to the end of a line in visual mode", "foo\nbar", area.Point{X: 1, Y: 0}, "v$d", "fbar"},
+		{"d to the end of the next line in visual mode", "foo\nbar\nbaz", area.Point{X: 1, Y: 0}, "v$jd", "fbaz"},
+		{"d to the last rune of a line in visual mode", "foo\nbar", area.Point{X: 1, Y: 0}, "vlld", "f\nbar"},
+		{"d in visual line mode", "a\nb\nc", area.Point{X: 0, Y: 0}, "Vjd", "c"},
+		{"text objects in visual mode", "foo bar baz", area.Point{X: 5, Y: 0}, "vawd", "foo baz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, tt.start)
+			typeKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+}
+
+func TestGoObjects(t *testing.T) {
+	source := `package main
+
+// f does things
+func f(a int, b string) {
+	if a > 0 {
+		g(a, b)
+	}
+	// done
+}`
+	tests := []struct {
+		name  string
+		start area.Point
+		keys  string
+		want  string
+	}{
+		{"dif", area.Point{X: 2, Y: 5}, "dif", "package main\n\n// f does things\nfunc f(a int, b string) {\n}"},
+		{"daf", area.Point{X: 2, Y: 5}, "daf", "package main\n"},
+		{"dik", area.Point{X: 2, Y: 5}, "dik", "package main\n\n// f does things\nfunc f(a int, b string) {\n\tif a > 0 {\n\t}\n\t// done\n}"},
+		{"dak", area.Point{X: 2, Y: 5}, "dak", "package main\n\n// f does things\nfunc f(a int, b string) {\n\t// done\n}"},
+		{"dia", area.Point{X: 4, Y: 5}, "dia", "package main\n\n// f does things\nfunc f(a int, b string) {\n\tif a > 0 {\n\t\tg(, b)\n\t}\n\t// done\n}"},
+		{"daa", area.Point{X: 4, Y: 5}, "daa", "package main\n\n// f does things\nfunc f(a int, b string) {\n\tif a > 0 {\n\t\tg(b)\n\t}\n\t// done\n}"},
+		{"daa on a parameter", area.Point{X: 7, Y: 3}, "daa", "package main\n\n// f does things\nfunc f(b string) {\n\tif a > 0 {\n\t\tg(a, b)\n\t}\n\t// done\n}"},
+		{"dic", area.Point{X: 1, Y: 7}, "dic", "package main\n\n// f does things\nfunc f(a int, b string) {\n\tif a > 0 {\n\t\tg(a, b)\n\t}\n\t// \n}"},
+		{"dac", area.Point{X: 1, Y: 7}, "dac", "package main\n\n// f does things\nfunc f(a int, b string) {\n\tif a > 0 {\n\t\tg(a, b)\n\t}\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditAreaForFile("test.go", source, tt.start)
+			typeKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+
+	// The objects only work in Go files
+	e := newTestEditAreaForFile("test.txt", source, area.Point{X: 2, Y: 5})
+	typeKeys(e, "dif")
+	assert.Equal(t, source, e.Text().String())
+}
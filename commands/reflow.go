@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jamesroutley/fuji/editarea"
+)
+
+// ReflowOperator rewraps the lines in r to textwidth, like gq
+func ReflowOperator(e *editarea.EditArea, r editarea.Region) {
+	e.Reflow(r.Start.Y, r.End.Y, 0)
+}
+
+// Reflow rewraps the lines in r to the width given as the argument, or to
+// textwidth if there isn't one
+func Reflow(e *editarea.EditArea, r editarea.Range, args string) error {
+	width := 0
+	if args = strings.TrimSpace(args); args != "" {
+		var err error
+		if width, err = strconv.Atoi(args); err != nil || width <= 0 {
+			return fmt.Errorf("invalid width: %s", args)
+		}
+	}
+	e.Reflow(r.Start, r.End, width)
+	return nil
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddOperator("gq", ReflowOperator)
+	editarea.AddExCommand("reflow", Reflow)
+}
+
+func TestReflow(t *testing.T) {
+	tests := []struct {
+		name      string
+		filename  string
+		textWidth int
+		source    string
+		keys      string
+		want      string
+	}{
+		{"wrapping a line", "test.txt", 8, "aaa bbb ccc ddd", "gqq", "aaa bbb\nccc ddd"},
+		{"joining lines", "test.txt", 8, "aaa\nbbb\nccc", "gqip", "aaa bbb\nccc"},
+		{"separate paragraphs", "test.txt", 0, "a\nb\n\nc\nd", "gqG", "a b\n\nc d"},
+		{"keeping indentation", "test.txt", 10, "\taa bb cc", "gqq", "\taa bb\n\tcc"},
+		{"long words", "test.txt", 5, "a bbbbbbbbbb c", "gqq", "a\nbbbbbbbbbb\nc"},
+		{"line comments", "test.go", 10, "// aaa bbb ccc", "gqq", "// aaa bbb\n// ccc"},
+		{"block comments", "test.go", 0, "/*\n * aaa\n * bbb\n */", "gqG", "/*\n * aaa bbb\n */"},
+		{"changing comment leaders", "test.py", 0, "# a\nb", "gqj", "# a\nb"},
+		{"list items", "test.md", 7, "- aaa bbb\n- ccc\nddd", "gqG", "- aaa\n  bbb\n- ccc\n  ddd"},
+		{"numbered lists", "test.md", 8, "10. aaa bbb", "gqq", "10. aaa\n    bbb"},
+		{"lists in comments", "test.go", 7, "// - aa bb", "gqq", "// - aa\n//   bb"},
+		{"headings", "test.md", 0, "# A\nb\nc", "gqG", "# A\nb c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditAreaForFile(tt.filename, tt.source, area.Point{X: 0, Y: 0})
+			assert.NoError(t, e.SetOptions(fmt.Sprintf("textwidth=%d", tt.textWidth)))
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+}
+
+func TestReflowCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   string
+		want   string
+	}{
+		{"a width", "aaa bbb ccc", "reflow 4", "aaa\nbbb\nccc"},
+		{"a range", "a\nb\nc\nd", "2,3reflow", "a\nb c\nd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			assert.NoError(t, e.RunCommandLine(tt.line))
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+
+	e := newTestEditArea("a", area.Point{X: 0, Y: 0})
+	assert.Error(t, e.RunCommandLine("reflow x"))
+}
+
+func TestAutoWrap(t *testing.T) {
+	tests := []struct {
+		name      string
+		filename  string
+		textWidth int
+		source    string
+		keys      string
+		want      string
+		cursor    area.Point
+	}{
+		{"not wrapping", "test.txt", 0, "", "iaaa bbb ccc", "aaa bbb ccc", area.Point{X: 11, Y: 0}},
+		{"wrapping", "test.txt", 8, "", "iaaa bbb ccc", "aaa bbb\nccc", area.Point{X: 3, Y: 1}},
+		{"long words", "test.txt", 4, "", "iaaaaaa", "aaaaaa", area.Point{X: 6, Y: 0}},
+		{"keeping indentation", "test.txt", 8, "", "i  aa bb cc", "  aa bb\n  cc", area.Point{X: 4, Y: 1}},
+		{"comments", "test.go", 7, "", "i// aa bb", "// aa\n// bb", area.Point{X: 5, Y: 1}},
+		{"list items", "test.md", 6, "", "i- aa bb", "- aa\n  bb", area.Point{X: 4, Y: 1}},
+		{"appending", "test.txt", 6, "aa bb", "A cc", "aa bb\ncc", area.Point{X: 2, Y: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditAreaForFile(tt.filename, tt.source, area.Point{X: 0, Y: 0})
+			assert.NoError(t, e.SetOptions(fmt.Sprintf("textwidth=%d", tt.textWidth)))
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+			assert.Equal(t, tt.cursor, e.Cursor())
+		})
+	}
+}
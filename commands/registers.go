@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/jamesroutley/fuji/editor"
+	"github.com/jamesroutley/fuji/pane"
+)
+
+// YankOperator copies the text in r into a register, and moves the cursor to
+// the start of r
+func YankOperator(e *editarea.EditArea, r editarea.Region) {
+	e.Yank(r)
+	if r.Linewise {
+		// Like vim, yy leaves the cursor where it is
+		p := e.Cursor()
+		if r.Start.Y < p.Y {
+			p.Y = r.Start.Y
+		}
+		e.SetCursor(p)
+		return
+	}
+	e.SetCursor(r.Start)
+}
+
+// register returns the contents of the register typed before the running
+// command. ok is false, and a message is shown, if it is empty.
+func register(e *editarea.EditArea) (r editarea.Register, ok bool) {
+	name := e.RegisterName()
+	r, ok = e.Register(name)
+	if !ok {
+		e.SetMessage("Nothing in register %c", name)
+	}
+	return r, ok
+}
+
+// Put puts the text in a register after the cursor
+func Put(e *editarea.EditArea) {
+	if r, ok := register(e); ok {
+		e.Put(r, false, e.Count())
+	}
+}
+
+// PutBefore puts the text in a register before the cursor
+func PutBefore(e *editarea.EditArea) {
+	if r, ok := register(e); ok {
+		e.Put(r, true, e.Count())
+	}
+}
+
+// VisualPut replaces the selection with the text in a register, which is
+// then replaced with the selected text
+func VisualPut(e *editarea.EditArea) {
+	r, ok := register(e)
+	if !ok {
+		return
+	}
+	selection := e.Selection()
+	e.ExitVisualMode()
+	e.StoreDeleted(selection)
+	if selection.Linewise {
+		text := strings.Repeat(r.Text, e.Count())
+		if !r.Linewise {
+			text = strings.Repeat(r.Text+"\n", e.Count())
+		}
+		e.ReplaceRegion(selection, text)
+		return
+	}
+	e.ReplaceRegion(selection, "")
+	p := e.Cursor()
+	if length := e.Text().LineLength(p.Y); p.X >= length && length > 0 {
+		// The selection was at the end of the line
+		e.SetCursor(area.Point{X: length - 1, Y: p.Y})
+		e.Put(r, false, e.Count())
+		return
+	}
+	e.Put(r, true, e.Count())
+}
+
+// cyclePut replaces the text put by the last put with text from the yank
+// ring
+func cyclePut(e *editarea.EditArea, delta int) {
+	if err := e.CyclePut(delta); err != nil {
+		e.SetMessage("%s", err)
+	}
+}
+
+// PutOlder replaces the text put by the last put with the next older text in
+// the yank ring
+func PutOlder(e *editarea.EditArea) { cyclePut(e, e.Count()) }
+
+// PutNewer replaces the text put by the last put with the next newer text in
+// the yank ring
+func PutNewer(e *editarea.EditArea) { cyclePut(e, -e.Count()) }
+
+// Delete deletes the characters under and after the cursor, copying them
+// into a register
+func Delete(e *editarea.EditArea) {
+	p := e.Cursor()
+	length := e.Text().LineLength(p.Y)
+	if length == 0 {
+		return
+	}
+	end := p.X + e.Count()
+	if end > length {
+		end = length
+	}
+	r := editarea.Region{Start: p, End: area.Point{X: end, Y: p.Y}}
+	e.StoreDeleted(r)
+	e.ReplaceRegion(r, "")
+	if p.X >= e.Text().LineLength(p.Y) && p.X > 0 {
+		e.SetCursor(area.Point{X: p.X - 1, Y: p.Y})
+	}
+}
+
+// Registers opens a pane listing the contents of the registers
+func Registers(e *editarea.EditArea, r editarea.Range, args string) error {
+	editor.Open(pane.NewRegistersPane(e))
+	return nil
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddOperator("y", YankOperator)
+	editarea.AddNormalModeCommand("x", Delete)
+	editarea.AddNormalModeCommand("p", Put)
+	editarea.AddNormalModeCommand("P", PutBefore)
+	editarea.AddNormalModeCommand("<C-p>", PutOlder)
+	editarea.AddNormalModeCommand("<C-n>", PutNewer)
+	editarea.AddNormalModeCommand("<C-v>", VisualBlock)
+	editarea.AddVisualModeCommand("p", VisualPut)
+}
+
+func TestYankAndPut(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		start  area.Point
+		keys   string
+		want   string
+	}{
+		{"yw and p", "foo bar", area.Point{X: 0, Y: 0}, "ywP", "foo foo bar"},
+		{"yy and p", "a\nb", area.Point{X: 0, Y: 0}, "yyjp", "a\nb\na"},
+		{"yy and P with a count", "a\nb", area.Point{X: 0, Y: 1}, "yy2P", "a\nb\nb\nb"},
+		{"dd and p", "a\nb\nc", area.Point{X: 0, Y: 0}, "ddp", "b\na\nc"},
+		{"x and p", "abc", area.Point{X: 0, Y: 0}, "xp", "bac"},
+		{"x with a count", "abcd", area.Point{X: 1, Y: 0}, "2x$p", "adbc"},
+		{"named registers", "a b", area.Point{X: 0, Y: 0}, `"ayiwwdiw"ap`, "a a"},
+		{"appending to a register", "a b", area.Point{X: 0, Y: 0}, `"byl"Bylw"bp`, "a baa"},
+		{"the 0 register after a delete", "a b", area.Point{X: 0, Y: 0}, `yiwwdiw"0p`, "a a"},
+		{"the black hole register", "a b", area.Point{X: 0, Y: 0}, `yiww"_diwp`, "a a"},
+		{"visual p", "foo bar", area.Point{X: 0, Y: 0}, "yiwwviwp", "foo foo"},
+		{"visual p swaps", "foo bar", area.Point{X: 0, Y: 0}, "yiwwviwp0viwp", "bar foo"},
+		{"blocks", "ab\ncd\nef", area.Point{X: 0, Y: 0}, "\x16jyjjp", "ab\ncd\neaf\n c"},
+		{"blocks from non-ASCII lines", "éab\nécd\nxy", area.Point{X: 1, Y: 0}, "\x16jyjjp", "éab\nécd\nxya\n  c"},
+		{"blocks past the end of non-ASCII lines", "éé\nxyz", area.Point{X: 0, Y: 0}, "j$\x16kyp", "ééé\nxyyzz"},
+		{"blocks into non-ASCII lines", "ab\ncd\néé\nö", area.Point{X: 0, Y: 0}, "\x16jyjj$p", "ab\ncd\nééa\nö c"},
+		{"deleting a block", "abc\ndef", area.Point{X: 1, Y: 0}, "\x16jd$p", "acb\ndfe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, tt.start)
+			typeKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+		})
+	}
+}
+
+func TestYankRing(t *testing.T) {
+	e := newTestEditArea("a b c", area.Point{X: 0, Y: 0})
+	typeKeys(e, "ylwylwylp")
+	assert.Equal(t, "a b cc", e.Text().String())
+	ctrl := func(key tcell.Key) {
+		e.HandleEvent(tcell.NewEventKey(key, 0, tcell.ModNone))
+	}
+	ctrl(tcell.KeyCtrlP)
+	assert.Equal(t, "a b cb", e.Text().String())
+	ctrl(tcell.KeyCtrlP)
+	assert.Equal(t, "a b ca", e.Text().String())
+	ctrl(tcell.KeyCtrlN)
+	assert.Equal(t, "a b cb", e.Text().String())
+
+	// Cycling puts blocks in the right columns of non-ASCII lines
+	e = newTestEditArea("éa\néb\nxy", area.Point{X: 1, Y: 0})
+	typeKeys(e, "\x16jyjjylp")
+	assert.Equal(t, "éa\néb\nxyy", e.Text().String())
+	ctrl(tcell.KeyCtrlP)
+	assert.Equal(t, "éa\néb\nxya\n  b", e.Text().String())
+}
@@ -0,0 +1,26 @@
+package commands
+
+import "github.com/jamesroutley/fuji/editarea"
+
+// Replace switches the EditArea into replace mode, where the text typed
+// overwrites the text under the cursor
+func Replace(e *editarea.EditArea) { e.Mode = editarea.ModeReplace }
+
+// ReplaceChar replaces the rune under the cursor, and count - 1 more after
+// it, with the character typed next
+func ReplaceChar(e *editarea.EditArea) {
+	e.ReadChar(func(e *editarea.EditArea) {
+		e.ReplaceChars(e.Char(), e.Count())
+	})
+}
+
+// VisualReplace replaces every rune selected with the character typed next.
+// The selection is kept if it's <CR>.
+func VisualReplace(e *editarea.EditArea) {
+	e.ReadChar(func(e *editarea.EditArea) {
+		if e.Char() == '\r' {
+			return
+		}
+		e.ReplaceSelection(e.Char())
+	})
+}
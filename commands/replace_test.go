@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddNormalModeCommand("R", Replace)
+	editarea.AddNormalModeCommand("r", ReplaceChar)
+	editarea.AddVisualModeCommand("r", VisualReplace)
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		keys   string
+		want   string
+		cursor area.Point
+	}{
+		{"overwriting", "abcd", "lRxy<Esc>", "axyd", area.Point{X: 2, Y: 0}},
+		{"overwriting past the end of the line", "ab\ncd", "lRxyz<Esc>", "axyz\ncd", area.Point{X: 3, Y: 0}},
+		{"restoring with backspace", "abcd", "lRxyz<BS><BS><Esc>", "axcd", area.Point{X: 1, Y: 0}},
+		{"restoring appended runes", "ab", "lRxyz<BS><BS><Esc>", "ax", area.Point{X: 1, Y: 0}},
+		{"backspace before the start", "abcd", "llRx<BS><BS><Esc>", "abcd", area.Point{X: 0, Y: 0}},
+		{"line breaks", "abcd", "lRx<CR>y<BS><BS><Esc>", "axcd", area.Point{X: 1, Y: 0}},
+		{"restoring after a line is opened", "aaa\nbbb\nfff\nEND", "Onew<Esc>jjjRxy<BS><BS><Esc>", "new\naaa\nbbb\nfff\nEND", area.Point{X: 1, Y: 3}},
+		{"restoring after a line is put", "aaa\nbbb\nfff\nEND", "yypjjlRxy<BS><BS><Esc>", "aaa\naaa\nbbb\nfff\nEND", area.Point{X: 0, Y: 3}},
+		{"repeating", "abcd\nefgh", "Rxy<Esc>j0.", "xycd\nxygh", area.Point{X: 1, Y: 1}},
+		{"replacing a rune", "abcd", "lrx", "axcd", area.Point{X: 1, Y: 0}},
+		{"replacing runes with a count", "abcd", "l3rx", "axxx", area.Point{X: 3, Y: 0}},
+		{"replacing too many runes", "abcd", "l4rx", "abcd", area.Point{X: 1, Y: 0}},
+		{"repeating a replace", "abcd", "2rxl.", "xxxx", area.Point{X: 3, Y: 0}},
+		{"replacing a rune with a line break", "abcd", "lr<CR>", "a\ncd", area.Point{X: 0, Y: 1}},
+		{"replacing runes with a line break", "abcd", "l2r<CR>", "a\nd", area.Point{X: 0, Y: 1}},
+		{"repeating a replace with a line break", "abcd\nefgh", "lr<CR>j0l.", "a\ncd\ne\ngh", area.Point{X: 0, Y: 3}},
+		{"replacing a selection", "abc\ndef", "lvjrx", "axx\nxxf", area.Point{X: 1, Y: 0}},
+		{"replacing to the end of a non-ASCII line", "héllo wörld", "wv$r-", "héllo -----", area.Point{X: 6, Y: 0}},
+		{"replacing lines", "abc\nde\nf", "jVjrx", "abc\nxx\nx", area.Point{X: 0, Y: 1}},
+		{"replacing a block", "abc\ndef", "l<C-v>jlrx", "axx\ndxx", area.Point{X: 1, Y: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+			assert.Equal(t, tt.cursor, e.Cursor())
+			assert.Equal(t, editarea.ModeNormal, e.Mode)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package commands
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+)
+
+// Surroundings are pairs of brackets, quotes, tags or function calls around
+// some text, which are added with ys and visual S, changed with cs and
+// deleted with ds. Each is named by a character typed after the command:
+//
+//	( ) b	parentheses
+//	{ } B	braces
+//	[ ] r	square brackets
+//	> a	angle brackets
+//	< t	an HTML or XML tag, which is typed after < or t up to its closing >
+//	f	a function call, whose name is typed after f up to the (
+//
+// Typing an opening bracket adds spaces inside the brackets, or deletes them
+// along with the brackets. Any other punctuation, like a quote, surrounds
+// text with itself.
+
+// surroundBracket is a pair of brackets which can surround text
+type surroundBracket struct {
+	open, close rune
+}
+
+// surroundBrackets are the pairs of brackets which can surround text, by the
+// characters which name them
+var surroundBrackets = map[rune]surroundBracket{
+	'(': {'(', ')'}, ')': {'(', ')'}, 'b': {'(', ')'},
+	'{': {'{', '}'}, '}': {'{', '}'}, 'B': {'{', '}'},
+	'[': {'[', ']'}, ']': {'[', ']'}, 'r': {'[', ']'},
+	'<': {'<', '>'}, '>': {'<', '>'}, 'a': {'<', '>'},
+}
+
+// surrounding is the text added before and after text to surround it
+type surrounding struct {
+	open, close string
+}
+
+// readUntil reads characters until end is typed, then runs then with the
+// characters typed before it
+func readUntil(e *editarea.EditArea, end rune, then func(e *editarea.EditArea, s string)) {
+	var typed []rune
+	var read editarea.NormalModeCommand
+	read = func(e *editarea.EditArea) {
+		if c := e.Char(); c != end {
+			typed = append(typed, c)
+			e.ReadChar(read)
+			return
+		}
+		then(e, string(typed))
+	}
+	e.ReadChar(read)
+}
+
+// readSurrounding reads the surrounding to add, which is named by the
+// character typed next, and then runs then with it
+func readSurrounding(e *editarea.EditArea, then func(e *editarea.EditArea, s surrounding)) {
+	e.ReadChar(func(e *editarea.EditArea) {
+		switch c := e.Char(); {
+		case c == '<' || c == 't':
+			readUntil(e, '>', func(e *editarea.EditArea, tag string) {
+				fields := strings.Fields(tag)
+				if len(fields) == 0 {
+					return
+				}
+				then(e, surrounding{"<" + strings.TrimSpace(tag) + ">", "</" + fields[0] + ">"})
+			})
+		case c == 'f':
+			readUntil(e, '(', func(e *editarea.EditArea, name string) {
+				then(e, surrounding{strings.TrimSpace(name) + "(", ")"})
+			})
+		case surroundBrackets[c] != (surroundBracket{}):
+			b := surroundBrackets[c]
+			if c == b.open {
+				then(e, surrounding{string(b.open) + " ", " " + string(b.close)})
+				return
+			}
+			then(e, surrounding{string(b.open), string(b.close)})
+		case unicode.IsPunct(c) || unicode.IsSymbol(c):
+			then(e, surrounding{string(c), string(c)})
+		default:
+			e.SetMessage("invalid surrounding: %c", c)
+		}
+	})
+}
+
+// isFunctionName returns whether r can be part of the name of a function
+// which surrounds text
+func isFunctionName(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// findSurrounding returns the regions of the opening and closing parts of the
+// surrounding named c around the cursor, which are found with the text
+// objects for them
+func findSurrounding(e *editarea.EditArea, c rune) (open, close editarea.Region, ok bool) {
+	switch {
+	case c == '"' || c == '\'' || c == '`':
+		r, ok := QuoteObject(c, false)(e)
+		if !ok {
+			return open, close, false
+		}
+		open = editarea.Region{Start: area.Point{X: r.Start.X - 1, Y: r.Start.Y}, End: r.Start}
+		close = editarea.Region{Start: r.End, End: area.Point{X: r.End.X + 1, Y: r.End.Y}}
+		return open, close, true
+	case c == 't':
+		inner, ok := InnerTag(e)
+		if !ok {
+			return open, close, false
+		}
+		around, _ := AroundTag(e)
+		return editarea.Region{Start: around.Start, End: inner.Start}, editarea.Region{Start: inner.End, End: around.End}, true
+	case c == 'f':
+		r, ok := BracketObject('(', ')', true)(e)
+		if !ok {
+			return open, close, false
+		}
+		runes := lineRunes(e.Text(), r.Start.Y)
+		start := r.Start.X
+		for start > 0 && isFunctionName(runes[start-1]) {
+			start--
+		}
+		if start == r.Start.X {
+			return open, close, false
+		}
+		open = editarea.Region{Start: area.Point{X: start, Y: r.Start.Y}, End: area.Point{X: r.Start.X + 1, Y: r.Start.Y}}
+		close = editarea.Region{Start: area.Point{X: r.End.X - 1, Y: r.End.Y}, End: r.End}
+		return open, close, true
+	}
+	b, isBracket := surroundBrackets[c]
+	if !isBracket {
+		return open, close, false
+	}
+	r, ok := BracketObject(b.open, b.close, true)(e)
+	if !ok {
+		return open, close, false
+	}
+	open = editarea.Region{Start: r.Start, End: area.Point{X: r.Start.X + 1, Y: r.Start.Y}}
+	close = editarea.Region{Start: area.Point{X: r.End.X - 1, Y: r.End.Y}, End: r.End}
+	if c == b.open {
+		// The whitespace inside the brackets on their rows goes too
+		first, last := lineRunes(e.Text(), open.End.Y), lineRunes(e.Text(), close.Start.Y)
+		for open.End.X < len(first) && unicode.IsSpace(first[open.End.X]) {
+			open.End.X++
+		}
+		for close.Start.X > 0 && unicode.IsSpace(last[close.Start.X-1]) &&
+			(close.Start.Y != open.End.Y || close.Start.X > open.End.X) {
+			close.Start.X--
+		}
+	}
+	return open, close, true
+}
+
+// replaceSurrounding replaces the surrounding in the regions open and close
+// with s, and moves to the start of the surrounding
+func replaceSurrounding(e *editarea.EditArea, open, close editarea.Region, s surrounding) {
+	// The closing part is replaced first, so the opening part doesn't move
+	e.ReplaceRegion(close, s.close)
+	e.ReplaceRegion(open, s.open)
+	e.SetCursor(open.Start)
+}
+
+// DeleteSurrounding deletes the surrounding named by the character typed
+// next, like ds(
+func DeleteSurrounding(e *editarea.EditArea) {
+	e.ReadChar(func(e *editarea.EditArea) {
+		if open, close, ok := findSurrounding(e, e.Char()); ok {
+			replaceSurrounding(e, open, close, surrounding{})
+		}
+	})
+}
+
+// ChangeSurrounding replaces the surrounding named by the character typed
+// next with the one named by the character typed after it, like cs"'
+func ChangeSurrounding(e *editarea.EditArea) {
+	e.ReadChar(func(e *editarea.EditArea) {
+		open, close, ok := findSurrounding(e, e.Char())
+		if !ok {
+			return
+		}
+		readSurrounding(e, func(e *editarea.EditArea, s surrounding) {
+			replaceSurrounding(e, open, close, s)
+		})
+	})
+}
+
+// surround adds s around the text in r. The text of a linewise region is
+// surrounded from the first non-blank rune of its first row, or if onRows is
+// set, s is put on rows of its own before and after the region. Each row of a
+// blockwise region is surrounded.
+func surround(e *editarea.EditArea, r editarea.Region, s surrounding, onRows bool) {
+	t := e.Text()
+	switch {
+	case r.Blockwise:
+		for row := r.End.Y; row >= r.Start.Y; row-- {
+			start, end := r.Start.X, r.End.X
+			length := t.LineLength(row)
+			if start >= length {
+				continue
+			}
+			if end > length {
+				end = length
+			}
+			e.ReplaceRegion(editarea.Region{Start: area.Point{X: end, Y: row}, End: area.Point{X: end, Y: row}}, s.close)
+			e.ReplaceRegion(editarea.Region{Start: area.Point{X: start, Y: row}, End: area.Point{X: start, Y: row}}, s.open)
+		}
+		e.SetCursor(r.Start)
+		return
+	case r.Linewise && onRows:
+		indent := string(lineRunes(t, r.Start.Y)[:firstNonBlank(t, r.Start.Y).X])
+		end := area.Point{X: t.LineLength(r.End.Y), Y: r.End.Y}
+		start := area.Point{X: 0, Y: r.Start.Y}
+		e.ReplaceRegion(editarea.Region{Start: end, End: end}, "\n"+indent+strings.TrimSpace(s.close))
+		e.ReplaceRegion(editarea.Region{Start: start, End: start}, indent+strings.TrimSpace(s.open)+"\n")
+		e.SetCursor(firstNonBlank(e.Text(), r.Start.Y))
+		return
+	case r.Linewise:
+		r = editarea.Region{
+			Start: firstNonBlank(t, r.Start.Y),
+			End:   area.Point{X: t.LineLength(r.End.Y), Y: r.End.Y},
+		}
+	}
+	// Whitespace at the end of the region, like that covered by aw, is left
+	// outside the surrounding
+	last := lineRunes(t, r.End.Y)
+	for r.End.X > 0 && r.End.X <= len(last) && unicode.IsSpace(last[r.End.X-1]) &&
+		(r.End.Y != r.Start.Y || r.End.X > r.Start.X+1) {
+		r.End.X--
+	}
+	e.ReplaceRegion(editarea.Region{Start: r.End, End: r.End}, s.close)
+	e.ReplaceRegion(editarea.Region{Start: r.Start, End: r.Start}, s.open)
+	e.SetCursor(r.Start)
+}
+
+// SurroundOperator surrounds the text in r with the surrounding named by the
+// character typed next, like ysiw"
+func SurroundOperator(e *editarea.EditArea, r editarea.Region) {
+	readSurrounding(e, func(e *editarea.EditArea, s surrounding) {
+		surround(e, r, s, false)
+	})
+}
+
+// VisualSurround surrounds the selection with the surrounding named by the
+// character typed next. Selected lines are surrounded by rows of their own.
+func VisualSurround(e *editarea.EditArea) {
+	r := e.Selection()
+	e.ExitVisualMode()
+	readSurrounding(e, func(e *editarea.EditArea, s surrounding) {
+		surround(e, r, s, r.Linewise)
+	})
+}
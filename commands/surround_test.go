@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddOperator("y", YankOperator)
+	editarea.AddOperator("ys", SurroundOperator)
+	editarea.AddNormalModeCommand("ds", DeleteSurrounding)
+	editarea.AddNormalModeCommand("cs", ChangeSurrounding)
+	editarea.AddVisualModeCommand("S", VisualSurround)
+}
+
+func TestSurround(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		keys   string
+		want   string
+	}{
+		{"adding quotes", "foo bar", `ysiw"`, `"foo" bar`},
+		{"adding brackets", "foo bar", "ysiw)", "(foo) bar"},
+		{"adding spaced brackets", "foo bar", "ysiw(", "( foo ) bar"},
+		{"adding brackets by alias", "foo bar", "ysiwB", "{foo} bar"},
+		{"leaving trailing space out", "foo bar", "ysaw]", "[foo] bar"},
+		{"adding around a line", "\tfoo bar", "yss)", "\t(foo bar)"},
+		{"adding around a non-ASCII line", "héllo wörld", "yss)", "(héllo wörld)"},
+		{"adding to the end of a non-ASCII line", "héllo wörld", `wys$"`, `héllo "wörld"`},
+		{"surrounding a block of non-ASCII lines", "éa\néb", "<C-v>j$S)", "(éa)\n(éb)"},
+		{"surrounding non-ASCII lines", "é\nö", "VjS{", "{\né\nö\n}"},
+		{"adding a tag", "foo", `ysiw<a href="x">`, `<a href="x">foo</a>`},
+		{"adding a tag with t", "foo", "ysiwtem>", "<em>foo</em>"},
+		{"adding a function call", "x", "ysiwfprint(", "print(x)"},
+		{"deleting quotes", `x "foo" y`, `fods"`, "x foo y"},
+		{"deleting brackets", "f(a, b)", "fads)", "fa, b"},
+		{"deleting spaced brackets", "( a )", "lds(", "a"},
+		{"deleting brackets across lines", "{\n\tx\n}", "jdsB", "\n\tx\n"},
+		{"deleting a tag", "<p>foo</p>", "fods" + "t", "foo"},
+		{"deleting a function call", "print(x)", "fxdsf", "x"},
+		{"changing quotes", `"foo"`, `lcs"'`, "'foo'"},
+		{"changing brackets to a tag", "(foo)", "lcs)<b>", "<b>foo</b>"},
+		{"changing a tag", "<p>foo</p>", "focst<div>", "<div>foo</div>"},
+		{"surrounding a selection", "foo bar", "veS'", "'foo' bar"},
+		{"surrounding lines", "\tfoo\n\tbar", "VjS{", "\t{\n\tfoo\n\tbar\n\t}"},
+		{"repeating an addition", "a b", `ysiw"W.`, `"a" "b"`},
+		{"repeating a tag", "a b", "ysiw<i>W.", "<i>a</i> <i>b</i>"},
+		{"repeating a change", `"a" "b"`, `lcs"'W.`, `'a' 'b'`},
+		{"no surrounding", "foo", "ds(", "foo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+			assert.Equal(t, editarea.ModeNormal, e.Mode)
+		})
+	}
+
+	e := newTestEditArea("<p>foo</p>", area.Point{X: 3, Y: 0})
+	typeNamedKeys(e, "cst<div>")
+	e.Draw(area.Area{End: area.Point{X: 80, Y: 10}})
+	typeNamedKeys(e, "u")
+	assert.Equal(t, "<p>foo</p>", e.Text().String())
+}
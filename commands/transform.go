@@ -0,0 +1,303 @@
+package commands
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+)
+
+// toggleCase returns r in upper case if it is lower case, and in lower case
+// otherwise
+func toggleCase(r rune) rune {
+	if unicode.IsLower(r) {
+		return unicode.ToUpper(r)
+	}
+	return unicode.ToLower(r)
+}
+
+// changeCase maps the runes in r with f. A blockwise region is changed row by
+// row, so its rows stay where they are.
+func changeCase(e *editarea.EditArea, r editarea.Region, f func(rune) rune) {
+	if !r.Blockwise {
+		e.ReplaceRegion(r, strings.Map(f, e.RegionText(r)))
+		return
+	}
+	for row := r.Start.Y; row <= r.End.Y; row++ {
+		length := e.Text().LineLength(row)
+		start, end := r.Start.X, r.End.X
+		if start > length {
+			start = length
+		}
+		if end > length {
+			end = length
+		}
+		rowRegion := editarea.Region{Start: area.Point{X: start, Y: row}, End: area.Point{X: end, Y: row}}
+		e.ReplaceRegion(rowRegion, strings.Map(f, e.RegionText(rowRegion)))
+	}
+	e.SetCursor(r.Start)
+}
+
+// caseOperator returns an operator which maps the runes in its region with
+// f, and moves to the start of the region. Whole lines are changed without
+// moving the cursor.
+func caseOperator(f func(rune) rune) editarea.Operator {
+	return func(e *editarea.EditArea, r editarea.Region) {
+		p := e.Cursor()
+		changeCase(e, r, f)
+		if r.Linewise {
+			e.SetCursor(p)
+		}
+	}
+}
+
+var (
+	// ToggleCaseOperator switches the case of the runes in a region, like g~
+	ToggleCaseOperator = caseOperator(toggleCase)
+	// UpperCaseOperator makes the runes in a region upper case, like gU
+	UpperCaseOperator = caseOperator(unicode.ToUpper)
+	// LowerCaseOperator makes the runes in a region lower case, like gu
+	LowerCaseOperator = caseOperator(unicode.ToLower)
+)
+
+// visualCase returns a visual mode command which maps the selected runes
+// with f, and leaves visual mode
+func visualCase(f func(rune) rune) editarea.NormalModeCommand {
+	return func(e *editarea.EditArea) {
+		r := e.Selection()
+		e.ExitVisualMode()
+		changeCase(e, r, f)
+		e.SetCursor(r.Start)
+	}
+}
+
+var (
+	// VisualToggleCase switches the case of the selected runes
+	VisualToggleCase = visualCase(toggleCase)
+	// VisualUpperCase makes the selected runes upper case
+	VisualUpperCase = visualCase(unicode.ToUpper)
+	// VisualLowerCase makes the selected runes lower case
+	VisualLowerCase = visualCase(unicode.ToLower)
+)
+
+// ToggleCase switches the case of count runes from the cursor, and moves
+// past them, like ~
+func ToggleCase(e *editarea.EditArea) {
+	p := cursor(e)
+	length := e.Text().LineLength(p.Y)
+	if length == 0 {
+		return
+	}
+	end := p.X + e.Count()
+	if end > length {
+		end = length
+	}
+	changeCase(e, editarea.Region{Start: p, End: area.Point{X: end, Y: p.Y}}, toggleCase)
+	if end == length {
+		end--
+	}
+	e.SetCursor(area.Point{X: end, Y: p.Y})
+}
+
+// numberPattern matches the numbers <C-a> and <C-x> change: dates,
+// hexadecimal and binary numbers with their prefixes, and decimal numbers
+var numberPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}|0[xX][0-9a-fA-F]+|0[bB][01]+|\d+`)
+
+// padded formats n with digits digits in base, with leading zeros
+func padded(n uint64, base, digits int) string {
+	s := strconv.FormatUint(n, base)
+	if len(s) < digits {
+		s = strings.Repeat("0", digits-len(s)) + s
+	}
+	return s
+}
+
+// addToDate adds delta to the part of the date s, in the form 2006-01-02,
+// which is at offset i: years, months, or otherwise days. Adding years or
+// months keeps the day, unless the new month is too short for it.
+func addToDate(s string, i, delta int) (string, bool) {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return "", false
+	}
+	months := delta
+	switch {
+	case i < 4:
+		months *= 12
+	case i >= 7:
+		return t.AddDate(0, 0, delta).Format("2006-01-02"), true
+	}
+	month := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	day := t.Day()
+	if last := month.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return month.AddDate(0, 0, day-1).Format("2006-01-02"), true
+}
+
+// addToNumber adds delta to the number in runes at or after x. Hexadecimal
+// and binary numbers keep their number of digits and case, and so do
+// decimal numbers with leading zeros. A - before a decimal number makes it
+// negative, unless it follows a word. It returns the changed runes and the
+// offset of the last rune of the number.
+func addToNumber(runes []rune, x, delta int) ([]rune, int, bool) {
+	s := string(runes)
+	for _, m := range numberPattern.FindAllStringIndex(s, -1) {
+		start, end := len([]rune(s[:m[0]])), len([]rune(s[:m[1]]))
+		if end <= x {
+			continue
+		}
+		number := s[m[0]:m[1]]
+		var changed string
+		switch {
+		case len(number) == 10 && number[4] == '-':
+			var ok bool
+			if changed, ok = addToDate(number, x-start, delta); !ok {
+				continue
+			}
+		case len(number) > 2 && (number[1] == 'x' || number[1] == 'X'):
+			n, err := strconv.ParseUint(number[2:], 16, 64)
+			if err != nil {
+				continue
+			}
+			digits := padded(n+uint64(delta), 16, len(number)-2)
+			if strings.ContainsAny(number[2:], "ABCDEF") {
+				digits = strings.ToUpper(digits)
+			}
+			changed = number[:2] + digits
+		case len(number) > 2 && (number[1] == 'b' || number[1] == 'B'):
+			n, err := strconv.ParseUint(number[2:], 2, 64)
+			if err != nil {
+				continue
+			}
+			changed = number[:2] + padded(n+uint64(delta), 2, len(number)-2)
+		default:
+			n, err := strconv.ParseInt(number, 10, 64)
+			if err != nil {
+				continue
+			}
+			if start > 0 && runes[start-1] == '-' && (start < 2 || !isWordRune(runes[start-2])) {
+				start--
+				n = -n
+			}
+			n += int64(delta)
+			digits := 0
+			if number[0] == '0' {
+				digits = len(number)
+			}
+			changed = padded(uint64(abs(n)), 10, digits)
+			if n < 0 {
+				changed = "-" + changed
+			}
+		}
+		result := append(append(append([]rune{}, runes[:start]...), []rune(changed)...), runes[end:]...)
+		return result, start + len([]rune(changed)) - 1, true
+	}
+	return runes, x, false
+}
+
+// isWordRune returns whether r can be part of a word
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// addToLine adds delta to the first number at or after x on row, and returns
+// the offset of the number's last rune
+func addToLine(e *editarea.EditArea, row, x, delta int) (int, bool) {
+	runes, end, ok := addToNumber(lineRunes(e.Text(), row), x, delta)
+	if !ok {
+		return 0, false
+	}
+	e.ReplaceRegion(editarea.Region{
+		Start: area.Point{X: 0, Y: row},
+		End:   area.Point{X: e.Text().LineLength(row), Y: row},
+	}, string(runes))
+	return end, true
+}
+
+// increment returns a command which adds count times delta to the number
+// under or after the cursor, like <C-a>, and moves to its last rune
+func increment(delta int) editarea.NormalModeCommand {
+	return func(e *editarea.EditArea) {
+		p := cursor(e)
+		if end, ok := addToLine(e, p.Y, p.X, delta*e.Count()); ok {
+			e.SetCursor(area.Point{X: end, Y: p.Y})
+		}
+	}
+}
+
+var (
+	// Increment adds the count to the number under or after the cursor
+	Increment = increment(1)
+	// Decrement subtracts the count from the number under or after the
+	// cursor
+	Decrement = increment(-1)
+)
+
+// visualIncrement returns a visual mode command which adds count times
+// delta to the first number in the selection on each selected row
+func visualIncrement(delta int) editarea.NormalModeCommand {
+	return func(e *editarea.EditArea) {
+		r := e.Selection()
+		e.ExitVisualMode()
+		for row := r.Start.Y; row <= r.End.Y; row++ {
+			x := 0
+			if r.Blockwise || (!r.Linewise && row == r.Start.Y) {
+				x = r.Start.X
+			}
+			addToLine(e, row, x, delta*e.Count())
+		}
+		e.SetCursor(r.Start)
+	}
+}
+
+var (
+	// VisualIncrement adds the count to the first number on each selected
+	// row
+	VisualIncrement = visualIncrement(1)
+	// VisualDecrement subtracts the count from the first number on each
+	// selected row
+	VisualDecrement = visualIncrement(-1)
+)
+
+// join returns a command which joins count lines from the cursor's, like J,
+// with spaces between them if spaces is set
+func join(spaces bool) editarea.NormalModeCommand {
+	return func(e *editarea.EditArea) {
+		e.JoinLines(e.Cursor().Y, e.Count(), spaces)
+	}
+}
+
+var (
+	// Join joins lines with a space between them
+	Join = join(true)
+	// JoinWithoutSpaces joins lines as they are
+	JoinWithoutSpaces = join(false)
+)
+
+// visualJoin returns a visual mode command which joins the selected lines
+func visualJoin(spaces bool) editarea.NormalModeCommand {
+	return func(e *editarea.EditArea) {
+		r := e.Selection()
+		e.ExitVisualMode()
+		e.JoinLines(r.Start.Y, r.End.Y-r.Start.Y+1, spaces)
+	}
+}
+
+var (
+	// VisualJoin joins the selected lines with spaces between them
+	VisualJoin = visualJoin(true)
+	// VisualJoinWithoutSpaces joins the selected lines as they are
+	VisualJoinWithoutSpaces = visualJoin(false)
+)
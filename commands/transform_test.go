@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	editarea.AddNormalModeCommand("~", ToggleCase)
+	editarea.AddNormalModeCommand("<C-a>", Increment)
+	editarea.AddNormalModeCommand("<C-x>", Decrement)
+	editarea.AddNormalModeCommand("J", Join)
+	editarea.AddNormalModeCommand("gJ", JoinWithoutSpaces)
+	editarea.AddVisualModeCommand("~", VisualToggleCase)
+	editarea.AddVisualModeCommand("U", VisualUpperCase)
+	editarea.AddVisualModeCommand("<C-a>", VisualIncrement)
+	editarea.AddVisualModeCommand("J", VisualJoin)
+	editarea.AddOperator("g~", ToggleCaseOperator)
+	editarea.AddOperator("gu", LowerCaseOperator)
+	editarea.AddOperator("gU", UpperCaseOperator)
+}
+
+func TestTransforms(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		keys   string
+		want   string
+		cursor area.Point
+	}{
+		{"toggling case", "aBc", "~", "ABc", area.Point{X: 1, Y: 0}},
+		{"toggling case with a count", "aBc", "5~", "AbC", area.Point{X: 2, Y: 0}},
+		{"upper case operator", "foo bar", "gUiw", "FOO bar", area.Point{X: 0, Y: 0}},
+		{"lower case lines", "FOO\nBAR", "ll2guu", "foo\nbar", area.Point{X: 2, Y: 0}},
+		{"toggle case operator", "Foo", "g~~", "fOO", area.Point{X: 0, Y: 0}},
+		{"visual case", "foo bar", "wvU", "foo Bar", area.Point{X: 4, Y: 0}},
+		{"visual block case", "ab\ncd", "l<C-v>j~", "aB\ncD", area.Point{X: 1, Y: 0}},
+		{"toggling the case of non-ASCII text", "éa", "5~", "ÉA", area.Point{X: 1, Y: 0}},
+		{"case operator to the end of a non-ASCII line", "héllo wörld", "wg~$", "héllo WÖRLD", area.Point{X: 6, Y: 0}},
+		{"visual block case to the end of non-ASCII lines", "héllo\nwörld", "<C-v>j$U", "HÉLLO\nWÖRLD", area.Point{X: 0, Y: 0}},
+		{"incrementing after non-ASCII text", "é 5", "<C-a>", "é 6", area.Point{X: 2, Y: 0}},
+		{"incrementing", "x 9 y", "<C-a>", "x 10 y", area.Point{X: 3, Y: 0}},
+		{"incrementing with a count", "x 9 y", "5<C-a>", "x 14 y", area.Point{X: 3, Y: 0}},
+		{"decrementing below zero", "x 1", "2<C-x>", "x -1", area.Point{X: 3, Y: 0}},
+		{"negative numbers", "-5", "<C-a>", "-4", area.Point{X: 1, Y: 0}},
+		{"a dash after a word", "a-5", "<C-a>", "a-6", area.Point{X: 2, Y: 0}},
+		{"leading zeros", "007", "<C-a>", "008", area.Point{X: 2, Y: 0}},
+		{"hex", "0xff", "<C-a>", "0x100", area.Point{X: 4, Y: 0}},
+		{"hex width and case", "0x0F", "<C-a>", "0x10", area.Point{X: 3, Y: 0}},
+		{"upper case hex", "0x0E", "<C-a>", "0x0F", area.Point{X: 3, Y: 0}},
+		{"binary", "0b011", "<C-a>", "0b100", area.Point{X: 4, Y: 0}},
+		{"the day of a date", "2024-02-28", "9l2<C-a>", "2024-03-01", area.Point{X: 9, Y: 0}},
+		{"the month of a date", "2024-01-31", "5l<C-a>", "2024-02-29", area.Point{X: 9, Y: 0}},
+		{"the year of a date", "2024-02-29", "<C-a>", "2025-02-28", area.Point{X: 9, Y: 0}},
+		{"no number", "abc", "<C-a>", "abc", area.Point{X: 0, Y: 0}},
+		{"visual increment", "1\n1\n1", "Vj<C-a>", "2\n2\n1", area.Point{X: 0, Y: 0}},
+		{"joining", "a\n    b", "J", "a b", area.Point{X: 1, Y: 0}},
+		{"joining with a count", "a\nb\nc\nd", "3J", "a b c\nd", area.Point{X: 3, Y: 0}},
+		{"joining after trailing space", "a \nb", "J", "a b", area.Point{X: 2, Y: 0}},
+		{"joining a closing bracket", "f(a\n)", "J", "f(a)", area.Point{X: 3, Y: 0}},
+		{"joining an empty line", "a\n\nb", "J", "a\nb", area.Point{X: 1, Y: 0}},
+		{"joining without spaces", "a\n  b", "gJ", "a  b", area.Point{X: 1, Y: 0}},
+		{"visual join", "a\nb\nc", "VjjJ", "a b c", area.Point{X: 3, Y: 0}},
+		{"joining the last line", "a", "J", "a", area.Point{X: 0, Y: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea(tt.source, area.Point{X: 0, Y: 0})
+			typeNamedKeys(e, tt.keys)
+			assert.Equal(t, tt.want, e.Text().String())
+			assert.Equal(t, tt.cursor, e.Cursor())
+		})
+	}
+}
+
+func TestJoinComments(t *testing.T) {
+	e := newTestEditAreaForFile("test.go", "// a\n// b", area.Point{X: 0, Y: 0})
+	typeNamedKeys(e, "J")
+	assert.Equal(t, "// a b", e.Text().String())
+}
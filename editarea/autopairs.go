@@ -0,0 +1,155 @@
+package editarea
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/line"
+)
+
+// defaultAutoPairs are the pairs of runes typed together in files whose
+// filetype isn't in filetypeAutoPairs
+const defaultAutoPairs = `(:),[:],{:},":",':'`
+
+// filetypeAutoPairs are the pairs of runes typed together in each filetype,
+// by file extension. Single quotes aren't paired where they are usually
+// apostrophes or lifetimes, and backticks are paired where they quote
+// strings.
+var filetypeAutoPairs = map[string]string{
+	".md":   "(:),[:],{:},\":\",`:`",
+	".go":   defaultAutoPairs + ",`:`",
+	".js":   defaultAutoPairs + ",`:`",
+	".ts":   defaultAutoPairs + ",`:`",
+	".rs":   `(:),[:],{:},":"`,
+	".lisp": `(:),[:],{:},":"`,
+	".clj":  `(:),[:],{:},":"`,
+	".el":   `(:),[:],{:},":"`,
+	".scm":  `(:),[:],{:},":"`,
+}
+
+// parseAutoPairs parses the autopairs option. Unlike matchpairs, a pair may
+// open and close with the same rune, like quotes, and the option may be
+// empty, so nothing is paired.
+func parseAutoPairs(s string) ([]bracketPair, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var pairs []bracketPair
+	for _, field := range strings.Split(s, ",") {
+		runes := []rune(field)
+		if len(runes) != 3 || runes[1] != ':' {
+			return nil, fmt.Errorf("invalid pair %q", field)
+		}
+		pairs = append(pairs, bracketPair{runes[0], runes[2]})
+	}
+	return pairs, nil
+}
+
+// autoPairs returns the pairs in the autopairs option
+func (e *EditArea) autoPairs() []bracketPair {
+	// The option is checked when it is set
+	pairs, _ := parseAutoPairs(e.options.AutoPairs)
+	return pairs
+}
+
+// runeAt returns the rune at x on the cursor's row, and false if there isn't
+// one
+func (e *EditArea) runeAt(x int) (rune, bool) {
+	runes := []rune(e.text.Line(e.cursor.Y).String())
+	if x < 0 || x >= len(runes) {
+		return 0, false
+	}
+	return runes[x], true
+}
+
+// inStringOrComment returns whether the cursor is inside a string or a
+// comment, where runes aren't paired
+func (e *EditArea) inStringOrComment() bool {
+	row := e.cursor.Y
+	g := e.grid(row-matchLimit, row+1)
+	before, ok := g.at(area.Point{X: e.cursor.X - 1, Y: row})
+	if !ok || before.IsCode() {
+		return false
+	}
+	after, ok := g.at(e.cursor)
+	if !ok {
+		// Only a line comment carries on past the end of the line
+		return before.InLineComment()
+	}
+	return !after.IsCode()
+}
+
+// betweenPair returns the pair whose opening rune is before the cursor and
+// closing rune is under it, if there is one
+func (e *EditArea) betweenPair() (bracketPair, bool) {
+	before, ok := e.runeAt(e.cursor.X - 1)
+	if !ok {
+		return bracketPair{}, false
+	}
+	after, ok := e.runeAt(e.cursor.X)
+	if !ok {
+		return bracketPair{}, false
+	}
+	for _, pair := range e.autoPairs() {
+		if pair.open == before && pair.close == after {
+			return pair, true
+		}
+	}
+	return bracketPair{}, false
+}
+
+// autoPair handles r being typed in insert mode, if it is in one of the
+// autopairs. A closing rune steps over the same rune under the cursor, and an
+// opening rune is inserted with its closing rune after the cursor, unless the
+// cursor is in a string or comment, or before a word. It returns whether r
+// has been handled.
+func (e *EditArea) autoPair(r rune) bool {
+	pairs := e.autoPairs()
+	next, hasNext := e.runeAt(e.cursor.X)
+	closer := false
+	for _, pair := range pairs {
+		if hasNext && next == pair.close {
+			closer = true
+		}
+		if r == pair.close && hasNext && next == r {
+			e.cursor.X++
+			e.scrollToCursor()
+			return true
+		}
+	}
+	for _, pair := range pairs {
+		if r != pair.open {
+			continue
+		}
+		if hasNext && !unicode.IsSpace(next) && !closer {
+			return false
+		}
+		if pair.open == pair.close {
+			// Quotes after a word are usually apostrophes
+			if prev, ok := e.runeAt(e.cursor.X - 1); ok && (unicode.IsLetter(prev) || unicode.IsDigit(prev)) {
+				return false
+			}
+		}
+		if e.inStringOrComment() {
+			return false
+		}
+		e.Insert(pair.open)
+		e.Insert(pair.close)
+		e.cursor.X--
+		return true
+	}
+	return false
+}
+
+// openPairedBlock splits the line between a pair of brackets which has just
+// been broken by LineBreak, so the closing bracket is on its own line below
+// the cursor, which is on a new line indented inside the block
+func (e *EditArea) openPairedBlock() {
+	row := e.cursor.Y
+	e.text = e.text.InsertLine(row, line.New(""))
+	e.cursor = area.Point{X: 0, Y: row}
+	e.autoIndent(row)
+	e.scrollToCursor()
+}
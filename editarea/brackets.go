@@ -0,0 +1,334 @@
+package editarea
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/syntax"
+	"github.com/jamesroutley/fuji/theme"
+)
+
+// matchLimit is the number of lines either side of the cursor searched for
+// the brackets to highlight or jump to, which keeps the search fast in long
+// files. Brackets further apart aren't matched: they aren't highlighted, and
+// % says there's no match within matchLimit lines. Brackets in strings and
+// comments are skipped using the highlighter's tokens, so in files too long
+// to highlight at once they may not be skipped until the text has been
+// tokenised in the background.
+const matchLimit = 300
+
+// bracketPair is a pair of brackets which match each other
+type bracketPair struct {
+	open, close rune
+}
+
+// parseMatchPairs parses the matchpairs option
+func parseMatchPairs(s string) ([]bracketPair, error) {
+	var pairs []bracketPair
+	for _, field := range strings.Split(s, ",") {
+		runes := []rune(field)
+		if len(runes) != 3 || runes[1] != ':' || runes[0] == runes[2] {
+			return nil, fmt.Errorf("invalid pair %q", field)
+		}
+		pairs = append(pairs, bracketPair{runes[0], runes[2]})
+	}
+	return pairs, nil
+}
+
+// runeGrid holds the styled runes of a range of rows of the text, so that
+// they can be searched without tokenising rows more than once
+type runeGrid struct {
+	lines [][]syntax.StyledRune
+	// first is the row of lines[0]
+	first int
+}
+
+// grid returns the styled runes of rows [start, end) of the text
+func (e *EditArea) grid(start, end int) runeGrid {
+	if start < 0 {
+		start = 0
+	}
+	if end > e.text.Length() {
+		end = e.text.Length()
+	}
+	return runeGrid{lines: e.highlighter.Lines(e.text, start, end), first: start}
+}
+
+// at returns the styled rune at p
+func (g runeGrid) at(p area.Point) (sr syntax.StyledRune, ok bool) {
+	i := p.Y - g.first
+	if i < 0 || i >= len(g.lines) || p.X < 0 || p.X >= len(g.lines[i]) {
+		return sr, false
+	}
+	return g.lines[i][p.X], true
+}
+
+// next returns the position of the rune after p, skipping empty rows
+func (g runeGrid) next(p area.Point) (area.Point, bool) {
+	p.X++
+	for p.Y-g.first < len(g.lines) {
+		if p.X < len(g.lines[p.Y-g.first]) {
+			return p, true
+		}
+		p = area.Point{X: 0, Y: p.Y + 1}
+	}
+	return p, false
+}
+
+// prev returns the position of the rune before p, skipping empty rows
+func (g runeGrid) prev(p area.Point) (area.Point, bool) {
+	p.X--
+	for p.X < 0 {
+		p.Y--
+		if p.Y < g.first {
+			return p, false
+		}
+		p.X = len(g.lines[p.Y-g.first]) - 1
+	}
+	return p, true
+}
+
+// matchBracket returns the position of the bracket which matches the one at
+// p. Brackets in comments and strings are only matched with brackets which
+// are also in comments or strings.
+func (g runeGrid) matchBracket(pairs []bracketPair, p area.Point) (area.Point, bool) {
+	start, ok := g.at(p)
+	if !ok {
+		return p, false
+	}
+	for _, pair := range pairs {
+		var step func(area.Point) (area.Point, bool)
+		var same, other rune
+		switch start.Rune {
+		case pair.open:
+			step, same, other = g.next, pair.open, pair.close
+		case pair.close:
+			step, same, other = g.prev, pair.close, pair.open
+		default:
+			continue
+		}
+		depth := 0
+		for q, ok := step(p); ok; q, ok = step(q) {
+			sr, _ := g.at(q)
+			if sr.IsCode() != start.IsCode() {
+				continue
+			}
+			switch sr.Rune {
+			case same:
+				depth++
+			case other:
+				if depth == 0 {
+					return q, true
+				}
+				depth--
+			}
+		}
+		return p, false
+	}
+	return p, false
+}
+
+// enclosingBracket returns the position of the opening bracket of the
+// innermost pair which contains p
+func (g runeGrid) enclosingBracket(pairs []bracketPair, p area.Point) (area.Point, bool) {
+	var closers []rune
+	for q, ok := g.prev(p); ok; q, ok = g.prev(q) {
+		sr, _ := g.at(q)
+		if !sr.IsCode() {
+			continue
+		}
+		for _, pair := range pairs {
+			switch sr.Rune {
+			case pair.close:
+				closers = append(closers, pair.close)
+			case pair.open:
+				if len(closers) == 0 {
+					return q, true
+				}
+				if closers[len(closers)-1] == pair.close {
+					closers = closers[:len(closers)-1]
+				}
+			}
+		}
+	}
+	return p, false
+}
+
+// matchingBrackets returns the positions of the pair of brackets under or
+// around the cursor, which are highlighted
+func (e *EditArea) matchingBrackets() []area.Point {
+	pairs, err := parseMatchPairs(e.options.MatchPairs)
+	if err != nil {
+		return nil
+	}
+	g := e.grid(e.cursor.Y-matchLimit, e.cursor.Y+matchLimit)
+	cursor := area.Point{X: e.cursorColumn(), Y: e.cursor.Y}
+	if match, ok := g.matchBracket(pairs, cursor); ok {
+		return []area.Point{cursor, match}
+	}
+	if open, ok := g.enclosingBracket(pairs, cursor); ok {
+		if match, ok := g.matchBracket(pairs, open); ok {
+			return []area.Point{open, match}
+		}
+	}
+	return nil
+}
+
+// highlightBrackets highlights the brackets at positions in content, which is
+// drawn for segment s
+func (e *EditArea) highlightBrackets(s segment, content []syntax.StyledRune, positions []area.Point) []syntax.StyledRune {
+	copied := false
+	for _, p := range positions {
+		if p.Y != s.row {
+			continue
+		}
+		x := e.displayColumn(p.Y, p.X) - s.start + s.prefix
+		if x < s.prefix || x >= len(content) {
+			continue
+		}
+		if !copied {
+			// content may share its runes with the cells of the row
+			content = append([]syntax.StyledRune(nil), content...)
+			copied = true
+		}
+		content[x].Style = theme.UI(theme.MatchParen)
+	}
+	return content
+}
+
+// JumpToMatch moves the cursor to the bracket which matches the one under
+// the cursor or, if the cursor isn't on a bracket, the first bracket after
+// the cursor on its line. If matchtags is set and the cursor is in an HTML or
+// XML tag, the cursor moves to the matching tag instead.
+func (e *EditArea) JumpToMatch() {
+	pairs, err := parseMatchPairs(e.options.MatchPairs)
+	if err != nil {
+		return
+	}
+	g := e.grid(e.cursor.Y-matchLimit, e.cursor.Y+matchLimit)
+	if e.options.MatchTags {
+		if p, ok := g.matchTag(area.Point{X: e.cursorColumn(), Y: e.cursor.Y}); ok {
+			e.pushJump()
+			e.SetCursor(p)
+			return
+		}
+	}
+	line := g.lines[e.cursor.Y-g.first]
+	for x := e.cursorColumn(); x < len(line); x++ {
+		for _, pair := range pairs {
+			if line[x].Rune != pair.open && line[x].Rune != pair.close {
+				continue
+			}
+			if p, ok := g.matchBracket(pairs, area.Point{X: x, Y: e.cursor.Y}); ok {
+				e.pushJump()
+				e.SetCursor(p)
+			} else if g.first > 0 || g.first+len(g.lines) < e.text.Length() {
+				e.SetMessage("no match within %d lines", matchLimit)
+			}
+			return
+		}
+	}
+}
+
+// tagPattern matches the start of an HTML or XML tag, capturing whether it
+// is a closing tag and its name
+var tagPattern = regexp.MustCompile(`<(/?)([A-Za-z][\w:.-]*)`)
+
+// tag is an HTML or XML tag
+type tag struct {
+	start       area.Point
+	name        string
+	closing     bool
+	selfClosing bool
+}
+
+// tags returns the tags in the code of g, in order
+func (g runeGrid) tags() []tag {
+	var tags []tag
+	// open is the index of the tag whose end hasn't been found yet
+	open := -1
+	for i, line := range g.lines {
+		runes := make([]rune, len(line))
+		for x, sr := range line {
+			runes[x] = sr.Rune
+		}
+		s := string(runes)
+		first := len(tags)
+		for _, m := range tagPattern.FindAllStringSubmatchIndex(s, -1) {
+			// Byte offsets are converted to rune offsets
+			x := len([]rune(s[:m[0]]))
+			if !line[x].IsCode() {
+				continue
+			}
+			tags = append(tags, tag{
+				start:   area.Point{X: x, Y: g.first + i},
+				name:    s[m[4]:m[5]],
+				closing: m[3] > m[2],
+			})
+		}
+		// A tag ending in "/>", possibly on a later line, is self-closing
+		next := first
+		for x, sr := range line {
+			if next < len(tags) && tags[next].start.X == x {
+				open = next
+				next++
+				continue
+			}
+			if sr.Rune == '>' && open >= 0 {
+				tags[open].selfClosing = x > 0 && line[x-1].Rune == '/'
+				open = -1
+			}
+		}
+	}
+	return tags
+}
+
+// matchTag returns the start of the tag which matches the tag which contains
+// p
+func (g runeGrid) matchTag(p area.Point) (area.Point, bool) {
+	tags := g.tags()
+	current := -1
+	for i, t := range tags {
+		if t.start.Y != p.Y || t.start.X > p.X {
+			continue
+		}
+		// The cursor is in the tag if there's no '>' between its start and
+		// the cursor
+		line := g.lines[p.Y-g.first]
+		closed := false
+		for x := t.start.X; x < p.X && x < len(line); x++ {
+			if line[x].Rune == '>' {
+				closed = true
+			}
+		}
+		if !closed {
+			current = i
+		}
+	}
+	if current < 0 || tags[current].selfClosing {
+		return p, false
+	}
+	t := tags[current]
+	step := 1
+	if t.closing {
+		step = -1
+	}
+	depth := 0
+	for i := current + step; i >= 0 && i < len(tags); i += step {
+		other := tags[i]
+		if other.name != t.name || other.selfClosing {
+			continue
+		}
+		if other.closing == t.closing {
+			depth++
+			continue
+		}
+		if depth == 0 {
+			return other.start, true
+		}
+		depth--
+	}
+	return p, false
+}
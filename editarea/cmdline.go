@@ -0,0 +1,159 @@
+package editarea
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell"
+)
+
+// ExCommand defines the behaviour of a command run from the command line.
+// r is the range of lines given before the command's name, which defaults
+// to the line the cursor is on; commands with another default can check
+// r.Given. args is the rest of the command line.
+type ExCommand func(e *EditArea, r Range, args string) error
+
+// Range is an inclusive range of lines in the text
+type Range struct {
+	Start, End int
+	// Given is whether the range was typed on the command line, rather than
+	// being the default
+	Given bool
+}
+
+var exCommands = make(map[string]ExCommand)
+
+// AddExCommand adds a new command line command
+func AddExCommand(name string, behaviour ExCommand) {
+	exCommands[name] = behaviour
+}
+
+// EnterCommandMode switches the EditArea into command mode, with an empty
+// command line
+func (e *EditArea) EnterCommandMode() {
+	e.commandLine = nil
+	e.Mode = ModeCommand
+}
+
+// CommandLine returns the contents of the command line
+func (e *EditArea) CommandLine() string {
+	return string(e.commandLine)
+}
+
+// Message returns the message which should be shown to the user, if any
+func (e *EditArea) Message() string {
+	return e.message
+}
+
+// SetMessage sets a message to show to the user. The message is cleared by
+// the next key press.
+func (e *EditArea) SetMessage(format string, a ...interface{}) {
+	e.message = fmt.Sprintf(format, a...)
+}
+
+func (e *EditArea) handleCommandModeEvent(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyESC:
+		e.Mode = ModeNormal
+	case tcell.KeyEnter:
+		e.Mode = ModeNormal
+		if len(e.commandLine) > 0 {
+			lastCommandLine = string(e.commandLine)
+		}
+		if err := e.RunCommandLine(string(e.commandLine)); err != nil {
+			e.SetMessage("%s", err)
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(e.commandLine) == 0 {
+			e.Mode = ModeNormal
+			return
+		}
+		e.commandLine = e.commandLine[:len(e.commandLine)-1]
+	case tcell.KeyRune:
+		e.commandLine = append(e.commandLine, ev.Rune())
+	}
+}
+
+// RunCommandLine parses and runs a command line, such as "1,5sort"
+func (e *EditArea) RunCommandLine(line string) error {
+	line = strings.TrimSpace(line)
+	r, rest, err := e.parseRange(line)
+	if err != nil {
+		return err
+	}
+	name := rest
+	args := ""
+	if i := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) }); i >= 0 {
+		name, args = rest[:i], strings.TrimSpace(rest[i:])
+	}
+	if name == "" {
+		// A bare line number jumps to that line
+		e.pushJump()
+		e.JumpToRow(r.End)
+		return nil
+	}
+	command := exCommands[name]
+	if command == nil {
+		return fmt.Errorf("not an editor command: %s", name)
+	}
+	return command(e, r, args)
+}
+
+// parseRange parses the range at the start of a command line, returning the
+// range and the rest of the line
+func (e *EditArea) parseRange(line string) (r Range, rest string, err error) {
+	if strings.HasPrefix(line, "%") {
+		return Range{0, e.text.Length() - 1, true}, line[1:], nil
+	}
+	start, rest, ok, err := e.parseAddress(line)
+	if err != nil || !ok {
+		return Range{e.row(), e.row(), false}, rest, err
+	}
+	r = Range{start, start, true}
+	if strings.HasPrefix(rest, ",") {
+		end, afterEnd, ok, err := e.parseAddress(rest[1:])
+		if err != nil {
+			return r, rest, err
+		}
+		if !ok {
+			return r, rest, fmt.Errorf("invalid range: %s", line)
+		}
+		r.End, rest = end, afterEnd
+	}
+	if r.Start > r.End {
+		r.Start, r.End = r.End, r.Start
+	}
+	return r, rest, nil
+}
+
+// parseAddress parses a single line address - a line number, "." or "$" -
+// from the start of s. ok is false if s doesn't start with an address.
+func (e *EditArea) parseAddress(s string) (row int, rest string, ok bool, err error) {
+	switch {
+	case strings.HasPrefix(s, "."):
+		return e.row(), s[1:], true, nil
+	case strings.HasPrefix(s, "$"):
+		return e.text.Length() - 1, s[1:], true, nil
+	}
+	i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if i < 0 {
+		i = len(s)
+	}
+	if i == 0 {
+		return 0, s, false, nil
+	}
+	n, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return 0, s, false, err
+	}
+	row = n - 1
+	if row < 0 {
+		row = 0
+	}
+	if row >= e.text.Length() {
+		row = e.text.Length() - 1
+	}
+	return row, s[i:], true, nil
+}
@@ -0,0 +1,163 @@
+package editarea
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/line"
+	"github.com/jamesroutley/fuji/syntax"
+)
+
+// commentStyle is how a line is commented out in a filetype. If end is
+// empty, start begins a line comment, and otherwise start and end wrap each
+// line in a block comment, for languages without line comments.
+type commentStyle struct {
+	start, end string
+}
+
+// commentStyles are the comment styles of filetypes, by file extension.
+// Files with other extensions use the comment markers their lexer
+// recognises.
+var commentStyles = map[string]commentStyle{
+	".go":    {start: "//"},
+	".c":     {start: "//"},
+	".h":     {start: "//"},
+	".cpp":   {start: "//"},
+	".java":  {start: "//"},
+	".js":    {start: "//"},
+	".ts":    {start: "//"},
+	".rs":    {start: "//"},
+	".swift": {start: "//"},
+	".py":    {start: "#"},
+	".rb":    {start: "#"},
+	".sh":    {start: "#"},
+	".yaml":  {start: "#"},
+	".yml":   {start: "#"},
+	".toml":  {start: "#"},
+	".sql":   {start: "--"},
+	".lua":   {start: "--"},
+	".hs":    {start: "--"},
+	".lisp":  {start: ";"},
+	".clj":   {start: ";"},
+	".el":    {start: ";"},
+	".vim":   {start: "\""},
+	".tex":   {start: "%"},
+	".css":   {start: "/*", end: "*/"},
+	".html":  {start: "<!--", end: "-->"},
+	".htm":   {start: "<!--", end: "-->"},
+	".xml":   {start: "<!--", end: "-->"},
+	".md":    {start: "<!--", end: "-->"},
+}
+
+// commentStyle returns how lines are commented out in the file being edited
+func (e *EditArea) commentStyle() (commentStyle, error) {
+	if c, ok := commentStyles[strings.ToLower(filepath.Ext(e.Filename))]; ok {
+		return c, nil
+	}
+	if start, end, ok := syntax.CommentMarkers(e.Filename); ok {
+		return commentStyle{start: start, end: end}, nil
+	}
+	return commentStyle{}, fmt.Errorf("no comment syntax for %s", filepath.Base(e.Filename))
+}
+
+// uncomment returns the text of a line without its indentation, s, with
+// its comment markers removed, and whether it was commented out
+func (c commentStyle) uncomment(s string) (string, bool) {
+	if !strings.HasPrefix(s, c.start) {
+		return s, false
+	}
+	body := strings.TrimPrefix(s, c.start)
+	if c.end != "" {
+		body = strings.TrimRightFunc(body, unicode.IsSpace)
+		if !strings.HasSuffix(body, c.end) {
+			return s, false
+		}
+		body = strings.TrimSuffix(strings.TrimSuffix(body, c.end), " ")
+	}
+	return strings.TrimPrefix(body, " "), true
+}
+
+// comment returns the text of a line without its indentation, s, commented
+// out
+func (c commentStyle) comment(s string) string {
+	if c.end == "" {
+		return c.start + " " + s
+	}
+	return c.start + " " + s + " " + c.end
+}
+
+// ToggleComment comments out the rows from start to end, or uncomments them
+// if they are all commented out already. Blank lines are left alone. The
+// comment markers are put at the indentation of the least indented line, so
+// they line up, and the lines keep their indentation inside the comment.
+func (e *EditArea) ToggleComment(start, end int) error {
+	c, err := e.commentStyle()
+	if err != nil {
+		return err
+	}
+	if end >= e.text.Length() {
+		end = e.text.Length() - 1
+	}
+	uncomment := true
+	column := -1
+	for row := start; row <= end; row++ {
+		if e.blank(row) {
+			continue
+		}
+		width, length := e.indentation(row)
+		if column < 0 || width < column {
+			column = width
+		}
+		rest := string([]rune(e.text.Line(row).String())[length:])
+		if _, ok := c.uncomment(rest); !ok {
+			uncomment = false
+		}
+	}
+	if column < 0 {
+		// There are only blank lines
+		return nil
+	}
+	for row := start; row <= end; row++ {
+		if e.blank(row) {
+			continue
+		}
+		runes := []rune(e.text.Line(row).String())
+		var s string
+		if uncomment {
+			_, length := e.indentation(row)
+			rest, _ := c.uncomment(string(runes[length:]))
+			s = string(runes[:length]) + rest
+		} else {
+			// The markers go after the runes of the indentation which fit in
+			// the least indented line's
+			i := indexOfColumn(runes, column)
+			s = string(runes[:i]) + c.comment(string(runes[i:]))
+		}
+		e.text = e.text.DeleteLine(row).InsertLine(row, line.New(s))
+	}
+	e.beenEdited = true
+	e.beenSaved = false
+	return nil
+}
+
+// indexOfColumn returns the index of the first rune of the indentation of
+// runes which doesn't fit in column columns
+func indexOfColumn(runes []rune, column int) int {
+	width := 0
+	for i, r := range runes {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += tabWidth - width%tabWidth
+		default:
+			return i
+		}
+		if width > column {
+			return i
+		}
+	}
+	return len(runes)
+}
@@ -2,13 +2,17 @@ package editarea
 
 import (
 	"io"
-	"math"
 	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell"
 	"github.com/jamesroutley/fuji/area"
 	"github.com/jamesroutley/fuji/syntax"
 	"github.com/jamesroutley/fuji/text"
+	"github.com/jamesroutley/fuji/theme"
 )
 
 // Mode distinguishes between editor modes
@@ -19,6 +23,13 @@ const (
 	ModeNormal Mode = iota
 	// ModeInsert indicates the editor is in insert mode
 	ModeInsert
+	// ModeCommand indicates the editor is reading a command line
+	ModeCommand
+	// ModeVisual indicates the editor is selecting text
+	ModeVisual
+	// ModeReplace indicates the editor is overwriting text with the text
+	// typed
+	ModeReplace
 )
 
 // NormalModeCommand is a function that defines the behaviour of a normal mode
@@ -31,8 +42,18 @@ type NormalModeCommand func(*EditArea)
 // InsertModeCommand defines the behaviour of an insert mode command
 type InsertModeCommand func(*EditArea)
 
+// scrollOff is the number of lines kept visible above and below the cursor
+const scrollOff = 10
+
+// Chord is a key pressed with modifier keys, like Ctrl-Left, which insert
+// mode commands are bound to
+type Chord struct {
+	Key       tcell.Key
+	Modifiers tcell.ModMask
+}
+
 var normalModeCommands = make(map[string]NormalModeCommand)
-var insertModeCommands = make(map[tcell.Key]InsertModeCommand)
+var insertModeCommands = make(map[Chord]InsertModeCommand)
 
 // EditArea exposes the main API of the text editor
 type EditArea struct {
@@ -46,127 +67,360 @@ type EditArea struct {
 	screen     tcell.Screen
 	lineno     int
 	displayLen int
+	// displayWidth is the width of the area the EditArea was last drawn in
+	displayWidth int
+	// leftcol is the first column of the text displayed when lines aren't
+	// wrapped
+	leftcol int
+	// commandLine holds the command being typed in command mode
+	commandLine []rune
+	message     string
+	// pending is the normal mode command being typed, and running the one
+	// being run
+	pending   command
+	running   command
+	selection selection
+	options   Options
+	// autoIndented is set when autoindent has indented the new line
+	// autoIndentedRow, and nothing else has been typed on it
+	autoIndented    bool
+	autoIndentedRow int
+	// normalOnce is set while a normal mode command is run from insert
+	// mode, which returns to insert mode when it is done
+	normalOnce bool
+	// inserted is the text typed since insert mode was entered, and
+	// replaced the runes overwritten since replace mode was entered
+	inserted []rune
+	replaced []rune
+	lastPut  *lastPut
+	// charCommand is the command waiting for the character typed after it
+	charCommand NormalModeCommand
+	// recording is the name of the register keys are being recorded into,
+	// and recorded the text of the keys typed since recording started
+	recording rune
+	recorded  []string
+	// typeahead holds the keys of the macros being replayed, which are
+	// handled while replaying is set. failed is set when a motion fails,
+	// which stops the macros.
+	typeahead []*tcell.EventKey
+	replaying bool
+	failed    bool
+	// changeStart is the state when the normal mode command being typed
+	// started, insertChange the change which entered insert mode, and undid
+	// is set by undo and redo, which aren't changes
+	changeStart  *changeStart
+	insertChange *change
+	undid        bool
+	// marks holds the local and special marks, which are adjusted for the
+	// lines inserted and deleted since markedText
+	marks      map[rune]area.Point
+	markedText *text.Text
+	// jumps and changes are the jump list and the change list, and
+	// jumpIndex and changeIndex the positions in them which were last moved
+	// to, or their lengths
+	jumps       []area.Point
+	jumpIndex   int
+	changes     []area.Point
+	changeIndex int
+	// signs holds the signs placed in each group, by row, and signsVersion
+	// counts the changes to them
+	signs        map[string]map[int]Sign
+	signsVersion int
+
+	highlighter *syntax.Highlighter
+	// drawn records what is on each row of the display, so that rows which
+	// haven't changed needn't be redrawn
+	drawn        []drawnRow
+	drawnArea    area.Area
+	drawnText    *text.Text
+	drawnLineno  int
+	drawnLeftcol int
+	// drawnRows are the segments of the text drawn on each row
+	drawnRows    []segment
+	drawnVersion int
+	drawnTheme   int
+	drawnCursor  area.Point
+	drawnOptions Options
+	drawnGutter  int
+	drawnSigns   int
+	// drawnSelection is the selection drawn, or the zero Region if there
+	// wasn't one
+	drawnSelection Region
 }
 
 // New returns a new EditArea
 func New(screen tcell.Screen, filename string, r io.ReadWriter) *EditArea {
 	t := text.New(r)
-	return &EditArea{
-		Filename:   filename,
-		Mode:       ModeNormal,
-		history:    newHistory(t, area.Point{X: 0, Y: 0}, 50),
-		text:       t,
-		cursor:     area.Point{X: 0, Y: 0},
-		beenEdited: false,
-		beenSaved:  true,
-		screen:     screen,
-		lineno:     0,
-		displayLen: 0,
+	options := defaultOptions
+	if pairs, ok := filetypeAutoPairs[strings.ToLower(filepath.Ext(filename))]; ok {
+		options.AutoPairs = pairs
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm", ".xml", ".xhtml", ".svg", ".vue":
+		options.MatchTags = true
+	case ".py":
+		options.ExpandTab, options.ShiftWidth = true, 4
+	case ".yaml", ".yml":
+		options.ExpandTab, options.ShiftWidth = true, 2
 	}
+	e := &EditArea{
+		Filename:    filename,
+		Mode:        ModeNormal,
+		history:     newHistory(t, area.Point{X: 0, Y: 0}, 50),
+		text:        t,
+		cursor:      area.Point{X: 0, Y: 0},
+		beenEdited:  false,
+		beenSaved:   true,
+		screen:      screen,
+		lineno:      0,
+		displayLen:  0,
+		options:     options,
+		highlighter: syntax.NewHighlighter(filename),
+		marks:       make(map[rune]area.Point),
+	}
+	e.restoreMarks()
+	return e
 }
 
 // HandleEvent handles the tcell event ev
 func (e *EditArea) HandleEvent(ev *tcell.EventKey) {
+	e.message = ""
+	if !e.replaying {
+		e.failed = false
+		if e.recording != 0 {
+			e.recorded = append(e.recorded, KeyText(ev))
+		}
+	}
+	mode, before, cursor := e.Mode, e.text, e.cursor
+	inserting := e.inserting()
+	selection := e.selection.anchor
+	defer func() {
+		// The text typed in insert mode goes in the . register
+		switch {
+		case !inserting && e.inserting():
+			e.inserted, e.replaced = nil, nil
+		case inserting && !e.inserting():
+			e.clearAutoIndent()
+			lastInserted = string(e.inserted)
+			e.endInsertChange()
+			e.marks['^'] = cursor
+		}
+		if mode == ModeVisual && e.Mode != ModeVisual {
+			e.setSelectionMarks(selection, cursor)
+		}
+		if e.normalOnce && mode != ModeInsert && e.Mode != ModeVisual && e.Mode != ModeCommand &&
+			e.pending == (command{}) && e.charCommand == nil {
+			e.normalOnce = false
+			if e.Mode == ModeNormal {
+				e.Mode = ModeInsert
+			}
+		}
+		e.adjustMarks()
+		if e.text != before && !e.undid {
+			e.recordChange()
+		}
+		e.checkpoint()
+	}()
 	switch e.Mode {
-	case ModeNormal:
+	case ModeNormal, ModeVisual:
 		e.handleNormalModeEvent(ev)
-	case ModeInsert:
+	case ModeInsert, ModeReplace:
 		e.handleInsertModeEvent(ev)
+	case ModeCommand:
+		e.handleCommandModeEvent(ev)
 	default:
 		panic("Should not reach here")
 	}
 }
 
-func (e *EditArea) handleNormalModeEvent(ev *tcell.EventKey) {
-	if ev.Key() != tcell.KeyRune {
+func (e *EditArea) handleInsertModeEvent(ev *tcell.EventKey) {
+	e.recordInsert(ev)
+	if c := e.charCommand; c != nil {
+		// A command, like <C-r>, is reading a character
+		e.charCommand = nil
+		e.running, e.pending = e.pending, command{}
+		if ev.Key() == tcell.KeyRune {
+			e.running.char = ev.Rune()
+			c(e)
+		}
 		return
 	}
-	command := normalModeCommands[string(ev.Rune())]
-	if command == nil {
+	switch ev.Key() {
+	case tcell.KeyRune:
+		e.inserted = append(e.inserted, ev.Rune())
+	case tcell.KeyEnter:
+		e.inserted = append(e.inserted, '\n')
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(e.inserted) > 0 {
+			e.inserted = e.inserted[:len(e.inserted)-1]
+		}
+	}
+	if e.Mode == ModeReplace && e.handleReplaceKey(ev) {
 		return
 	}
-	command(e)
-}
-
-func (e *EditArea) handleInsertModeEvent(ev *tcell.EventKey) {
-	command := insertModeCommands[ev.Key()]
-	if command != nil {
-		command(e)
+	c, ok := insertModeCommands[Chord{ev.Key(), ev.Modifiers()}]
+	if !ok {
+		// Commands bound to a key without modifiers are run whichever
+		// modifiers are held
+		c = insertModeCommands[Chord{Key: ev.Key()}]
+	}
+	if c != nil {
+		// Insert mode commands don't have a count
+		e.running = command{}
+		c(e)
 		return
 	}
 	if ev.Key() != tcell.KeyRune {
 		return
 	}
+	if e.Mode == ModeInsert && e.autoPair(ev.Rune()) {
+		e.autoWrap()
+		return
+	}
 	e.Insert(ev.Rune())
+	if e.bracketKind(ev.Rune()) == closeBracket {
+		e.alignCloser()
+	}
+	if e.Mode == ModeInsert && !unicode.IsSpace(ev.Rune()) {
+		e.autoWrap()
+	}
 }
 
-// AddNormalModeCommand adds a new command to the editor
+// AddNormalModeCommand adds a new command to the editor. name is the keys
+// which run the command, which may be more than one key long, such as "gj".
 func AddNormalModeCommand(name string, behaviour NormalModeCommand) {
 	normalModeCommands[name] = behaviour
 }
 
-// AddInsertModeCommand adds a new insert mode command
+// AddInsertModeCommand adds a new insert mode command, which is run by
+// pressing key
 func AddInsertModeCommand(key tcell.Key, behaviour InsertModeCommand) {
-	insertModeCommands[key] = behaviour
+	insertModeCommands[Chord{Key: key}] = behaviour
+}
+
+// AddInsertModeChord adds a new insert mode command, which is run by
+// pressing the key and modifiers of c, such as Ctrl-Left
+func AddInsertModeChord(c Chord, behaviour InsertModeCommand) {
+	insertModeCommands[c] = behaviour
+}
+
+// NormalModeOnce switches from insert mode to normal mode for one command,
+// then back to insert mode, like vim's <C-o>
+func (e *EditArea) NormalModeOnce() {
+	e.Mode = ModeNormal
+	e.normalOnce = true
 }
 
 // Draw writes the contents of the EditArea to tcell's internal buffer.
 // screen.Show() should be called after Draw() to write the contents to
-// the screen
-func (e *EditArea) Draw(a area.Area) {
+// the screen. Only rows which have changed since the last call to Draw are
+// written; Draw returns the areas of the screen it wrote to.
+func (e *EditArea) Draw(a area.Area) []area.Area {
 	e.displayLen = a.End.Y - a.Start.Y
-	if e.beenEdited {
-		e.history.add(e.text, e.cursor)
-		e.beenEdited = false
+	e.displayWidth = a.End.X - a.Start.X
+	e.scrollToCursor()
+	e.adjustMarks()
+	e.checkpoint()
+
+	if a != e.drawnArea || e.gutterWidth() != e.drawnGutter {
+		e.drawnArea = a
+		e.drawnGutter = e.gutterWidth()
+		e.drawn = make([]drawnRow, e.displayLen)
+	}
+	defer e.displayCursor()
+	if e.signsVersion == e.drawnSigns && e.text == e.drawnText && e.lineno == e.drawnLineno &&
+		e.leftcol == e.drawnLeftcol && e.highlighter.Version() == e.drawnVersion &&
+		theme.Version() == e.drawnTheme && e.cursor == e.drawnCursor &&
+		e.options == e.drawnOptions && e.Selection() == e.drawnSelection &&
+		e.drawnValid() {
+		return nil
 	}
 
-	// TODO: Need to do this in case the iterator panics
-	// defer func() {
-	// 	if perr := recover(); perr != nil {
-	// 		err = perr.(error)
-	// 	}
-	// }()
-
-	styledRunes := syntax.Highlight(e.Filename, e.text.String())
-
-	yMin := math.MaxInt32
-	for _, i := range []int{a.End.Y, e.text.Length()} {
-		if i < yMin {
-			yMin = i
-		}
+	rows := e.displayRows()
+	end := e.lineno
+	if len(rows) > 0 {
+		end = rows[len(rows)-1].row + 1
 	}
-	for y := a.Start.Y; y < yMin; y++ {
-		for x, sr := range styledRunes[y+e.lineno] {
-			switch sr.Rune {
-			case '\t':
-				for i := 0; i < 4; i++ {
-					e.screen.SetContent(x, y, ' ', nil, sr.Style)
-					x++
-				}
-			default:
-				e.screen.SetContent(x, y, sr.Rune, nil, sr.Style)
-				x++
+	styledRunes := e.highlighter.Lines(e.text, e.lineno, end)
+	var damage []area.Area
+	var rowCells []syntax.StyledRune
+	cellsRow := -1
+	_, cursorX := e.cursorSegment(e.layout(e.cursor.Y))
+	selection := e.Selection()
+	var brackets []area.Point
+	if e.options.MatchParen {
+		brackets = e.matchingBrackets()
+	}
+	for i := range e.drawn {
+		var gutter, content []syntax.StyledRune
+		if i < len(rows) {
+			s := rows[i]
+			if s.row != cellsRow {
+				rowCells, cellsRow = e.cells(styledRunes[s.row-e.lineno]), s.row
 			}
+			gutter = e.gutter(s.row, !s.first)
+			content = e.decorate(s, e.segmentContent(s, rowCells), cursorX)
+			content = e.highlightBrackets(s, content, brackets)
+			content = e.highlightSelection(s, content, selection)
+		} else {
+			gutter = e.gutter(e.text.Length(), false)
+		}
+		if e.drawn[i].valid && sameStyledRunes(e.drawn[i].runes, content) &&
+			sameStyledRunes(e.drawn[i].gutter, gutter) {
+			continue
 		}
+		y := a.Start.Y + i
+		e.drawRow(y, gutter, content)
+		e.drawn[i] = drawnRow{gutter: gutter, runes: content, valid: true}
+		damage = append(damage, area.Area{
+			Start: area.Point{X: a.Start.X, Y: y},
+			End:   area.Point{X: a.End.X, Y: y + 1},
+		})
 	}
-
-	e.displayCursor()
+	e.drawnRows = rows
+	e.drawnText = e.text
+	e.drawnLineno = e.lineno
+	e.drawnLeftcol = e.leftcol
+	e.drawnVersion = e.highlighter.Version()
+	e.drawnTheme = theme.Version()
+	e.drawnCursor = e.cursor
+	e.drawnOptions = e.options
+	e.drawnSelection = selection
+	e.drawnSigns = e.signsVersion
+	return damage
 }
 
 func (e *EditArea) displayCursor() {
-	x := e.cursor.X
-	maxX := e.cursorMaxX()
+	cell := e.displayColumn(e.cursor.Y, e.cursorColumn())
+	for i, s := range e.drawnRows {
+		if s.row != e.cursor.Y {
+			continue
+		}
+		last := i+1 == len(e.drawnRows) || e.drawnRows[i+1].row != s.row
+		if cell < s.end || last {
+			e.screen.ShowCursor(
+				e.drawnArea.Start.X+e.drawnGutter+s.prefix+cell-s.start,
+				e.drawnArea.Start.Y+i,
+			)
+			return
+		}
+	}
+	e.screen.HideCursor()
+}
 
-	// If the cursor x is greater than the number of characters on that line,
-	// display the cursor at the end of the line
-	if x >= maxX {
+// cursorColumn returns the column the cursor is displayed at on its line.
+// If the cursor x is greater than the number of characters on that line, the
+// cursor is displayed at the end of the line.
+func (e *EditArea) cursorColumn() int {
+	x := e.cursor.X
+	if maxX := e.cursorMaxX(); x >= maxX {
 		x = maxX
 	}
 	// TODO: pretty hacky!
 	if x < 0 {
 		x = 0
 	}
-	e.screen.ShowCursor(x, e.cursor.Y)
+	return x
 }
 
 // cursorMaxX returns the maximum x that the cursor can be at for the current
@@ -177,7 +431,7 @@ func (e *EditArea) cursorMaxX() (x int) {
 	if x == 0 {
 		return
 	}
-	if e.Mode == ModeNormal {
+	if e.Mode == ModeNormal || e.Mode == ModeVisual {
 		x--
 	}
 	return
@@ -185,29 +439,115 @@ func (e *EditArea) cursorMaxX() (x int) {
 
 // CursorUp moves the cursor up
 func (e *EditArea) CursorUp() {
-	if e.cursor.Y+e.lineno == 0 {
-		return
-	}
-	if e.cursor.Y < 10 && e.lineno > 0 {
-		e.lineno--
+	if e.cursor.Y == 0 {
 		return
 	}
 	e.cursor.Y--
+	e.scrollToCursor()
 }
 
 // CursorDown moves the cursor down
 func (e *EditArea) CursorDown() {
 	// Return early if the cursor is at the end of the document
-	if e.cursor.Y+e.lineno >= e.text.Length()-1 {
+	if e.cursor.Y >= e.text.Length()-1 {
 		return
 	}
-	// If the cursor is under 10 lines from the end of the displayed document
-	// and there is room to scroll
-	if e.displayLen-e.cursor.Y < 10 && e.lineno+e.displayLen < e.text.Length() {
-		e.lineno++
+	e.cursor.Y++
+	e.scrollToCursor()
+}
+
+// JumpToRow moves the cursor to the start of row
+func (e *EditArea) JumpToRow(row int) {
+	if row >= e.text.Length() {
+		row = e.text.Length() - 1
+	}
+	if row < 0 {
+		row = 0
+	}
+	e.cursor = area.Point{X: 0, Y: row}
+	e.scrollToCursor()
+}
+
+// scrollToCursor scrolls the displayed text so that the cursor is at least
+// scrollOff lines from the top and bottom of the display, where possible.
+// When lines aren't wrapped, it also scrolls horizontally so that the cursor
+// is at least sidescrolloff columns from the sides of the display.
+func (e *EditArea) scrollToCursor() {
+	if e.displayLen == 0 {
 		return
 	}
-	e.cursor.Y++
+	margin := e.ScrollOff()
+	if e.cursor.Y-margin < e.lineno {
+		e.lineno = e.cursor.Y - margin
+	}
+	if !e.options.Wrap && e.cursor.Y+margin >= e.lineno+e.displayLen {
+		e.lineno = e.cursor.Y + margin - e.displayLen + 1
+	}
+	if max := e.text.Length() - e.displayLen; e.lineno > max {
+		e.lineno = max
+	}
+	if e.options.Wrap {
+		e.scrollDownToCursor(margin)
+	}
+	if e.lineno < 0 {
+		e.lineno = 0
+	}
+	e.scrollSideways()
+}
+
+// row returns the row of the text that the cursor is on
+func (e *EditArea) row() int {
+	return e.cursor.Y
+}
+
+// Text returns the text being edited
+func (e *EditArea) Text() *text.Text {
+	return e.text
+}
+
+// Cursor returns the position of the cursor in the text
+func (e *EditArea) Cursor() area.Point {
+	return e.cursor
+}
+
+// SetCursor moves the cursor to p, scrolling the display to show it
+func (e *EditArea) SetCursor(p area.Point) {
+	if p.Y >= e.text.Length() {
+		p.Y = e.text.Length() - 1
+	}
+	if p.Y < 0 {
+		p.Y = 0
+	}
+	if p.X < 0 {
+		p.X = 0
+	}
+	e.cursor = p
+	e.scrollToCursor()
+}
+
+// VisibleRows returns the first and last rows of the text on the display
+func (e *EditArea) VisibleRows() (first, last int) {
+	last = e.lineno + e.displayLen - 1
+	if n := len(e.drawnRows); n > 0 && e.drawnLineno == e.lineno {
+		last = e.drawnRows[n-1].row
+	}
+	if last >= e.text.Length() {
+		last = e.text.Length() - 1
+	}
+	return e.lineno, last
+}
+
+// ScrollOff returns the number of rows kept visible above and below the
+// cursor
+func (e *EditArea) ScrollOff() int {
+	margin := scrollOff
+	if m := (e.displayLen - 1) / 2; m < margin {
+		margin = m
+	}
+	if margin < 0 {
+		margin = 0
+	}
+	return margin
 }
 
 // CursorLeft moves the cursor left
@@ -224,7 +564,7 @@ func (e *EditArea) CursorLeft() {
 	}
 	// If the x == 0, move the cursor to the end of the line above
 	if e.cursor.X <= 0 {
-		e.cursor.Y--
+		e.CursorUp()
 		e.cursor.X = e.cursorMaxX()
 		return
 	}
@@ -240,7 +580,7 @@ func (e *EditArea) CursorRight() {
 
 	// If at the end of a line, move cursor to beginning of next
 	if e.cursor.X >= e.cursorMaxX() {
-		e.cursor.Y++
+		e.CursorDown()
 		e.cursor.X = 0
 		return
 	}
@@ -271,10 +611,70 @@ func (e *EditArea) CursorAtTextEnd() bool {
 func (e *EditArea) Insert(r rune) {
 	e.beenEdited = true
 	e.text = e.text.Insert(e.cursor.Y, e.cursor.X, r)
-	e.CursorRight()
+	e.cursor.X++
+	e.scrollToCursor()
 	e.beenSaved = false
 }
 
+// Paste inserts the pasted string s at the cursor position.
+// s is inserted as a single edit, so it can be undone in one step, and it
+// bypasses insert mode commands, so pasted newlines and tabs are inserted
+// literally.
+func (e *EditArea) Paste(s string) {
+	if s == "" {
+		return
+	}
+	e.beenEdited = true
+	e.text = e.text.InsertString(e.cursor.Y, e.cursor.X, s)
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		e.CursorDown()
+	}
+	last := utf8.RuneCountInString(lines[len(lines)-1])
+	if len(lines) == 1 {
+		last += e.cursor.X
+	}
+	e.cursor.X = last
+	e.beenSaved = false
+}
+
+// HandlePaste handles text pasted into the terminal. In insert and replace
+// mode it is inserted at the cursor, like Paste. Otherwise it is put like the
+// text of a register, as vim does: before the cursor in normal mode, and in
+// place of the selection in visual mode. In command mode its first line is
+// added to the command line.
+func (e *EditArea) HandlePaste(s string) {
+	if s == "" {
+		return
+	}
+	defer e.checkpoint()
+	r := Register{Text: s, Linewise: strings.HasSuffix(s, "\n")}
+	switch e.Mode {
+	case ModeInsert, ModeReplace:
+		e.Paste(s)
+	case ModeCommand:
+		first := strings.SplitN(s, "\n", 2)[0]
+		e.commandLine = append(e.commandLine, []rune(first)...)
+	case ModeVisual:
+		selection := e.Selection()
+		e.ExitVisualMode()
+		if selection.Linewise {
+			e.ReplaceRegion(selection, withNewline(s))
+			return
+		}
+		e.ReplaceRegion(selection, "")
+		if length := e.text.LineLength(e.cursor.Y); e.cursor.X >= length && length > 0 {
+			// The selection was at the end of the line
+			e.cursor.X = length - 1
+			e.Put(r, false, 1)
+			return
+		}
+		e.Put(r, true, 1)
+	default:
+		e.Put(r, true, 1)
+	}
+}
+
 // Delete deletes the rune under the cursor
 func (e *EditArea) Delete() {
 	e.beenEdited = true
@@ -282,20 +682,34 @@ func (e *EditArea) Delete() {
 	e.beenSaved = false
 }
 
-// LineBreak inserts a line break at the cursor position
+// LineBreak inserts a line break at the cursor position. If autoindent is
+// set, the new line is indented in place of any whitespace after the cursor.
+// Breaking the line between a pair of brackets opens a block between them.
 func (e *EditArea) LineBreak() {
 	e.beenEdited = true
+	e.clearAutoIndent()
+	pair, between := e.betweenPair()
+	block := e.Mode == ModeInsert && between && pair.open != pair.close
+	if e.options.AutoIndent {
+		e.trimAroundCursor()
+	}
 	e.text = e.text.SplitLine(e.cursor.Y, e.cursor.X)
-	e.CursorDown()
-	for e.cursor.X > 0 {
-		e.CursorLeft()
+	e.cursor = area.Point{X: 0, Y: e.cursor.Y + 1}
+	e.scrollToCursor()
+	e.autoIndent(e.cursor.Y)
+	if block {
+		e.openPairedBlock()
 	}
 	e.beenSaved = false
 }
 
-// Backspace handles the backspace event
+// Backspace handles the backspace event. In insert mode, backspacing
+// between an empty pair of brackets or quotes deletes both.
 func (e *EditArea) Backspace() {
 	e.beenEdited = true
+	if _, ok := e.betweenPair(); ok && e.Mode == ModeInsert {
+		e.Delete()
+	}
 	if e.cursor.X == 0 && e.cursor.Y == 0 {
 		return
 	}
@@ -312,26 +726,45 @@ func (e *EditArea) Backspace() {
 	e.beenSaved = false
 }
 
-// Peek returns the rune under the cursor
-func (e *EditArea) Peek() (r rune) {
-	r, _, _, _ = e.screen.GetContent(e.cursor.X, e.cursor.Y)
-	return
+// Peek returns the rune under the cursor, or a space if there isn't one
+func (e *EditArea) Peek() rune {
+	runes := []rune(e.text.Line(e.cursor.Y).String())
+	if e.cursor.X < 0 || e.cursor.X >= len(runes) {
+		return ' '
+	}
+	return runes[e.cursor.X]
+}
+
+// checkpoint adds the text to the history if it has been edited since the
+// last checkpoint, so the edits made by each command are undone one at a
+// time. Like vim, the text typed in insert mode and the keys replayed by a
+// macro are undone in one step.
+func (e *EditArea) checkpoint() {
+	if !e.beenEdited || e.inserting() || e.replaying {
+		return
+	}
+	e.history.add(e.text, e.cursor)
+	e.beenEdited = false
 }
 
 // Undo undoes the last action
 func (e *EditArea) Undo() {
+	e.undid = true
 	e.history.undo()
 	e.text = e.history.head.text
 	e.cursor.X = e.history.head.cursor.X
 	e.cursor.Y = e.history.head.cursor.Y
+	e.scrollToCursor()
 }
 
 // Redo undoes the last undo
 func (e *EditArea) Redo() {
+	e.undid = true
 	e.history.redo()
 	e.text = e.history.head.text
 	e.cursor.X = e.history.head.cursor.X
 	e.cursor.Y = e.history.head.cursor.Y
+	e.scrollToCursor()
 }
 
 // Save saves the file
@@ -1 +1,809 @@
 package editarea
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/clipboard"
+	"github.com/jamesroutley/fuji/line"
+	"github.com/jamesroutley/fuji/syntax"
+	"github.com/jamesroutley/fuji/theme"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCommandLine(t *testing.T) {
+	var got Range
+	var gotArgs string
+	AddExCommand("test", func(e *EditArea, r Range, args string) error {
+		got, gotArgs = r, args
+		return nil
+	})
+	testCases := []struct {
+		line     string
+		expected Range
+		args     string
+	}{
+		{"test", Range{2, 2, false}, ""},
+		{"test a b", Range{2, 2, false}, "a b"},
+		{"%test", Range{0, 4, true}, ""},
+		{"2,4test", Range{1, 3, true}, ""},
+		{".,$test!", Range{2, 4, true}, "!"},
+		{"4,2test", Range{1, 3, true}, ""},
+		{"9test", Range{4, 4, true}, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			e := newTestEditArea("a\nb\nc\nd\ne")
+			e.JumpToRow(2)
+			assert.NoError(t, e.RunCommandLine(tc.line))
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.args, gotArgs)
+		})
+	}
+}
+
+func TestRunCommandLineErrors(t *testing.T) {
+	e := newTestEditArea("a\nb")
+	assert.Error(t, e.RunCommandLine("notacommand"))
+	assert.Error(t, e.RunCommandLine("1,test"))
+}
+
+func TestRunCommandLineJumpsToLine(t *testing.T) {
+	e := newTestEditArea("a\nb\nc")
+	assert.NoError(t, e.RunCommandLine("3"))
+	assert.Equal(t, 2, e.row())
+}
+
+// newTestEditArea returns an EditArea containing source, drawn to a
+// simulation screen
+func newTestEditArea(source string) *EditArea {
+	return newTestEditAreaForFile("test.txt", source)
+}
+
+// newTestEditAreaForFile returns an EditArea containing source, highlighted
+// as the file filename
+func newTestEditAreaForFile(filename, source string) *EditArea {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		panic(err)
+	}
+	screen.SetSize(80, 25)
+	return New(screen, filename, bytes.NewBufferString(source))
+}
+
+func TestDrawOnlyRedrawsChangedRows(t *testing.T) {
+	e := newTestEditArea("a\nb\nc")
+	a := area.Area{End: area.Point{X: 10, Y: 5}}
+
+	assert.Len(t, e.Draw(a), 5)
+	assert.Empty(t, e.Draw(a))
+
+	e.JumpToRow(1)
+	e.Insert('x')
+	damage := e.Draw(a)
+	assert.Equal(t, []area.Area{{
+		Start: area.Point{X: 0, Y: 1},
+		End:   area.Point{X: 10, Y: 2},
+	}}, damage)
+
+	e.Invalidate()
+	assert.Len(t, e.Draw(a), 5)
+}
+
+func TestMultiKeyNormalModeCommands(t *testing.T) {
+	var ran []string
+	AddNormalModeCommand("qx", func(e *EditArea) { ran = append(ran, "qx") })
+	AddNormalModeCommand("qyz", func(e *EditArea) { ran = append(ran, "qyz") })
+	e := newTestEditArea("a")
+	for _, r := range "qxqyzqqx" {
+		e.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	assert.Equal(t, []string{"qx", "qyz"}, ran)
+	assert.Equal(t, command{}, e.pending)
+}
+
+func TestSetOptions(t *testing.T) {
+	e := newTestEditArea("a")
+	assert.NoError(t, e.SetOptions("nu rnu nuw=6 scl=yes"))
+	expected := defaultOptions
+	expected.Number, expected.RelativeNumber = true, true
+	expected.NumberWidth, expected.SignColumn = 6, "yes"
+	assert.Equal(t, expected, *e.Options())
+
+	assert.NoError(t, e.SetOptions("nonu invrnu"))
+	assert.False(t, e.Options().Number)
+	assert.False(t, e.Options().RelativeNumber)
+	assert.NoError(t, e.SetOptions("number!"))
+	assert.True(t, e.Options().Number)
+
+	assert.NoError(t, e.SetOptions("nu? scl?"))
+	assert.Equal(t, "number  signcolumn=yes", e.Message())
+
+	for _, args := range []string{"notanoption", "nu=1", "nuw=x", "nonuw", "scl=sometimes"} {
+		assert.Error(t, e.SetOptions(args), args)
+	}
+}
+
+func TestGutter(t *testing.T) {
+	testCases := []struct {
+		options  string
+		expected []string
+	}{
+		{"nu", []string{"  1 ", "  2 ", "  3 "}},
+		{"rnu", []string{"  1 ", "  0 ", "  1 "}},
+		{"nu rnu", []string{"  1 ", "2   ", "  1 "}},
+		{"nonu nornu", []string{"", "", ""}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.options, func(t *testing.T) {
+			e := newTestEditArea("a\nb\nc")
+			e.JumpToRow(1)
+			assert.NoError(t, e.SetOptions(tc.options))
+			for row, expected := range tc.expected {
+				assert.Equal(t, expected, styledString(e.gutter(row, false)))
+			}
+		})
+	}
+}
+
+func TestSigns(t *testing.T) {
+	e := newTestEditArea("a\nb\nc")
+	assert.Equal(t, 0, e.gutterWidth())
+
+	e.PlaceSign("diagnostics", 1, Sign{Text: "E", Priority: 2})
+	e.PlaceSign("git", 1, Sign{Text: "+", Priority: 1})
+	e.PlaceSign("git", 2, Sign{Text: "~~~"})
+	assert.Equal(t, signWidth, e.gutterWidth())
+	assert.Equal(t, "  ", styledString(e.gutter(0, false)))
+	assert.Equal(t, "E ", styledString(e.gutter(1, false)))
+	assert.Equal(t, "~~", styledString(e.gutter(2, false)))
+
+	e.RemoveSign("diagnostics", 1)
+	assert.Equal(t, "+ ", styledString(e.gutter(1, false)))
+	e.ClearSigns("git")
+	assert.Equal(t, 0, e.gutterWidth())
+}
+
+func TestSignsWithEqualPriorities(t *testing.T) {
+	e := newTestEditArea("a")
+	for _, group := range []string{"d", "b", "c", "a", "e"} {
+		e.PlaceSign(group, 0, Sign{Text: group})
+	}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, "a ", styledString(e.gutter(0, false)))
+	}
+}
+
+func TestSignsMoveWithLines(t *testing.T) {
+	e := newTestEditArea("a\nb\nc")
+	a := area.Area{End: area.Point{X: 20, Y: 5}}
+	e.PlaceSign("git", 1, Sign{Text: "+"})
+	e.PlaceSign("git", 2, Sign{Text: "~"})
+	e.Draw(a)
+
+	e.text = e.text.InsertLine(0, line.New("new"))
+	e.Draw(a)
+	assert.Equal(t, map[int]Sign{2: {Text: "+"}, 3: {Text: "~"}}, e.signs["git"])
+
+	e.text = e.text.DeleteLine(2)
+	e.Draw(a)
+	assert.Equal(t, map[int]Sign{2: {Text: "~"}}, e.signs["git"])
+}
+
+func TestDrawShowsChangedSigns(t *testing.T) {
+	e := newTestEditArea("a\nb")
+	assert.NoError(t, e.SetOptions("signcolumn=yes"))
+	a := area.Area{End: area.Point{X: 20, Y: 5}}
+	e.Draw(a)
+	e.PlaceSign("git", 1, Sign{Text: "+"})
+	assert.Len(t, e.Draw(a), 1)
+	assert.Empty(t, e.Draw(a))
+}
+
+func TestClickAccountsForGutter(t *testing.T) {
+	e := newTestEditArea("a\n\tbcd\ne")
+	assert.NoError(t, e.SetOptions("nu"))
+	e.Draw(area.Area{End: area.Point{X: 20, Y: 5}})
+
+	e.Click(area.Point{X: 9, Y: 1})
+	assert.Equal(t, area.Point{X: 2, Y: 1}, e.cursor)
+	e.Click(area.Point{X: 0, Y: 2})
+	assert.Equal(t, area.Point{X: 0, Y: 2}, e.cursor)
+	e.Click(area.Point{X: 19, Y: 1})
+	assert.Equal(t, area.Point{X: 3, Y: 1}, e.cursor)
+	e.Click(area.Point{X: 5, Y: 4})
+	assert.Equal(t, area.Point{X: 3, Y: 1}, e.cursor)
+}
+
+func TestWrap(t *testing.T) {
+	e := newTestEditArea("short\n  the quick brown fox jumps\nend")
+	assert.NoError(t, e.SetOptions("wrap"))
+	e.Options().ShowBreak = ">"
+	a := area.Area{End: area.Point{X: 12, Y: 6}}
+	e.Draw(a)
+
+	var rows []string
+	for _, row := range e.drawn {
+		rows = append(rows, styledString(row.runes))
+	}
+	assert.Equal(t, []string{"short", "  the quick ", ">  brown ", ">  fox jumps", "end", ""}, rows)
+
+	// gj and gk move between the rows of the wrapped line
+	e.JumpToRow(1)
+	e.cursor.X = 6 // "q" in "quick"
+	e.DisplayDown()
+	assert.Equal(t, area.Point{X: 15, Y: 1}, e.cursor)
+	e.DisplayDown()
+	assert.Equal(t, area.Point{X: 21, Y: 1}, e.cursor)
+	e.DisplayDown()
+	assert.Equal(t, area.Point{X: 2, Y: 2}, e.cursor)
+	e.DisplayUp()
+	assert.Equal(t, area.Point{X: 18, Y: 1}, e.cursor)
+
+	// Clicking on a continuation maps to its part of the line
+	e.Draw(a)
+	e.Click(area.Point{X: 4, Y: 2})
+	assert.Equal(t, area.Point{X: 13, Y: 1}, e.cursor)
+}
+
+func TestHorizontalScroll(t *testing.T) {
+	e := newTestEditArea("0123456789abcdefghijklmnopqrstuvwxyz\nshort")
+	e.Options().SideScrollOff = 2
+	a := area.Area{End: area.Point{X: 10, Y: 5}}
+	e.Draw(a)
+	assert.Equal(t, "0123456789", styledString(e.drawn[0].runes))
+
+	e.cursor.X = 20
+	e.Draw(a)
+	assert.Equal(t, "defghijklm", styledString(e.drawn[0].runes))
+	assert.Equal(t, "", styledString(e.drawn[1].runes))
+
+	e.ScrollRight()
+	e.Draw(a)
+	assert.Equal(t, "efghijklmn", styledString(e.drawn[0].runes))
+	e.ScrollLeft()
+	e.ScrollLeft()
+	e.Draw(a)
+	assert.Equal(t, "cdefghijkl", styledString(e.drawn[0].runes))
+	assert.Equal(t, 19, e.cursor.X)
+}
+
+func TestWhitespaceAndIndentGuides(t *testing.T) {
+	e := newTestEditArea("\tx\u00a0y  \n        z")
+	a := area.Area{End: area.Point{X: 20, Y: 3}}
+	assert.NoError(t, e.SetOptions(`list lcs=tab:>-,trail:~,nbsp:+`))
+	e.Draw(a)
+	assert.Equal(t, ">---x+y~~", styledString(e.drawn[0].runes))
+	assert.Equal(t, "        z", styledString(e.drawn[1].runes))
+
+	assert.NoError(t, e.SetOptions("nolist ig sw=2"))
+	e.Draw(a)
+	assert.Equal(t, "│ │ │ │ z", styledString(e.drawn[1].runes))
+
+	assert.Error(t, e.SetOptions("lcs=tab:>"))
+	assert.Error(t, e.SetOptions("lcs=bogus"))
+}
+
+func TestCursorLineAndColumns(t *testing.T) {
+	e := newTestEditArea("abc\nde\nf")
+	a := area.Area{End: area.Point{X: 6, Y: 3}}
+	assert.NoError(t, e.SetOptions("cul cuc cc=5"))
+	e.cursor = area.Point{X: 1, Y: 1}
+	e.Draw(a)
+
+	_, cursorLine, _ := theme.UI(theme.CursorLine).Decompose()
+	_, colorColumn, _ := theme.UI(theme.ColorColumn).Decompose()
+	background := func(row, x int) tcell.Color {
+		_, bg, _ := e.drawn[row].runes[x].Style.Decompose()
+		return bg
+	}
+	for _, row := range e.drawn {
+		assert.Len(t, row.runes, 6)
+	}
+	for x := 0; x < 6; x++ {
+		assert.Equal(t, cursorLine, background(1, x), "cursor line %d", x)
+	}
+	assert.Equal(t, cursorLine, background(0, 1))
+	assert.Equal(t, cursorLine, background(2, 1))
+	assert.NotEqual(t, cursorLine, background(0, 0))
+	assert.Equal(t, colorColumn, background(0, 4))
+
+	assert.Error(t, e.SetOptions("cc=0"))
+	assert.Error(t, e.SetOptions("cc=a"))
+}
+
+func TestHandlePaste(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mode   Mode
+		pasted string
+		// selection is the columns selected in visual mode
+		selection [2]int
+		expected  string
+	}{
+		{"insert mode", ModeInsert, "x\ny", [2]int{}, "abx\nycd"},
+		{"normal mode", ModeNormal, "xy", [2]int{}, "abxycd"},
+		{"normal mode lines", ModeNormal, "x\n", [2]int{}, "x\nabcd"},
+		{"visual mode", ModeVisual, "xy", [2]int{0, 1}, "xycd"},
+		{"visual mode at the end of a line", ModeVisual, "x", [2]int{1, 3}, "ax"},
+		{"command mode", ModeCommand, "x\ny", [2]int{}, "abcd"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEditArea("abcd")
+			e.cursor.X = 2
+			switch tc.mode {
+			case ModeVisual:
+				e.cursor.X = tc.selection[0]
+				e.EnterVisualMode(SelectCharacters)
+				e.cursor.X = tc.selection[1]
+			case ModeCommand:
+				e.EnterCommandMode()
+			default:
+				e.Mode = tc.mode
+			}
+			e.HandlePaste(tc.pasted)
+			assert.Equal(t, tc.expected, e.text.String())
+		})
+	}
+
+	e := newTestEditArea("a")
+	e.EnterCommandMode()
+	e.HandlePaste("sort\nx")
+	assert.Equal(t, "sort", e.CommandLine())
+}
+
+func TestJumpToMatch(t *testing.T) {
+	source := "func f() {\n\tg(a, \")\", (b)) // )\n}"
+	testCases := []struct {
+		start, expected area.Point
+	}{
+		{area.Point{X: 9, Y: 0}, area.Point{X: 0, Y: 2}},
+		{area.Point{X: 0, Y: 2}, area.Point{X: 9, Y: 0}},
+		// Brackets in strings and comments are skipped
+		{area.Point{X: 2, Y: 1}, area.Point{X: 14, Y: 1}},
+		{area.Point{X: 14, Y: 1}, area.Point{X: 2, Y: 1}},
+		// The first bracket after the cursor is used
+		{area.Point{X: 0, Y: 1}, area.Point{X: 14, Y: 1}},
+		{area.Point{X: 5, Y: 0}, area.Point{X: 7, Y: 0}},
+	}
+	for _, tc := range testCases {
+		e := newTestEditAreaForFile("test.go", source)
+		e.cursor = tc.start
+		e.JumpToMatch()
+		assert.Equal(t, tc.expected, e.cursor, "from %v", tc.start)
+	}
+
+	// Only the lines near the cursor are tokenised, so strings are still
+	// skipped in files too long to highlight at once
+	e := newTestEditAreaForFile("test.go", "g(\")\")"+strings.Repeat("\nx", 6000))
+	e.JumpToMatch()
+	assert.Equal(t, area.Point{X: 5, Y: 0}, e.cursor)
+
+	// Brackets too far apart aren't matched
+	e = newTestEditArea("(" + strings.Repeat("\n", matchLimit+1) + ")")
+	e.JumpToMatch()
+	assert.Equal(t, area.Point{X: 0, Y: 0}, e.cursor)
+	assert.Equal(t, "no match within 300 lines", e.Message())
+	e = newTestEditArea("(\nx")
+	e.JumpToMatch()
+	assert.Equal(t, "", e.Message())
+}
+
+func TestJumpToMatchingTag(t *testing.T) {
+	source := "<div class=\"a\">\n  <div><br/></div>\n  <!-- </div> -->\n</div>"
+	testCases := []struct {
+		start, expected area.Point
+	}{
+		{area.Point{X: 3, Y: 0}, area.Point{X: 0, Y: 3}},
+		{area.Point{X: 2, Y: 3}, area.Point{X: 0, Y: 0}},
+		{area.Point{X: 2, Y: 1}, area.Point{X: 12, Y: 1}},
+	}
+	for _, tc := range testCases {
+		e := newTestEditAreaForFile("test.html", source)
+		e.cursor = tc.start
+		e.JumpToMatch()
+		assert.Equal(t, tc.expected, e.cursor, "from %v", tc.start)
+	}
+}
+
+func TestHighlightMatchingBrackets(t *testing.T) {
+	e := newTestEditAreaForFile("test.go", "f(a[1], b)")
+	e.cursor.X = 5
+	e.Draw(area.Area{End: area.Point{X: 20, Y: 2}})
+	matchParen := theme.UI(theme.MatchParen)
+	var highlighted []int
+	for x, sr := range e.drawn[0].runes {
+		if sr.Style == matchParen {
+			highlighted = append(highlighted, x)
+		}
+	}
+	assert.Equal(t, []int{3, 5}, highlighted)
+
+	e.cursor.X = 7
+	e.Draw(area.Area{End: area.Point{X: 20, Y: 2}})
+	highlighted = nil
+	for x, sr := range e.drawn[0].runes {
+		if sr.Style == matchParen {
+			highlighted = append(highlighted, x)
+		}
+	}
+	assert.Equal(t, []int{1, 9}, highlighted)
+}
+
+func TestRegions(t *testing.T) {
+	e := newTestEditArea("foo\nbar\nbaz")
+	charwise := Region{Start: area.Point{X: 1, Y: 0}, End: area.Point{X: 2, Y: 1}}
+	linewise := Region{Start: area.Point{X: 2, Y: 0}, End: area.Point{X: 0, Y: 1}, Linewise: true}
+	assert.Equal(t, "oo\nba", e.RegionText(charwise))
+	assert.Equal(t, "foo\nbar\n", e.RegionText(linewise))
+
+	e.ReplaceRegion(charwise, "x\ny")
+	assert.Equal(t, "fx\nyr\nbaz", e.text.String())
+	assert.Equal(t, area.Point{X: 1, Y: 0}, e.cursor)
+
+	e.ReplaceRegion(linewise, "")
+	assert.Equal(t, "baz", e.text.String())
+	e.ReplaceRegion(Region{Linewise: true}, "a\nb\n")
+	assert.Equal(t, "a\nb", e.text.String())
+	e.ReplaceRegion(Region{End: area.Point{X: 0, Y: 1}, Linewise: true}, "")
+	assert.Equal(t, "", e.text.String())
+	assert.Equal(t, 1, e.text.Length())
+}
+
+func TestVisualSelection(t *testing.T) {
+	e := newTestEditArea("foo\nbar\nbaz")
+	e.cursor.X = 1
+	e.EnterVisualMode(SelectCharacters)
+	e.cursor = area.Point{X: 1, Y: 1}
+	assert.Equal(t, Region{Start: area.Point{X: 1, Y: 0}, End: area.Point{X: 2, Y: 1}}, e.Selection())
+
+	e.Draw(area.Area{End: area.Point{X: 10, Y: 3}})
+	_, selected, _ := theme.UI(theme.Selection).Decompose()
+	highlighted := func(row int) []int {
+		var columns []int
+		for x, sr := range e.drawn[row].runes {
+			if _, bg, _ := sr.Style.Decompose(); bg == selected {
+				columns = append(columns, x)
+			}
+		}
+		return columns
+	}
+	// The line break at the end of the first row is selected
+	assert.Equal(t, []int{1, 2, 3}, highlighted(0))
+	assert.Equal(t, []int{0, 1}, highlighted(1))
+	assert.Empty(t, highlighted(2))
+
+	e.SwapSelectionEnds()
+	assert.Equal(t, area.Point{X: 1, Y: 0}, e.cursor)
+	e.EnterVisualMode(SelectLines)
+	e.Draw(area.Area{End: area.Point{X: 10, Y: 3}})
+	assert.Equal(t, Region{Start: area.Point{X: 1, Y: 0}, End: area.Point{X: 1, Y: 1}, Linewise: true}, e.Selection())
+	assert.Equal(t, []int{0, 1, 2, 3}, highlighted(1))
+
+	e.ExitVisualMode()
+	e.Draw(area.Area{End: area.Point{X: 10, Y: 3}})
+	assert.Equal(t, Region{}, e.Selection())
+	assert.Empty(t, highlighted(0))
+}
+
+func TestRegisters(t *testing.T) {
+	registers, yankRing = make(map[rune]Register), nil
+	e := newTestEditArea("foo bar\nbaz")
+	word := Region{End: area.Point{X: 3, Y: 0}}
+	lines := Region{End: area.Point{X: 0, Y: 1}, Linewise: true}
+
+	e.Yank(word)
+	assert.Equal(t, Register{Text: "foo"}, registers['0'])
+	assert.Equal(t, Register{Text: "foo"}, registers['"'])
+
+	// Small deletes go in -, and bigger ones shift the numbered registers
+	e.StoreDeleted(word)
+	assert.Equal(t, Register{Text: "foo"}, registers['-'])
+	e.StoreDeleted(lines)
+	e.StoreDeleted(Region{End: area.Point{X: 1, Y: 1}})
+	assert.Equal(t, Register{Text: "foo bar\nb"}, registers['1'])
+	assert.Equal(t, Register{Text: "foo bar\nbaz\n", Linewise: true}, registers['2'])
+	assert.Len(t, yankRing, 3)
+
+	// Named registers, and appending to them
+	e.running.register = 'a'
+	e.Yank(word)
+	e.running.register = 'A'
+	e.Yank(Region{Start: area.Point{X: 4, Y: 0}, End: area.Point{X: 7, Y: 0}})
+	assert.Equal(t, Register{Text: "foobar"}, registers['a'])
+	assert.Equal(t, registers['a'], registers['"'])
+	e.running.register = 'A'
+	e.Yank(lines)
+	assert.Equal(t, Register{Text: "foobar\nfoo bar\nbaz\n", Linewise: true}, registers['a'])
+
+	// Read-only registers
+	assert.Error(t, e.SetRegister('%', Register{Text: "x"}))
+	assert.Error(t, e.SetRegister('!', Register{Text: "x"}))
+	r, ok := e.Register('%')
+	assert.True(t, ok)
+	assert.Equal(t, "test.txt", r.Text)
+	_, ok = e.Register('_')
+	assert.False(t, ok)
+
+	// The text typed in insert mode goes in .
+	AddNormalModeCommand("i", func(e *EditArea) { e.Mode = ModeInsert })
+	AddInsertModeCommand(tcell.KeyESC, func(e *EditArea) { e.Mode = ModeNormal })
+	e.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'i', tcell.ModNone))
+	for _, r := range "abc" {
+		e.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	e.HandleEvent(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone))
+	e.HandleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	e.HandleEvent(tcell.NewEventKey(tcell.KeyESC, 0, tcell.ModNone))
+	r, _ = e.Register('.')
+	assert.Equal(t, "ab\n", r.Text)
+}
+
+func TestClipboardRegisters(t *testing.T) {
+	registers, yankRing = make(map[rune]Register), nil
+	fake := &clipboard.Fake{}
+	clipboard.Add("fake", fake)
+	e := newTestEditArea("foo bar\nbaz")
+	assert.NoError(t, e.SetOptions("cbp=fake"))
+	assert.Error(t, e.SetOptions("cbp=nope"))
+	word := Region{End: area.Point{X: 3, Y: 0}}
+	lines := Region{End: area.Point{X: 0, Y: 1}, Linewise: true}
+
+	e.running.register = '+'
+	e.Yank(lines)
+	assert.Equal(t, "foo bar\nbaz\n", fake.Text[clipboard.Clipboard])
+	assert.Equal(t, Register{Text: "foo bar\nbaz\n", Linewise: true}, registers['"'])
+	r, ok := e.Register('+')
+	assert.True(t, ok)
+	assert.True(t, r.Linewise)
+
+	// Text copied elsewhere is linewise if it ends in a newline
+	fake.Text[clipboard.Primary] = "x"
+	r, _ = e.Register('*')
+	assert.Equal(t, Register{Text: "x"}, r)
+
+	// With clipboard=unnamedplus, the clipboard is the default register
+	e.running.register = 0
+	assert.NoError(t, e.SetOptions("cb=unnamedplus"))
+	assert.Equal(t, '+', e.RegisterName())
+	e.Yank(word)
+	assert.Equal(t, "foo", fake.Text[clipboard.Clipboard])
+	assert.Equal(t, Register{Text: "foo"}, registers['0'])
+
+	fake.Err = errors.New("no clipboard")
+	e.Yank(word)
+	assert.Equal(t, "no clipboard", e.Message())
+}
+
+func TestPut(t *testing.T) {
+	tests := []struct {
+		name   string
+		r      Register
+		before bool
+		count  int
+		want   string
+		cursor area.Point
+	}{
+		{"characters", Register{Text: "xy"}, false, 1, "abxyc\nde", area.Point{X: 3, Y: 0}},
+		{"characters before", Register{Text: "xy"}, true, 2, "axyxybc\nde", area.Point{X: 4, Y: 0}},
+		{"lines", Register{Text: "  x\n", Linewise: true}, false, 1, "abc\n  x\nde", area.Point{X: 2, Y: 1}},
+		{"lines before", Register{Text: "x\n", Linewise: true}, true, 2, "x\nx\nabc\nde", area.Point{X: 0, Y: 0}},
+		{"block", Register{Text: "1\n2\n3", Blockwise: true}, false, 1, "ab1c\nde2\n  3", area.Point{X: 2, Y: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditArea("abc\nde")
+			e.cursor.X = 1
+			e.Put(tt.r, tt.before, tt.count)
+			assert.Equal(t, tt.want, e.text.String())
+			assert.Equal(t, tt.cursor, e.cursor)
+		})
+	}
+}
+
+func TestCyclePut(t *testing.T) {
+	registers, yankRing = make(map[rune]Register), nil
+	e := newTestEditArea("a")
+	assert.Error(t, e.CyclePut(1))
+	e.Yank(Region{End: area.Point{X: 1, Y: 0}})
+	e.StoreDeleted(Region{End: area.Point{X: 0, Y: 0}, Linewise: true})
+	e.Put(registers['"'], false, 1)
+	assert.Equal(t, "a\na", e.text.String())
+	assert.NoError(t, e.CyclePut(1))
+	assert.Equal(t, "aa", e.text.String())
+	assert.Error(t, e.CyclePut(1))
+	assert.NoError(t, e.CyclePut(-1))
+	assert.Equal(t, "a\na", e.text.String())
+
+	e.Insert('x')
+	assert.Error(t, e.CyclePut(1))
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := ParseKeys("a<lt><Esc><c-v><nope>\n")
+	var text []string
+	for _, ev := range keys {
+		text = append(text, KeyText(ev))
+	}
+	assert.Equal(t, []string{"a", "<lt>", "<Esc>", "<C-v>", "<lt>", "n", "o", "p", "e", ">", "<CR>"}, text)
+	assert.Equal(t, tcell.KeyCtrlV, keys[3].Key())
+
+	keys = ParseKeys("<C-Left><s-m-right><X-Left>")
+	text = nil
+	for _, ev := range keys {
+		text = append(text, KeyText(ev))
+	}
+	assert.Equal(t, []string{"<C-Left>", "<M-S-Right>", "<lt>", "X", "-", "L", "e", "f", "t", ">"}, text)
+	assert.Equal(t, tcell.ModCtrl, keys[0].Modifiers())
+}
+
+func init() {
+	// Don't read or write the marks saved by the editor
+	marksPath = ""
+}
+
+func TestMarks(t *testing.T) {
+	e := newTestEditArea("a\nb\nc\nd")
+	e.cursor = area.Point{X: 0, Y: 2}
+	assert.NoError(t, e.SetMark('a'))
+	assert.NoError(t, e.SetMark('Z'))
+	assert.Error(t, e.SetMark('!'))
+
+	// Marks follow their lines as lines are inserted and deleted
+	e.text = e.text.InsertLine(0, line.New("new"))
+	p, ok := e.Mark('a')
+	assert.True(t, ok)
+	assert.Equal(t, area.Point{X: 0, Y: 3}, p)
+	p, ok = e.Mark('Z')
+	assert.True(t, ok)
+	assert.Equal(t, 3, p.Y)
+	e.text = e.text.DeleteLine(1)
+	p, _ = e.Mark('a')
+	assert.Equal(t, 2, p.Y)
+
+	// Marks on changed lines stay, and marks on deleted lines are deleted
+	e.text = e.text.Insert(2, 0, 'x')
+	p, ok = e.Mark('a')
+	assert.True(t, ok)
+	assert.Equal(t, 2, p.Y)
+	e.text = e.text.DeleteLine(2)
+	_, ok = e.Mark('a')
+	assert.False(t, ok)
+	_, ok = e.Mark('Z')
+	assert.False(t, ok)
+
+	// Global marks in other files aren't in this one
+	other := newTestEditAreaForFile("other.txt", "a")
+	assert.NoError(t, other.SetMark('Y'))
+	_, ok = e.Mark('Y')
+	assert.False(t, ok)
+	_, _, err := e.JumpToMark('q')
+	assert.Error(t, err)
+}
+
+func TestSpecialMarks(t *testing.T) {
+	e := newTestEditArea("abc\ndef\nghi")
+	AddNormalModeCommand("i", func(e *EditArea) { e.Mode = ModeInsert })
+	AddInsertModeCommand(tcell.KeyESC, func(e *EditArea) { e.Mode = ModeNormal })
+	AddNormalModeCommand("v", func(e *EditArea) { e.EnterVisualMode(SelectCharacters) })
+	e.cursor = area.Point{X: 1, Y: 1}
+	for _, ev := range ParseKeys("ix<Esc>") {
+		e.HandleEvent(ev)
+	}
+	p, _ := e.Mark('^')
+	assert.Equal(t, area.Point{X: 2, Y: 1}, p)
+	p, _ = e.Mark('.')
+	assert.Equal(t, 1, p.Y)
+
+	e.cursor = area.Point{X: 2, Y: 2}
+	e.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'v', tcell.ModNone))
+	e.cursor = area.Point{X: 0, Y: 0}
+	e.HandleEvent(tcell.NewEventKey(tcell.KeyESC, 0, tcell.ModNone))
+	p, _ = e.Mark('<')
+	assert.Equal(t, area.Point{X: 0, Y: 0}, p)
+	p, _ = e.Mark('>')
+	assert.Equal(t, area.Point{X: 2, Y: 2}, p)
+}
+
+func TestJumpList(t *testing.T) {
+	e := newTestEditArea("a\nb\nc\nd\ne")
+	for _, row := range []int{2, 4} {
+		e.pushJump()
+		e.cursor = area.Point{X: 0, Y: row}
+	}
+	p, _ := e.Mark('`')
+	assert.Equal(t, 2, p.Y)
+	assert.NoError(t, e.JumpOlder(1))
+	assert.Equal(t, 2, e.cursor.Y)
+	assert.NoError(t, e.JumpOlder(1))
+	assert.Equal(t, 0, e.cursor.Y)
+	assert.Error(t, e.JumpOlder(1))
+	assert.NoError(t, e.JumpNewer(2))
+	assert.Equal(t, 4, e.cursor.Y)
+	assert.Error(t, e.JumpNewer(1))
+
+	// Jumps from a row already in the list replace the older entry
+	e = newTestEditArea("a\nb\nc")
+	e.pushJump()
+	e.cursor.Y = 2
+	e.pushJump()
+	e.cursor.Y = 0
+	e.pushJump()
+	assert.Equal(t, []area.Point{{X: 0, Y: 2}, {X: 0, Y: 0}}, e.jumps)
+
+	// The line number command line is a jump
+	assert.NoError(t, e.RunCommandLine("3"))
+	assert.NoError(t, e.JumpOlder(1))
+	assert.Equal(t, 0, e.cursor.Y)
+}
+
+func TestChangeList(t *testing.T) {
+	e := newTestEditArea("a\nb\nc")
+	AddNormalModeCommand("gX", func(e *EditArea) { e.Delete() })
+	for _, row := range []int{0, 2, 1} {
+		e.cursor = area.Point{X: 0, Y: row}
+		e.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
+		e.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'X', tcell.ModNone))
+	}
+	assert.NoError(t, e.ChangeOlder(1))
+	assert.Equal(t, 1, e.cursor.Y)
+	assert.NoError(t, e.ChangeOlder(2))
+	assert.Equal(t, 0, e.cursor.Y)
+	assert.Error(t, e.ChangeOlder(1))
+	assert.NoError(t, e.ChangeNewer(1))
+	assert.Equal(t, 2, e.cursor.Y)
+}
+
+func TestSaveMarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuji")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	marksPath = filepath.Join(dir, "cache", "marks.json")
+	defer func() { marksPath = "" }()
+	savedFileMarks := fileMarks
+	defer func() { fileMarks, buffers = savedFileMarks, nil }()
+	fileMarks = make(map[rune]fileMark)
+
+	// Only the marks of the open buffers are saved
+	scratch := newTestEditAreaForFile("scratch.txt", "a")
+	assert.NoError(t, scratch.SetMark('a'))
+	e := newTestEditArea("a\nb\nc")
+	SetBuffers(func() []*EditArea { return []*EditArea{e} })
+	e.cursor.Y = 1
+	assert.NoError(t, e.SetMark('a'))
+	assert.NoError(t, e.SetMark('A'))
+	e.cursor.Y = 2
+	assert.NoError(t, SaveMarks())
+
+	fileMarks = make(map[rune]fileMark)
+	loadOnce = sync.Once{}
+	e = newTestEditArea("a\nb\nc")
+	p, ok := e.Mark('a')
+	assert.True(t, ok)
+	assert.Equal(t, 1, p.Y)
+	p, ok = e.Mark('A')
+	assert.True(t, ok)
+	assert.Equal(t, 1, p.Y)
+	p, _ = e.Mark('"')
+	assert.Equal(t, 2, p.Y)
+	saved, err := readMarks()
+	assert.NoError(t, err)
+	assert.NotContains(t, saved.Files, scratch.absFilename())
+}
+
+func styledString(runes []syntax.StyledRune) string {
+	var s []rune
+	for _, sr := range runes {
+		s = append(s, sr.Rune)
+	}
+	return string(s)
+}
@@ -0,0 +1,197 @@
+package editarea
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/syntax"
+	"github.com/jamesroutley/fuji/theme"
+)
+
+// signWidth is the number of columns in the sign column
+const signWidth = 2
+
+// Sign is a marker shown in the sign column next to a line, such as a
+// diagnostic, a change or a breakpoint
+type Sign struct {
+	// Text is shown in the sign column. Only its first signWidth runes are
+	// shown.
+	Text string
+	// Style is the style Text is drawn in. The zero value uses the style of
+	// the gutter.
+	Style tcell.Style
+	// Priority decides which sign is shown when a line has more than one.
+	// The sign with the highest priority is shown, or of signs with the same
+	// priority, the one whose group's name sorts first.
+	Priority int
+}
+
+// PlaceSign shows s next to row. Signs are placed in groups, so that each
+// subsystem, such as diagnostics or version control, can manage its own signs
+// without disturbing others'. A group has at most one sign on each row. Signs
+// move with their lines as lines are added and deleted above them, and are
+// removed when their lines are deleted.
+func (e *EditArea) PlaceSign(group string, row int, s Sign) {
+	e.signsVersion++
+	if e.signs == nil {
+		e.signs = make(map[string]map[int]Sign)
+	}
+	if e.signs[group] == nil {
+		e.signs[group] = make(map[int]Sign)
+	}
+	e.signs[group][row] = s
+}
+
+// RemoveSign removes group's sign from row, if it has one
+func (e *EditArea) RemoveSign(group string, row int) {
+	e.signsVersion++
+	delete(e.signs[group], row)
+	if len(e.signs[group]) == 0 {
+		delete(e.signs, group)
+	}
+}
+
+// ClearSigns removes all of group's signs
+func (e *EditArea) ClearSigns(group string) {
+	e.signsVersion++
+	delete(e.signs, group)
+}
+
+// sign returns the sign shown next to row
+func (e *EditArea) sign(row int) (s Sign, ok bool) {
+	var group string
+	for name, signs := range e.signs {
+		sign, found := signs[row]
+		if !found {
+			continue
+		}
+		if !ok || sign.Priority > s.Priority || sign.Priority == s.Priority && name < group {
+			s, group, ok = sign, name, true
+		}
+	}
+	return
+}
+
+// gutterWidth returns the number of columns the gutter takes up
+func (e *EditArea) gutterWidth() int {
+	return e.signColumnWidth() + e.numberColumnWidth()
+}
+
+// signColumnWidth returns the number of columns the sign column takes up
+func (e *EditArea) signColumnWidth() int {
+	switch e.options.SignColumn {
+	case "yes":
+		return signWidth
+	case "auto":
+		if len(e.signs) > 0 {
+			return signWidth
+		}
+	}
+	return 0
+}
+
+// numberColumnWidth returns the number of columns the line numbers take up,
+// including the space which separates them from the text
+func (e *EditArea) numberColumnWidth() int {
+	if !e.options.Number && !e.options.RelativeNumber {
+		return 0
+	}
+	width := len(strconv.Itoa(e.text.Length())) + 1
+	if width < e.options.NumberWidth {
+		width = e.options.NumberWidth
+	}
+	return width
+}
+
+// gutter returns the contents of the gutter next to row. The gutter next to
+// the continuation of a wrapped line is blank.
+func (e *EditArea) gutter(row int, continuation bool) []syntax.StyledRune {
+	style := theme.UI(theme.Gutter)
+	var runes []syntax.StyledRune
+	add := func(s string, style tcell.Style) {
+		for _, r := range s {
+			runes = append(runes, syntax.StyledRune{Rune: r, Style: style})
+		}
+	}
+
+	if width := e.signColumnWidth(); width > 0 {
+		text, signStyle := "", style
+		if s, ok := e.sign(row); ok && row < e.text.Length() && !continuation {
+			text = s.Text
+			if s.Style != tcell.StyleDefault {
+				signStyle = s.Style
+			}
+		}
+		r := []rune(text)
+		if len(r) > width {
+			r = r[:width]
+		}
+		add(string(r), signStyle)
+		add(fmt.Sprintf("%*s", width-len(r), ""), style)
+	}
+
+	if width := e.numberColumnWidth(); width > 0 {
+		number := ""
+		if row < e.text.Length() && !continuation {
+			number = e.lineNumber(row, width-1)
+		}
+		if row == e.cursor.Y {
+			style = style.Bold(true)
+		}
+		add(fmt.Sprintf("%*s ", width-1, number), style)
+	}
+	return runes
+}
+
+// lineNumber returns the number shown in the gutter for row, padded to width
+func (e *EditArea) lineNumber(row, width int) string {
+	distance := row - e.cursor.Y
+	if distance < 0 {
+		distance = -distance
+	}
+	switch {
+	case !e.options.RelativeNumber:
+		return strconv.Itoa(row + 1)
+	case distance == 0 && e.options.Number:
+		// Like vim, the cursor line's number is aligned to the left in hybrid
+		// mode, to make it stand out
+		return fmt.Sprintf("%-*d", width, row+1)
+	default:
+		return strconv.Itoa(distance)
+	}
+}
+
+// PositionAt returns the position in the text displayed at the point p of
+// the screen. ok is false if p isn't on the text.
+func (e *EditArea) PositionAt(p area.Point) (pos area.Point, ok bool) {
+	a := e.drawnArea
+	i := p.Y - a.Start.Y
+	if p.X < a.Start.X || p.X >= a.End.X || i < 0 || i >= len(e.drawnRows) {
+		return pos, false
+	}
+	s := e.drawnRows[i]
+	x := p.X - a.Start.X - e.drawnGutter - s.prefix
+	if x < 0 {
+		x = 0
+	}
+	cell := s.start + x
+	if cell >= s.end && s.end > s.start {
+		cell = s.end - 1
+	}
+	return area.Point{X: e.textColumn(s.row, cell), Y: s.row}, true
+}
+
+// Click moves the cursor to the text displayed at the point p of the screen
+func (e *EditArea) Click(p area.Point) {
+	pos, ok := e.PositionAt(p)
+	if !ok {
+		return
+	}
+	e.cursor = pos
+	e.selection.lineEnd = false
+	if max := e.cursorMaxX(); e.cursor.X > max {
+		e.cursor.X = max
+	}
+}
@@ -0,0 +1,344 @@
+package editarea
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/line"
+)
+
+// indentRule describes how smart indent indents a filetype
+type indentRule struct {
+	// brackets indents the lines inside unclosed brackets, and lines
+	// starting with a closing bracket line up with the line it was opened on
+	brackets bool
+	// colon indents the lines after a line ending in a colon, like Python
+	colon bool
+	// dedentAfter are keywords which end a block, so the line after a line
+	// starting with one is dedented, like Python's return
+	dedentAfter []string
+}
+
+// indentRules are the rules for indenting each filetype, by file extension.
+// Files with other extensions use defaultIndentRule.
+var indentRules = map[string]indentRule{
+	".py":   {brackets: true, colon: true, dedentAfter: []string{"return", "pass", "break", "continue", "raise"}},
+	".yaml": {colon: true},
+	".yml":  {colon: true},
+	".txt":  {},
+	".md":   {},
+}
+
+// defaultIndentRule indents the lines inside brackets, which suits most
+// languages
+var defaultIndentRule = indentRule{brackets: true}
+
+// indentRule returns the rule for indenting the file being edited
+func (e *EditArea) indentRule() indentRule {
+	if r, ok := indentRules[strings.ToLower(filepath.Ext(e.Filename))]; ok {
+		return r
+	}
+	return defaultIndentRule
+}
+
+// indentation returns the width in columns of the indentation at the start
+// of row, and its length in runes
+func (e *EditArea) indentation(row int) (width, length int) {
+	for _, r := range e.text.Line(row).String() {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += tabWidth - width%tabWidth
+		default:
+			return width, length
+		}
+		length++
+	}
+	return width, length
+}
+
+// indentString returns indentation width columns wide. It is made of spaces
+// if expandtab is set, and otherwise of tabs and then spaces.
+func (e *EditArea) indentString(width int) string {
+	if e.options.ExpandTab {
+		return strings.Repeat(" ", width)
+	}
+	return strings.Repeat("\t", width/tabWidth) + strings.Repeat(" ", width%tabWidth)
+}
+
+// blank returns whether row is empty or only whitespace
+func (e *EditArea) blank(row int) bool {
+	_, length := e.indentation(row)
+	return length == e.text.LineLength(row)
+}
+
+// setIndent replaces the indentation of row with indentation width columns
+// wide. The cursor stays on the same rune, or moves to the end of the
+// indentation if it was in it.
+func (e *EditArea) setIndent(row, width int) {
+	_, length := e.indentation(row)
+	indent := e.indentString(width)
+	runes := []rune(e.text.Line(row).String())
+	if string(runes[:length]) == indent {
+		return
+	}
+	e.text = e.text.DeleteLine(row).InsertLine(row, line.New(indent+string(runes[length:])))
+	e.beenEdited = true
+	e.beenSaved = false
+	if e.cursor.Y != row {
+		return
+	}
+	if e.cursor.X < length {
+		e.cursor.X = length
+	}
+	e.cursor.X += len([]rune(indent)) - length
+}
+
+// ShiftLine indents row by levels levels of shiftwidth columns, or dedents
+// it if levels is negative. The indentation is rounded to a multiple of
+// shiftwidth, like vim's <C-t> and <C-d>. The cursor stays on the same rune,
+// or moves to the end of the indentation if it was in it.
+func (e *EditArea) ShiftLine(row, levels int) {
+	sw := e.shiftWidth()
+	width, _ := e.indentation(row)
+	if levels > 0 {
+		width = (width/sw + levels) * sw
+	} else {
+		width = ((width+sw-1)/sw + levels) * sw
+	}
+	if width < 0 {
+		width = 0
+	}
+	e.setIndent(row, width)
+}
+
+// nextIndent returns the width of the indentation of a line following row,
+// and whether row opens a block, so the line is indented further. Blank
+// lines are skipped, so the indentation continues after them.
+func (e *EditArea) nextIndent(row int) (width int, opens bool) {
+	for row > 0 && e.blank(row) {
+		row--
+	}
+	width, _ = e.indentation(row)
+	if !e.options.SmartIndent || e.blank(row) {
+		return width, false
+	}
+	rule, sw := e.indentRule(), e.shiftWidth()
+	g := e.grid(row-matchLimit, row+1)
+	var code []rune
+	var open int
+	for x, sr := range g.lines[row-g.first] {
+		if !sr.IsCode() || unicode.IsSpace(sr.Rune) {
+			continue
+		}
+		code = append(code, sr.Rune)
+		if !rule.brackets {
+			continue
+		}
+		switch e.bracketKind(sr.Rune) {
+		case openBracket:
+			open++
+		case closeBracket:
+			if open > 0 {
+				open--
+				break
+			}
+			// The bracket closes one opened on an earlier row, so the
+			// indentation goes back to that row's
+			if p, ok := g.matchBracket(e.bracketPairs(), area.Point{X: x, Y: row}); ok {
+				width, _ = e.indentation(p.Y)
+			}
+		}
+	}
+	switch {
+	case open > 0, rule.colon && len(code) > 0 && code[len(code)-1] == ':':
+		return width + sw, true
+	}
+	fields := strings.FieldsFunc(e.text.Line(row).String(), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, keyword := range rule.dedentAfter {
+		if len(fields) > 0 && fields[0] == keyword && width >= sw {
+			return width - sw, false
+		}
+	}
+	return width, false
+}
+
+// bracketKind describes whether a rune is a bracket
+type bracketKind uint8
+
+const (
+	notBracket bracketKind = iota
+	openBracket
+	closeBracket
+)
+
+// bracketPairs returns the pairs of brackets which smart indent indents
+// inside, which are those in the matchpairs option
+func (e *EditArea) bracketPairs() []bracketPair {
+	// The option is checked when it is set
+	pairs, _ := parseMatchPairs(e.options.MatchPairs)
+	return pairs
+}
+
+// bracketKind returns whether r is an opening or closing bracket
+func (e *EditArea) bracketKind(r rune) bracketKind {
+	for _, pair := range e.bracketPairs() {
+		switch r {
+		case pair.open:
+			return openBracket
+		case pair.close:
+			return closeBracket
+		}
+	}
+	return notBracket
+}
+
+// closedIndent returns the width of the indentation of the line which opened
+// the bracket at the start of row, if row starts with a closing bracket, so
+// the two line up
+func (e *EditArea) closedIndent(row int) (width int, ok bool) {
+	if !e.options.SmartIndent || !e.indentRule().brackets {
+		return 0, false
+	}
+	_, length := e.indentation(row)
+	if length == e.text.LineLength(row) {
+		return 0, false
+	}
+	g := e.grid(row-matchLimit, row+1)
+	sr := g.lines[row-g.first][length]
+	if !sr.IsCode() || e.bracketKind(sr.Rune) != closeBracket {
+		return 0, false
+	}
+	p, ok := g.matchBracket(e.bracketPairs(), area.Point{X: length, Y: row})
+	if !ok {
+		return 0, false
+	}
+	width, _ = e.indentation(p.Y)
+	return width, true
+}
+
+// newLineIndent returns the width of the indentation of the new line next,
+// which follows row
+func (e *EditArea) newLineIndent(row, next int) int {
+	if width, ok := e.closedIndent(next); ok {
+		return width
+	}
+	width, _ := e.nextIndent(row)
+	return width
+}
+
+// trimAroundCursor deletes the whitespace either side of the cursor, before
+// the line is broken at it, so that autoindent doesn't leave trailing
+// whitespace on the line or add to the new line's indentation
+func (e *EditArea) trimAroundCursor() {
+	runes := []rune(e.text.Line(e.cursor.Y).String())
+	start, end := e.cursor.X, e.cursor.X
+	for start > 0 && unicode.IsSpace(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && unicode.IsSpace(runes[end]) {
+		end++
+	}
+	if start == end {
+		return
+	}
+	trimmed := string(runes[:start]) + string(runes[end:])
+	e.text = e.text.DeleteLine(e.cursor.Y).InsertLine(e.cursor.Y, line.New(trimmed))
+	e.cursor.X = start
+}
+
+// autoIndent indents the new line row, which follows the line above it, if
+// autoindent is set
+func (e *EditArea) autoIndent(row int) {
+	if !e.options.AutoIndent {
+		return
+	}
+	width := 0
+	if row > 0 {
+		width = e.newLineIndent(row-1, row)
+	}
+	e.setIndent(row, width)
+	e.autoIndented, e.autoIndentedRow = true, row
+}
+
+// clearAutoIndent removes the indentation added to a new line by autoindent
+// if nothing else has been typed on the line, so that lines aren't left
+// with only whitespace on them
+func (e *EditArea) clearAutoIndent() {
+	if !e.autoIndented {
+		return
+	}
+	e.autoIndented = false
+	row := e.autoIndentedRow
+	if row == e.cursor.Y && row < e.text.Length() && e.blank(row) {
+		e.setIndent(row, 0)
+	}
+}
+
+// alignCloser reindents the cursor's line to line up with the line which
+// opened a bracket, when the closing bracket has just been typed at the
+// start of the line
+func (e *EditArea) alignCloser() {
+	row := e.cursor.Y
+	if _, length := e.indentation(row); length != e.cursor.X-1 {
+		return
+	}
+	if width, ok := e.closedIndent(row); ok {
+		e.setIndent(row, width)
+	}
+}
+
+// OpenLine opens a new line below the cursor's, or above it if above is set,
+// and enters insert mode on it. The new line is indented if autoindent is
+// set.
+func (e *EditArea) OpenLine(above bool) {
+	row := e.cursor.Y
+	if !above {
+		row++
+	}
+	e.text = e.text.InsertLine(row, line.New(""))
+	e.beenEdited = true
+	e.beenSaved = false
+	e.cursor = area.Point{X: 0, Y: row}
+	if above && e.options.AutoIndent {
+		// The line is indented like the line below it, which is inside it if
+		// it starts with a closing bracket
+		width, _ := e.indentation(row + 1)
+		if _, ok := e.closedIndent(row + 1); ok {
+			width += e.shiftWidth()
+		}
+		e.setIndent(row, width)
+		e.autoIndented, e.autoIndentedRow = true, row
+	} else {
+		e.autoIndent(row)
+	}
+	e.Mode = ModeInsert
+	e.scrollToCursor()
+}
+
+// Reindent reindents the rows from start to end, each following the line
+// above it, using the smart indent rules. Blank lines lose their
+// indentation. In filetypes where blocks end by being dedented, like Python,
+// lines which are already dedented stay dedented.
+func (e *EditArea) Reindent(start, end int) {
+	for row := start; row <= end && row < e.text.Length(); row++ {
+		if row == 0 || e.blank(row) {
+			e.setIndent(row, 0)
+			continue
+		}
+		if width, ok := e.closedIndent(row); ok {
+			e.setIndent(row, width)
+			continue
+		}
+		width, opens := e.nextIndent(row - 1)
+		if current, _ := e.indentation(row); e.indentRule().colon && !opens && current < width {
+			width = current
+		}
+		e.setIndent(row, width)
+	}
+}
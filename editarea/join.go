@@ -0,0 +1,57 @@
+package editarea
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/line"
+)
+
+// JoinLines joins count lines into one, starting at row, like J. Joining
+// fewer than two lines joins two. If spaces is set, the whitespace at the
+// start of each joined line is replaced by a single space, which is left out
+// after trailing whitespace, before a closing bracket or next to an empty
+// line, and the comment leader is removed from a comment joined onto a
+// comment. Otherwise the lines are joined as they are, like gJ. The cursor
+// moves to where the last line was joined, which is on the space if one was
+// added. ok is false if there aren't lines after row to join.
+func (e *EditArea) JoinLines(row, count int, spaces bool) (ok bool) {
+	if count < 2 {
+		count = 2
+	}
+	if row+1 >= e.text.Length() {
+		return false
+	}
+	if row+count > e.text.Length() {
+		count = e.text.Length() - row
+	}
+	c, err := e.commentStyle()
+	comments := err == nil && c.end == ""
+	joined := e.text.Line(row).String()
+	x := 0
+	for i := 1; i < count; i++ {
+		next := e.text.Line(row + i).String()
+		x = len([]rune(joined))
+		if spaces {
+			next = strings.TrimLeftFunc(next, unicode.IsSpace)
+			first := strings.TrimLeftFunc(joined, unicode.IsSpace)
+			if comments && strings.HasPrefix(first, c.start) && strings.HasPrefix(next, c.start) {
+				next = strings.TrimLeftFunc(strings.TrimPrefix(next, c.start), unicode.IsSpace)
+			}
+			trailing := strings.TrimRightFunc(joined, unicode.IsSpace) != joined
+			if joined != "" && next != "" && !trailing && !strings.HasPrefix(next, ")") {
+				joined += " "
+			}
+		}
+		joined += next
+	}
+	for i := 0; i < count; i++ {
+		e.text = e.text.DeleteLine(row)
+	}
+	e.text = e.text.InsertLine(row, line.New(joined))
+	e.beenEdited = true
+	e.beenSaved = false
+	e.SetCursor(area.Point{X: x, Y: row})
+	return true
+}
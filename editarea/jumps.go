@@ -0,0 +1,103 @@
+package editarea
+
+import (
+	"fmt"
+
+	"github.com/jamesroutley/fuji/area"
+)
+
+// maxJumps is the number of positions kept in the jump list and the change
+// list
+const maxJumps = 100
+
+// pushJump adds the cursor to the end of the jump list, before a jump moves
+// it, and sets the ` mark. Older jumps on the cursor's row are removed, so
+// each row is in the list once.
+func (e *EditArea) pushJump() {
+	e.adjustMarks()
+	p := area.Point{X: e.cursorColumn(), Y: e.cursor.Y}
+	e.marks['`'] = p
+	jumps := e.jumps[:0]
+	for _, j := range e.jumps {
+		if j.Y != p.Y {
+			jumps = append(jumps, j)
+		}
+	}
+	jumps = append(jumps, p)
+	if len(jumps) > maxJumps {
+		jumps = jumps[len(jumps)-maxJumps:]
+	}
+	e.jumps, e.jumpIndex = jumps, len(jumps)
+}
+
+// JumpOlder moves the cursor count positions back through the jump list
+func (e *EditArea) JumpOlder(count int) error {
+	e.adjustMarks()
+	if e.jumpIndex == len(e.jumps) {
+		// Record where the cursor is, so JumpNewer can come back to it
+		e.pushJump()
+		e.jumpIndex--
+	}
+	i := e.jumpIndex - count
+	if i < 0 {
+		return fmt.Errorf("at the start of the jump list")
+	}
+	e.jumpIndex = i
+	e.SetCursor(e.jumps[i])
+	return nil
+}
+
+// JumpNewer moves the cursor count positions forward through the jump list,
+// after JumpOlder has moved back through it
+func (e *EditArea) JumpNewer(count int) error {
+	e.adjustMarks()
+	i := e.jumpIndex + count
+	if i >= len(e.jumps) {
+		return fmt.Errorf("at the end of the jump list")
+	}
+	e.jumpIndex = i
+	e.SetCursor(e.jumps[i])
+	return nil
+}
+
+// recordChange adds the cursor to the end of the change list, after the text
+// has been changed. Like vim, a change on the same row as the last one
+// replaces it.
+func (e *EditArea) recordChange() {
+	e.adjustMarks()
+	p := area.Point{X: e.cursorColumn(), Y: e.cursor.Y}
+	if n := len(e.changes); n > 0 && e.changes[n-1].Y == p.Y {
+		e.changes[n-1] = p
+	} else {
+		e.changes = append(e.changes, p)
+	}
+	if len(e.changes) > maxJumps {
+		e.changes = e.changes[len(e.changes)-maxJumps:]
+	}
+	e.changeIndex = len(e.changes)
+}
+
+// ChangeOlder moves the cursor count positions back through the change list
+func (e *EditArea) ChangeOlder(count int) error {
+	e.adjustMarks()
+	i := e.changeIndex - count
+	if len(e.changes) == 0 || i < 0 {
+		return fmt.Errorf("at the start of the change list")
+	}
+	e.changeIndex = i
+	e.SetCursor(e.changes[i])
+	return nil
+}
+
+// ChangeNewer moves the cursor count positions forward through the change
+// list
+func (e *EditArea) ChangeNewer(count int) error {
+	e.adjustMarks()
+	i := e.changeIndex + count
+	if i >= len(e.changes) {
+		return fmt.Errorf("at the end of the change list")
+	}
+	e.changeIndex = i
+	e.SetCursor(e.changes[i])
+	return nil
+}
@@ -0,0 +1,266 @@
+package editarea
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell"
+)
+
+// Macros are stored in registers as text, so they can be edited and yanked
+// back. Keys which aren't runes are written between angle brackets, like
+// vim: "<Esc>", "<CR>" or "<C-v>", and "<" itself is written "<lt>".
+
+// keyNames are the names of the special keys which can be used in macros
+var keyNames = map[tcell.Key]string{
+	tcell.KeyESC:        "Esc",
+	tcell.KeyEnter:      "CR",
+	tcell.KeyTab:        "Tab",
+	tcell.KeyBackspace2: "BS",
+	tcell.KeyDelete:     "Del",
+	tcell.KeyUp:         "Up",
+	tcell.KeyDown:       "Down",
+	tcell.KeyLeft:       "Left",
+	tcell.KeyRight:      "Right",
+	tcell.KeyHome:       "Home",
+	tcell.KeyEnd:        "End",
+	tcell.KeyPgUp:       "PageUp",
+	tcell.KeyPgDn:       "PageDown",
+}
+
+// maxReplayKeys is the most keys a macro can replay, which stops recursive
+// macros which never fail
+const maxReplayKeys = 1000000
+
+// modifierNames are the prefixes of the names of keys pressed with modifier
+// keys, like "<C-Left>"
+var modifierNames = []struct {
+	mod    tcell.ModMask
+	prefix string
+}{{tcell.ModCtrl, "C-"}, {tcell.ModAlt, "M-"}, {tcell.ModShift, "S-"}}
+
+// KeyText returns the text of the key pressed in ev, as it is written in a
+// macro, or "" if it can't be written
+func KeyText(ev *tcell.EventKey) string {
+	key := ev.Key()
+	if key == tcell.KeyRune {
+		if ev.Rune() == '<' {
+			return "<lt>"
+		}
+		return string(ev.Rune())
+	}
+	if name, ok := keyNames[key]; ok {
+		prefix := ""
+		for _, m := range modifierNames {
+			if ev.Modifiers()&m.mod != 0 {
+				prefix += m.prefix
+			}
+		}
+		return "<" + prefix + name + ">"
+	}
+	if key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ {
+		return fmt.Sprintf("<C-%c>", 'a'+rune(key-tcell.KeyCtrlA))
+	}
+	return ""
+}
+
+// ParseKeys returns the keys written in s, as they are written in macros.
+// Text between angle brackets which isn't the name of a key is read as it
+// is, and newlines are read as <CR>.
+func ParseKeys(s string) []*tcell.EventKey {
+	var keys []*tcell.EventKey
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\n' {
+			keys = append(keys, tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+			continue
+		}
+		if r == '<' {
+			if ev, n := parseBracketedKey(runes[i+1:]); ev != nil {
+				keys = append(keys, ev)
+				i += n
+				continue
+			}
+		}
+		keys = append(keys, tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	return keys
+}
+
+// parseBracketedKey parses the name of a key, which follows a "<" in the
+// text of a macro, up to the closing ">". It returns the key and the number
+// of runes read, or nil if the text isn't the name of a key.
+func parseBracketedKey(runes []rune) (*tcell.EventKey, int) {
+	for i, r := range runes {
+		if r == '>' {
+			return parseKeyName(string(runes[:i])), i + 1
+		}
+	}
+	return nil, 0
+}
+
+// parseKeyName returns the key called name, or nil if there isn't one
+func parseKeyName(name string) *tcell.EventKey {
+	if strings.EqualFold(name, "lt") {
+		return tcell.NewEventKey(tcell.KeyRune, '<', tcell.ModNone)
+	}
+	// Named keys may be pressed with modifiers, like "C-Left"
+	mod, rest := tcell.ModNone, name
+	for len(rest) > 2 && rest[1] == '-' {
+		found := false
+		for _, m := range modifierNames {
+			if strings.EqualFold(rest[:2], m.prefix) {
+				mod, rest, found = mod|m.mod, rest[2:], true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	for key, n := range keyNames {
+		if strings.EqualFold(rest, n) {
+			return tcell.NewEventKey(key, 0, mod)
+		}
+	}
+	runes := []rune(strings.ToLower(name))
+	if len(runes) == 3 && runes[0] == 'c' && runes[1] == '-' && runes[2] >= 'a' && runes[2] <= 'z' {
+		key := tcell.KeyCtrlA + tcell.Key(runes[2]-'a')
+		return tcell.NewEventKey(key, rune(key), tcell.ModCtrl)
+	}
+	return nil
+}
+
+// ReadChar waits for the next key to be typed, then runs then, with the
+// character typed returned by Char. It is used by normal mode commands which
+// take a character, like q. The count and register typed before the running
+// command are kept. Commands can read more than one character by calling
+// ReadChar again from then.
+func (e *EditArea) ReadChar(then NormalModeCommand) {
+	e.pending = e.running
+	if c := e.running.char; c != 0 {
+		// The character read before becomes part of the command's keys, so
+		// that the command is repeated with every character it read
+		e.pending.keys += KeyText(tcell.NewEventKey(tcell.KeyRune, c, tcell.ModNone))
+		e.pending.char = 0
+	}
+	e.charCommand = then
+}
+
+// StartRecording starts recording the keys typed into the register called
+// name. Recording into an upper case register appends to the lower case one.
+func (e *EditArea) StartRecording(name rune) error {
+	if !strings.ContainsRune(`"0123456789abcdefghijklmnopqrstuvwxyz`, unicode.ToLower(name)) {
+		return fmt.Errorf("invalid register name: %c", name)
+	}
+	e.recording = name
+	e.recorded = nil
+	return nil
+}
+
+// StopRecording stops recording keys, and writes them into the register
+// being recorded into. The last key typed, which stopped the recording, is
+// left out.
+func (e *EditArea) StopRecording() {
+	if e.recording == 0 {
+		return
+	}
+	keys := e.recorded
+	if len(keys) > 0 {
+		keys = keys[:len(keys)-1]
+	}
+	if err := e.SetRegister(e.recording, Register{Text: strings.Join(keys, "")}); err != nil {
+		e.SetMessage("%s", err)
+	}
+	e.recording, e.recorded = 0, nil
+}
+
+// Recording returns the name of the register keys are being recorded into,
+// or 0 if they aren't being recorded
+func (e *EditArea) Recording() rune {
+	return e.recording
+}
+
+// ReplayMacro handles the keys in the register called name count times, as
+// if they were typed. The register @ is the last register replayed, and : is
+// the last command line. The macro stops if a motion or text object fails,
+// so recursive macros stop at the end of the text.
+func (e *EditArea) ReplayMacro(name rune, count int) error {
+	if name == '@' {
+		if lastMacro == 0 {
+			return fmt.Errorf("no previous macro")
+		}
+		name = lastMacro
+	}
+	lastMacro = name
+	if name == ':' {
+		for i := 0; i < count; i++ {
+			if err := e.RunCommandLine(lastCommandLine); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	r, _ := e.Register(name)
+	text := r.Text
+	if r.Linewise {
+		text = strings.TrimSuffix(text, "\n")
+	}
+	keys := ParseKeys(text)
+	var all []*tcell.EventKey
+	for i := 0; i < count; i++ {
+		all = append(all, keys...)
+	}
+	// Keys from macros replayed by macros are handled before the rest of the
+	// outer macro, like vim, rather than recursively
+	e.typeahead = append(all, e.typeahead...)
+	if e.replaying {
+		return nil
+	}
+	return e.replay()
+}
+
+// RunKeys handles the keys written in s as if they were typed in normal
+// mode, then leaves whichever mode they ended in, like vim's :normal
+func (e *EditArea) RunKeys(s string) error {
+	saved := e.typeahead
+	defer func() { e.typeahead = saved }()
+	e.typeahead = ParseKeys(s)
+	e.failed = false
+	err := e.replay()
+	switch e.Mode {
+	case ModeInsert, ModeReplace:
+		// Leave insert mode as part of the keys, so they are undone together
+		replaying := e.replaying
+		e.replaying = true
+		e.HandleEvent(tcell.NewEventKey(tcell.KeyESC, 0, tcell.ModNone))
+		e.replaying = replaying
+	case ModeCommand, ModeVisual:
+		e.Mode = ModeNormal
+	}
+	e.pending, e.charCommand = command{}, nil
+	return err
+}
+
+// replay handles the keys waiting to be replayed
+func (e *EditArea) replay() error {
+	replaying := e.replaying
+	e.replaying = true
+	defer func() { e.replaying = replaying }()
+	for n := 0; len(e.typeahead) > 0; n++ {
+		if e.failed {
+			e.typeahead = nil
+			return nil
+		}
+		if n == maxReplayKeys {
+			e.typeahead = nil
+			return fmt.Errorf("macro stopped after %d keys", maxReplayKeys)
+		}
+		ev := e.typeahead[0]
+		e.typeahead = e.typeahead[1:]
+		e.HandleEvent(ev)
+	}
+	return nil
+}
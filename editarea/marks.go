@@ -0,0 +1,333 @@
+package editarea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/area"
+)
+
+// Marks are positions in the text which can be jumped back to. The marks a
+// to z are local to a file, and A to Z are global, and jump to the file they
+// were set in. The special marks are set by the editor:
+//
+//	.	where the last change was made
+//	^	where insert mode was last left
+//	< >	the start and end of the last selection
+//	` '	where the cursor was before the last jump
+//	"	where the cursor was when the file was last closed
+//
+// Marks follow the lines they are on as lines are inserted and deleted, and
+// are kept between sessions.
+
+// fileMark is a global mark, which is in a file
+type fileMark struct {
+	Filename string
+	Pos      area.Point
+}
+
+// fileMarks are the global marks, A to Z
+var fileMarks = make(map[rune]fileMark)
+
+// buffers returns the EditAreas of the files which are open, whose marks are
+// saved by SaveMarks. It is set with SetBuffers.
+var buffers func() []*EditArea
+
+// SetBuffers sets the function which returns the EditAreas of the files
+// which are open
+func SetBuffers(f func() []*EditArea) {
+	buffers = f
+}
+
+// open opens filename for editing, and returns its EditArea. It is set with
+// SetOpen.
+var open func(filename string) (*EditArea, error)
+
+// SetOpen sets the function used to open the file a global mark is in. It
+// should show the file's EditArea in place of the current one.
+func SetOpen(f func(filename string) (*EditArea, error)) {
+	open = f
+}
+
+// absFilename returns the absolute path of the file being edited, which
+// identifies it in the global and saved marks
+func (e *EditArea) absFilename() string {
+	abs, err := filepath.Abs(e.Filename)
+	if err != nil {
+		return e.Filename
+	}
+	return abs
+}
+
+// SetMark sets the mark called name at the cursor
+func (e *EditArea) SetMark(name rune) error {
+	e.adjustMarks()
+	p := area.Point{X: e.cursorColumn(), Y: e.cursor.Y}
+	switch {
+	case name >= 'a' && name <= 'z', name == '<', name == '>', name == '"':
+		e.marks[name] = p
+	case name >= 'A' && name <= 'Z':
+		fileMarks[name] = fileMark{Filename: e.absFilename(), Pos: p}
+	case name == '`', name == '\'':
+		e.marks['`'] = p
+	default:
+		return fmt.Errorf("invalid mark name: %c", name)
+	}
+	return nil
+}
+
+// Mark returns the position of the mark called name in this file. ok is
+// false if the mark isn't set, or is a global mark in another file.
+func (e *EditArea) Mark(name rune) (p area.Point, ok bool) {
+	e.adjustMarks()
+	switch {
+	case name >= 'A' && name <= 'Z':
+		m, ok := fileMarks[name]
+		if !ok || m.Filename != e.absFilename() {
+			return p, false
+		}
+		return m.Pos, true
+	case name == '\'':
+		name = '`'
+	case name == '.':
+		if len(e.changes) == 0 {
+			return p, false
+		}
+		return e.changes[len(e.changes)-1], true
+	}
+	p, ok = e.marks[name]
+	return p, ok
+}
+
+// setSelectionMarks sets the < and > marks to the start and end of the
+// selection between anchor and cursor, which has just been left
+func (e *EditArea) setSelectionMarks(anchor, cursor area.Point) {
+	if cursor.Y < anchor.Y || (cursor.Y == anchor.Y && cursor.X < anchor.X) {
+		anchor, cursor = cursor, anchor
+	}
+	e.marks['<'], e.marks['>'] = anchor, cursor
+}
+
+// JumpToMark returns the position of the mark called name, for motions which
+// jump to marks. If the mark is a global mark in another file, the file is
+// opened, its cursor is moved to the mark, and ok is false, since the cursor
+// in this file doesn't move.
+func (e *EditArea) JumpToMark(name rune) (p area.Point, ok bool, err error) {
+	if p, ok := e.Mark(name); ok {
+		return p, true, nil
+	}
+	m, global := fileMarks[name]
+	if !global || open == nil {
+		return p, false, fmt.Errorf("mark not set: %c", name)
+	}
+	e.pushJump()
+	other, err := open(m.Filename)
+	if err != nil {
+		return p, false, err
+	}
+	other.pushJump()
+	other.SetCursor(m.Pos)
+	return p, false, nil
+}
+
+// adjustMarks moves the marks, jumps and changes to follow the lines they are
+// on, as lines have been inserted and deleted since they were last adjusted.
+// Lines which haven't changed are found by comparing the lines of the text,
+// which are shared between versions of the text. Local marks on deleted lines
+// are deleted, like vim. Signs are moved in the same way.
+func (e *EditArea) adjustMarks() {
+	old, new := e.markedText, e.text
+	e.markedText = new
+	if old == nil || old == new {
+		return
+	}
+	oldLen, newLen := old.Length(), new.Length()
+	prefix := 0
+	for prefix < oldLen && prefix < newLen && old.Line(prefix) == new.Line(prefix) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < oldLen-prefix && suffix < newLen-prefix && old.Line(oldLen-1-suffix) == new.Line(newLen-1-suffix) {
+		suffix++
+	}
+	// The old rows from prefix up to oldLen-suffix were replaced by the new
+	// rows from prefix up to newLen-suffix
+	changed := newLen - suffix - prefix
+	move := func(p area.Point) (area.Point, bool) {
+		switch {
+		case p.Y < prefix:
+			return p, true
+		case p.Y >= oldLen-suffix:
+			p.Y += newLen - oldLen
+			return p, true
+		case p.Y-prefix < changed:
+			return p, true
+		}
+		row := prefix + changed
+		if row >= newLen {
+			row = newLen - 1
+		}
+		return area.Point{X: 0, Y: row}, false
+	}
+
+	for name, p := range e.marks {
+		if p, ok := move(p); ok {
+			e.marks[name] = p
+		} else {
+			delete(e.marks, name)
+		}
+	}
+	filename := e.absFilename()
+	for name, m := range fileMarks {
+		if m.Filename != filename {
+			continue
+		}
+		if p, ok := move(m.Pos); ok {
+			fileMarks[name] = fileMark{Filename: filename, Pos: p}
+		} else {
+			delete(fileMarks, name)
+		}
+	}
+	for _, list := range [][]area.Point{e.jumps, e.changes} {
+		for i, p := range list {
+			list[i], _ = move(p)
+		}
+	}
+	if len(e.signs) > 0 {
+		e.signsVersion++
+	}
+	for group, signs := range e.signs {
+		moved := make(map[int]Sign, len(signs))
+		for row, s := range signs {
+			if p, ok := move(area.Point{Y: row}); ok {
+				moved[p.Y] = s
+			}
+		}
+		e.signs[group] = moved
+		if len(moved) == 0 {
+			delete(e.signs, group)
+		}
+	}
+}
+
+// savedMarks is the file the marks are kept in between sessions
+type savedMarks struct {
+	// Files holds the local marks of each file, by absolute path
+	Files  map[string]map[string]area.Point
+	Global map[string]fileMark
+}
+
+var (
+	// marksPath is the path of the file the marks are kept in
+	marksPath = defaultMarksPath()
+	loadOnce  sync.Once
+	// loaded holds the marks loaded when the editor started
+	loaded savedMarks
+)
+
+// SetMarksPath sets the path of the file the marks are kept in. An empty path
+// keeps the marks for this session only.
+func SetMarksPath(path string) {
+	marksPath = path
+}
+
+// defaultMarksPath returns the path of the file the marks are kept in
+func defaultMarksPath() string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, "fuji", "marks.json")
+}
+
+// readMarks reads the saved marks. A missing file has no marks.
+func readMarks() (savedMarks, error) {
+	s := savedMarks{
+		Files:  make(map[string]map[string]area.Point),
+		Global: make(map[string]fileMark),
+	}
+	if marksPath == "" {
+		return s, nil
+	}
+	b, err := ioutil.ReadFile(marksPath)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return s, err
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]map[string]area.Point)
+	}
+	if s.Global == nil {
+		s.Global = make(map[string]fileMark)
+	}
+	return s, nil
+}
+
+// restoreMarks restores the marks saved for the file being edited, and the
+// global marks, when the first file is opened
+func (e *EditArea) restoreMarks() {
+	loadOnce.Do(func() {
+		loaded, _ = readMarks()
+		for name, m := range loaded.Global {
+			if r := []rune(name); len(r) == 1 {
+				fileMarks[r[0]] = m
+			}
+		}
+	})
+	for name, p := range loaded.Files[e.absFilename()] {
+		if r := []rune(name); len(r) == 1 && p.Y < e.text.Length() {
+			e.marks[r[0]] = p
+		}
+	}
+	e.markedText = e.text
+}
+
+// SaveMarks saves the marks of every file which is open, the global
+// marks, and where the cursor is in each file, which is the " mark, so they
+// can be restored the next time the files are opened
+func SaveMarks() error {
+	if marksPath == "" {
+		return nil
+	}
+	// Other sessions may have saved marks since they were loaded
+	s, err := readMarks()
+	if err != nil {
+		return err
+	}
+	var open []*EditArea
+	if buffers != nil {
+		open = buffers()
+	}
+	for _, e := range open {
+		e.adjustMarks()
+		e.marks['"'] = area.Point{X: e.cursorColumn(), Y: e.cursor.Y}
+		local := make(map[string]area.Point)
+		for name, p := range e.marks {
+			if unicode.IsLower(name) || name == '"' {
+				local[string(name)] = p
+			}
+		}
+		s.Files[e.absFilename()] = local
+	}
+	for name, m := range fileMarks {
+		s.Global[string(name)] = m
+	}
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(marksPath), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(marksPath, b, 0644)
+}
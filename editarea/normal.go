@@ -0,0 +1,290 @@
+package editarea
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+)
+
+// MotionKind describes which text a motion covers when it is used after an
+// operator
+type MotionKind uint8
+
+const (
+	// Exclusive motions cover the text up to, but not including, where they
+	// move the cursor to, like w
+	Exclusive MotionKind = iota
+	// Inclusive motions cover the text up to and including where they move
+	// the cursor to, like e
+	Inclusive
+	// Linewise motions cover whole lines, like j
+	Linewise
+)
+
+// Motion is a normal mode command which moves the cursor. Motions can also
+// be used after operators, to choose the text they act on.
+type Motion struct {
+	// Move returns where the cursor moves to, without moving it. ok is false
+	// if the motion fails, for example because there's no next word.
+	Move func(e *EditArea) (p area.Point, ok bool)
+	Kind MotionKind
+	// KindOf, if set, decides the motion's kind when it is run, for motions
+	// like ; whose kind depends on the motion they repeat
+	KindOf func(e *EditArea) MotionKind
+	// TakesChar is true for motions which read a character typed after their
+	// keys, like f. The character is returned by Char.
+	TakesChar bool
+	// Jump is true for motions which can move the cursor far, like G. They
+	// add where the cursor was to the jump list.
+	Jump bool
+	// LineEnd is true for motions which move to the end of a line, like $.
+	// A characterwise selection made with them includes the line break.
+	LineEnd bool
+}
+
+var motions = make(map[string]Motion)
+
+// AddMotion adds a new motion, which is run by typing keys
+func AddMotion(keys string, m Motion) {
+	motions[keys] = m
+}
+
+// command is the state of a normal mode command, which is made up of an
+// optional count, the command's keys, and a character if it takes one. The
+// keys of a motion or text object typed after an operator follow the
+// operator's, with their own optional count.
+type command struct {
+	count int
+	keys  string
+	// motion is set while waiting for the character a motion takes
+	motion *Motion
+	char   rune
+	// operator is the keys of the operator typed before the motion or text
+	// object, and opCount the count typed before the operator
+	operator string
+	opCount  int
+	// register is the name of the register typed after ", or 0 if there
+	// wasn't one. readingRegister is set while waiting for the name.
+	register        rune
+	readingRegister bool
+}
+
+// Count returns the count typed before the running command, or 1 if there
+// wasn't one. The counts typed before an operator and the motion after it
+// are multiplied together, so 2d3w deletes 6 words.
+func (e *EditArea) Count() int {
+	count := 1
+	if e.running.count > 0 {
+		count = e.running.count
+	}
+	if e.running.opCount > 0 {
+		count *= e.running.opCount
+	}
+	return count
+}
+
+// HasCount returns whether a count was typed before the running command
+func (e *EditArea) HasCount() bool {
+	return e.running.count > 0 || e.running.opCount > 0
+}
+
+// Char returns the character typed after the keys of the running command,
+// for commands which take one
+func (e *EditArea) Char() rune {
+	return e.running.char
+}
+
+// keyName returns the name of the key pressed in ev as it is written in the
+// keys of normal mode commands: the rune typed, or "<C-x>" for control keys.
+// ok is false for other keys.
+func keyName(ev *tcell.EventKey) (name string, ok bool) {
+	switch key := ev.Key(); {
+	case key == tcell.KeyRune:
+		return string(ev.Rune()), true
+	case key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ:
+		return fmt.Sprintf("<C-%c>", 'a'+rune(key-tcell.KeyCtrlA)), true
+	}
+	return "", false
+}
+
+// handleNormalModeEvent handles a key typed in normal or visual mode
+func (e *EditArea) handleNormalModeEvent(ev *tcell.EventKey) {
+	if e.pending == (command{}) && e.charCommand == nil {
+		e.startChange()
+	}
+	defer e.endChange()
+	if c := e.charCommand; c != nil {
+		e.charCommand = nil
+		char := ev.Rune()
+		switch ev.Key() {
+		case tcell.KeyRune:
+		case tcell.KeyEnter:
+			// Like vim, <CR> is read as a carriage return, so that r<CR>
+			// breaks the line
+			char = '\r'
+		default:
+			e.pending = command{}
+			return
+		}
+		e.running, e.pending = e.pending, command{}
+		e.running.char = char
+		c(e)
+		return
+	}
+	name, ok := keyName(ev)
+	if !ok {
+		if ev.Key() == tcell.KeyESC && e.pending == (command{}) {
+			e.ExitVisualMode()
+		}
+		e.pending = command{}
+		return
+	}
+	r := ev.Rune()
+	p := &e.pending
+	if p.motion != nil {
+		p.char = r
+		e.runMotion(*p.motion)
+		return
+	}
+	if p.readingRegister {
+		p.register, p.readingRegister = r, false
+		return
+	}
+	if ev.Key() == tcell.KeyRune && p.keys == "" {
+		switch {
+		case r >= '0' && r <= '9' && (r != '0' || p.count > 0):
+			p.count = p.count*10 + int(r-'0')
+			return
+		case r == '"' && p.operator == "":
+			p.readingRegister = true
+			return
+		}
+	}
+
+	p.keys += name
+	if p.operator != "" && isLinewiseOperator(p.operator, p.keys) {
+		e.running, e.pending = e.pending, command{}
+		e.operateOnLines()
+		return
+	}
+	if m, ok := motions[p.keys]; ok {
+		if m.TakesChar {
+			p.motion = &m
+			return
+		}
+		e.runMotion(m)
+		return
+	}
+	if obj := textObjects[p.keys]; obj != nil && (p.operator != "" || e.Mode == ModeVisual) {
+		e.runTextObject(obj)
+		return
+	}
+	if p.operator != "" && p.count == 0 {
+		// Operators and commands whose keys start with an operator's, like ys
+		// and ds, are typed as if they followed the operator
+		keys := p.operator + p.keys
+		if operators[keys] != nil {
+			p.operator, p.keys = keys, ""
+			return
+		}
+		if c := e.modeCommands()[keys]; c != nil {
+			p.keys, p.count, p.operator, p.opCount = keys, p.opCount, "", 0
+			e.running, e.pending = e.pending, command{}
+			c(e)
+			return
+		}
+	}
+	if p.operator == "" {
+		if operators[p.keys] != nil {
+			if e.Mode == ModeVisual {
+				e.operateOnSelection(p.keys)
+				return
+			}
+			p.operator, p.opCount, p.count, p.keys = p.keys, p.count, 0, ""
+			return
+		}
+		if c := e.modeCommands()[p.keys]; c != nil {
+			e.running, e.pending = e.pending, command{}
+			c(e)
+			return
+		}
+	}
+	if !e.isCommandPrefix(p.keys) {
+		e.pending = command{}
+	}
+	// Otherwise wait for the rest of the command
+}
+
+// modeCommands returns the commands which can be run in the current mode
+func (e *EditArea) modeCommands() map[string]NormalModeCommand {
+	if e.Mode == ModeVisual {
+		return visualModeCommands
+	}
+	return normalModeCommands
+}
+
+// runMotion runs the pending motion m, moving the cursor or running the
+// pending operator on the text it covers
+func (e *EditArea) runMotion(m Motion) {
+	e.running, e.pending = e.pending, command{}
+	target, ok := m.Move(e)
+	if !ok {
+		e.failed = true
+		return
+	}
+	if e.running.operator == "" {
+		if m.Jump {
+			e.pushJump()
+		}
+		// Like vim, moving up and down keeps the selection at the ends of
+		// lines
+		e.selection.lineEnd = m.LineEnd || e.selection.lineEnd && m.Kind == Linewise
+		e.cursor = target
+		e.scrollToCursor()
+		return
+	}
+	kind := m.Kind
+	if m.KindOf != nil {
+		kind = m.KindOf(e)
+	}
+	e.operate(e.motionRegion(target, kind))
+}
+
+// isCommandPrefix returns whether keys are the start of a longer command,
+// motion or text object which can be typed now, such as "g" in "gj"
+func (e *EditArea) isCommandPrefix(keys string) bool {
+	isPrefix := func(name string) bool {
+		return len(name) > len(keys) && strings.HasPrefix(name, keys)
+	}
+	for name := range motions {
+		if isPrefix(name) {
+			return true
+		}
+	}
+	if e.pending.operator != "" || e.Mode == ModeVisual {
+		for name := range textObjects {
+			if isPrefix(name) {
+				return true
+			}
+		}
+	}
+	if e.pending.operator != "" {
+		if e.pending.count > 0 {
+			return false
+		}
+		keys = e.pending.operator + keys
+	}
+	for name := range operators {
+		if isPrefix(name) {
+			return true
+		}
+	}
+	for name := range e.modeCommands() {
+		if isPrefix(name) {
+			return true
+		}
+	}
+	return false
+}
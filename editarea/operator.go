@@ -0,0 +1,106 @@
+package editarea
+
+import (
+	"unicode/utf8"
+
+	"github.com/jamesroutley/fuji/area"
+)
+
+// Operator is a normal mode command which acts on a region of the text, like
+// d. The region is chosen by a motion or text object typed after the
+// operator, by typing the operator's last key again for whole lines, like dd,
+// or by the selection in visual mode.
+type Operator func(e *EditArea, r Region)
+
+// TextObject returns a region of the text around the cursor, like the word
+// the cursor is on. ok is false if there is no such region. Text objects are
+// typed after operators, or in visual mode to select the region.
+type TextObject func(e *EditArea) (r Region, ok bool)
+
+var operators = make(map[string]Operator)
+var textObjects = make(map[string]TextObject)
+
+// AddOperator adds a new operator, which is run by typing keys
+func AddOperator(keys string, op Operator) {
+	operators[keys] = op
+}
+
+// AddTextObject adds a new text object, which is selected by typing keys,
+// such as "iw"
+func AddTextObject(keys string, obj TextObject) {
+	textObjects[keys] = obj
+}
+
+// Operator returns the keys of the operator typed before the running motion
+// or text object, or "" if there wasn't one. Some motions, like w, cover
+// different text after an operator.
+func (e *EditArea) Operator() string {
+	return e.running.operator
+}
+
+// isLinewiseOperator returns whether keys, typed after operator, make it act
+// on whole lines
+func isLinewiseOperator(operator, keys string) bool {
+	last, _ := utf8.DecodeLastRuneInString(operator)
+	return keys == operator || keys == string(last)
+}
+
+// operate runs the running operator on r
+func (e *EditArea) operate(r Region) {
+	op := operators[e.running.operator]
+	if op == nil {
+		return
+	}
+	op(e, e.clampRegion(r))
+}
+
+// operateOnLines runs the running operator on count lines, starting at the
+// cursor
+func (e *EditArea) operateOnLines() {
+	end := e.cursor.Y + e.Count() - 1
+	e.operate(Region{
+		Start:    area.Point{X: 0, Y: e.cursor.Y},
+		End:      area.Point{X: 0, Y: end},
+		Linewise: true,
+	})
+}
+
+// motionRegion returns the region covered by moving the cursor to target
+// with a motion of the given kind
+func (e *EditArea) motionRegion(target area.Point, kind MotionKind) Region {
+	start, end := area.Point{X: e.cursorColumn(), Y: e.cursor.Y}, target
+	if end.Y < start.Y || (end.Y == start.Y && end.X < start.X) {
+		start, end = end, start
+	}
+	switch kind {
+	case Linewise:
+		return Region{Start: start, End: end, Linewise: true}
+	case Inclusive:
+		if end.X < e.text.LineLength(end.Y) {
+			end.X++
+		}
+	case Exclusive:
+		// Like vim, an exclusive motion which ends at the start of a row
+		// doesn't cover the line break before it
+		if end.X == 0 && end.Y > start.Y {
+			end = area.Point{X: e.text.LineLength(end.Y - 1), Y: end.Y - 1}
+		}
+	}
+	return Region{Start: start, End: end}
+}
+
+// runTextObject runs the pending text object obj, selecting its region in
+// visual mode or running the pending operator on it
+func (e *EditArea) runTextObject(obj TextObject) {
+	e.running, e.pending = e.pending, command{}
+	r, ok := obj(e)
+	if !ok {
+		e.failed = true
+		return
+	}
+	if e.Mode == ModeVisual {
+		e.selectRegion(e.clampRegion(r))
+		return
+	}
+	e.operate(r)
+}
@@ -0,0 +1,304 @@
+package editarea
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jamesroutley/fuji/clipboard"
+)
+
+// Options are the settings of an EditArea which can be changed with :set
+type Options struct {
+	// Number shows the number of each line in the gutter
+	Number bool
+	// RelativeNumber shows the distance of each line from the cursor in the
+	// gutter. If Number is also set, the cursor line shows its own number.
+	RelativeNumber bool
+	// NumberWidth is the minimum number of columns used for line numbers
+	NumberWidth int
+	// SignColumn controls when the sign column is shown: "yes", "no", or
+	// "auto" to show it only when there are signs to show
+	SignColumn string
+	// Wrap wraps lines which are too long to fit on the display onto the
+	// following rows. Lines which aren't wrapped are scrolled horizontally.
+	Wrap bool
+	// LineBreak wraps lines at the end of a word, rather than at the last
+	// column which fits
+	LineBreak bool
+	// ShowBreak is shown at the start of the continuation of a wrapped line
+	ShowBreak string
+	// BreakIndent indents the continuation of a wrapped line to the same
+	// level as its start
+	BreakIndent bool
+	// SideScrollOff is the number of columns kept visible to the left and
+	// right of the cursor when lines aren't wrapped
+	SideScrollOff int
+	// ShiftWidth is the number of columns in each level of indentation. If
+	// it is 0, the width of a tab is used.
+	ShiftWidth int
+	// AutoIndent indents a new line like the line before it
+	AutoIndent bool
+	// SmartIndent, with AutoIndent, indents new lines further inside
+	// brackets and blocks, and lines up closing brackets with the line they
+	// were opened on, following the rules for the filetype
+	SmartIndent bool
+	// ExpandTab indents with spaces rather than tabs
+	ExpandTab bool
+	// AutoPairs is a comma separated list of pairs of runes, such as
+	// "(:),\":\"", whose closing rune is inserted after the cursor when the
+	// opening rune is typed in insert mode. Each filetype has its own pairs.
+	AutoPairs string
+	// TextWidth is the width which gq and :reflow wrap lines to, and which
+	// lines typed in insert mode are wrapped at. If it is 0, lines aren't
+	// wrapped as they are typed, and are reflowed to 79 columns.
+	TextWidth int
+	// CursorLine highlights the line the cursor is on
+	CursorLine bool
+	// CursorColumn highlights the column the cursor is in
+	CursorColumn bool
+	// ColorColumn is a comma separated list of columns to highlight, such as
+	// "80,120"
+	ColorColumn string
+	// List makes whitespace visible, using the glyphs in ListChars
+	List bool
+	// ListChars sets the glyphs used to show whitespace when List is set. It
+	// is a comma separated list of settings: "tab:xy" shows a tab as x
+	// followed by as many y as are needed to fill it, and "trail:c",
+	// "nbsp:c" and "space:c" show trailing spaces, non-breaking spaces and
+	// all other spaces as c.
+	ListChars string
+	// IndentGuides draws a line at each level of indentation in the leading
+	// whitespace of lines
+	IndentGuides bool
+	// MatchPairs is a comma separated list of the pairs of brackets matched
+	// by % and highlighted, such as "(:),[:]"
+	MatchPairs string
+	// MatchParen highlights the pair of brackets under or around the cursor
+	MatchParen bool
+	// MatchTags makes % jump between matching HTML and XML tags
+	MatchTags bool
+	// Clipboard makes yanks, deletes and puts which aren't given a register
+	// use the system clipboard: "unnamedplus" uses the + register,
+	// "unnamed" the * register, and "" neither
+	Clipboard string
+	// ClipboardProvider is the name of the clipboard provider behind the +
+	// and * registers, such as "osc52" or "xclip", or "auto" to choose one
+	// for the environment fuji is running in
+	ClipboardProvider string
+}
+
+// defaultOptions are the options a new EditArea starts with
+var defaultOptions = Options{
+	NumberWidth:       4,
+	SignColumn:        "auto",
+	LineBreak:         true,
+	BreakIndent:       true,
+	SideScrollOff:     5,
+	AutoIndent:        true,
+	SmartIndent:       true,
+	ListChars:         "tab:> ,trail:-,nbsp:+",
+	AutoPairs:         defaultAutoPairs,
+	MatchPairs:        "(:),{:},[:]",
+	MatchParen:        true,
+	ClipboardProvider: "auto",
+}
+
+// option describes an option which can be changed with :set
+type option struct {
+	names []string
+	// field returns a pointer to the option's field in o: a *bool, *int or
+	// *string
+	field func(o *Options) interface{}
+	// values are the values a string option can take, if they are limited
+	values []string
+	// check returns an error if a string option's value is invalid
+	check func(value string) error
+}
+
+var options = []option{
+	{names: []string{"number", "nu"}, field: func(o *Options) interface{} { return &o.Number }},
+	{names: []string{"relativenumber", "rnu"}, field: func(o *Options) interface{} { return &o.RelativeNumber }},
+	{names: []string{"numberwidth", "nuw"}, field: func(o *Options) interface{} { return &o.NumberWidth }},
+	{names: []string{"signcolumn", "scl"}, field: func(o *Options) interface{} { return &o.SignColumn },
+		values: []string{"yes", "no", "auto"}},
+	{names: []string{"wrap"}, field: func(o *Options) interface{} { return &o.Wrap }},
+	{names: []string{"linebreak", "lbr"}, field: func(o *Options) interface{} { return &o.LineBreak }},
+	{names: []string{"showbreak", "sbr"}, field: func(o *Options) interface{} { return &o.ShowBreak }},
+	{names: []string{"breakindent", "bri"}, field: func(o *Options) interface{} { return &o.BreakIndent }},
+	{names: []string{"sidescrolloff", "siso"}, field: func(o *Options) interface{} { return &o.SideScrollOff }},
+	{names: []string{"shiftwidth", "sw"}, field: func(o *Options) interface{} { return &o.ShiftWidth }},
+	{names: []string{"autoindent", "ai"}, field: func(o *Options) interface{} { return &o.AutoIndent }},
+	{names: []string{"smartindent", "si"}, field: func(o *Options) interface{} { return &o.SmartIndent }},
+	{names: []string{"expandtab", "et"}, field: func(o *Options) interface{} { return &o.ExpandTab }},
+	{names: []string{"autopairs", "ap"}, field: func(o *Options) interface{} { return &o.AutoPairs },
+		check: func(value string) error { _, err := parseAutoPairs(value); return err }},
+	{names: []string{"textwidth", "tw"}, field: func(o *Options) interface{} { return &o.TextWidth }},
+	{names: []string{"cursorline", "cul"}, field: func(o *Options) interface{} { return &o.CursorLine }},
+	{names: []string{"cursorcolumn", "cuc"}, field: func(o *Options) interface{} { return &o.CursorColumn }},
+	{names: []string{"colorcolumn", "cc"}, field: func(o *Options) interface{} { return &o.ColorColumn },
+		check: func(value string) error { _, err := parseColorColumn(value); return err }},
+	{names: []string{"list"}, field: func(o *Options) interface{} { return &o.List }},
+	{names: []string{"listchars", "lcs"}, field: func(o *Options) interface{} { return &o.ListChars },
+		check: func(value string) error { _, err := parseListChars(value); return err }},
+	{names: []string{"indentguides", "ig"}, field: func(o *Options) interface{} { return &o.IndentGuides }},
+	{names: []string{"matchpairs", "mps"}, field: func(o *Options) interface{} { return &o.MatchPairs },
+		check: func(value string) error { _, err := parseMatchPairs(value); return err }},
+	{names: []string{"matchparen"}, field: func(o *Options) interface{} { return &o.MatchParen }},
+	{names: []string{"matchtags"}, field: func(o *Options) interface{} { return &o.MatchTags }},
+	{names: []string{"clipboard", "cb"}, field: func(o *Options) interface{} { return &o.Clipboard },
+		values: []string{"", "unnamed", "unnamedplus"}},
+	{names: []string{"clipboardprovider", "cbp"}, field: func(o *Options) interface{} { return &o.ClipboardProvider },
+		check: func(value string) error { _, err := clipboard.Lookup(value); return err }},
+}
+
+// lookupOption returns the option called name
+func lookupOption(name string) (option, bool) {
+	for _, opt := range options {
+		for _, n := range opt.names {
+			if n == name {
+				return opt, true
+			}
+		}
+	}
+	return option{}, false
+}
+
+// Options returns the EditArea's options, which may be changed
+func (e *EditArea) Options() *Options {
+	return &e.options
+}
+
+// SetOptions changes options using the syntax of vim's :set, for example
+// "nu", "nonu", "invnu", "nuw=6" or "scl?". Each option is separated by
+// whitespace, and whitespace in values is escaped with a backslash. Options
+// whose values are asked for are reported with SetMessage.
+func (e *EditArea) SetOptions(args string) error {
+	var shown []string
+	for _, arg := range splitOptions(args) {
+		s, err := e.setOption(arg)
+		if err != nil {
+			return err
+		}
+		if s != "" {
+			shown = append(shown, s)
+		}
+	}
+	if len(shown) > 0 {
+		e.SetMessage("%s", strings.Join(shown, "  "))
+	}
+	return nil
+}
+
+// setOption applies a single :set argument. If arg asks for the value of an
+// option, it is returned.
+func (e *EditArea) setOption(arg string) (shown string, err error) {
+	name, value, assign := arg, "", false
+	if i := strings.IndexAny(arg, "=:"); i >= 0 {
+		name, value, assign = arg[:i], arg[i+1:], true
+	}
+	query := strings.HasSuffix(name, "?")
+	name = strings.TrimSuffix(name, "?")
+	toggle := strings.HasSuffix(name, "!")
+	name = strings.TrimSuffix(name, "!")
+
+	opt, ok := lookupOption(name)
+	prefix := ""
+	if !ok {
+		for _, p := range []string{"no", "inv"} {
+			if strings.HasPrefix(name, p) {
+				if opt, ok = lookupOption(name[len(p):]); ok {
+					prefix = p
+					break
+				}
+			}
+		}
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown option: %s", name)
+	}
+
+	switch field := opt.field(&e.options).(type) {
+	case *bool:
+		switch {
+		case assign:
+			return "", fmt.Errorf("invalid argument: %s", arg)
+		case query:
+			if *field {
+				return opt.names[0], nil
+			}
+			return "no" + opt.names[0], nil
+		case toggle || prefix == "inv":
+			*field = !*field
+		default:
+			*field = prefix != "no"
+		}
+	case *int:
+		switch {
+		case prefix != "" || toggle:
+			return "", fmt.Errorf("invalid argument: %s", arg)
+		case !assign:
+			return fmt.Sprintf("%s=%d", opt.names[0], *field), nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid argument: %s", arg)
+		}
+		*field = n
+	case *string:
+		switch {
+		case prefix != "" || toggle:
+			return "", fmt.Errorf("invalid argument: %s", arg)
+		case !assign:
+			return fmt.Sprintf("%s=%s", opt.names[0], *field), nil
+		}
+		if opt.values != nil && !contains(opt.values, value) {
+			return "", fmt.Errorf("invalid argument: %s", arg)
+		}
+		if opt.check != nil {
+			if err := opt.check(value); err != nil {
+				return "", fmt.Errorf("invalid argument: %s: %v", arg, err)
+			}
+		}
+		*field = value
+	}
+	return "", nil
+}
+
+// splitOptions splits args on whitespace which isn't escaped with a
+// backslash
+func splitOptions(args string) []string {
+	var fields []string
+	var field []rune
+	escaped := false
+	for _, r := range args {
+		switch {
+		case escaped:
+			field = append(field, r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case unicode.IsSpace(r):
+			if len(field) > 0 {
+				fields = append(fields, string(field))
+			}
+			field = nil
+		default:
+			field = append(field, r)
+		}
+	}
+	if len(field) > 0 {
+		fields = append(fields, string(field))
+	}
+	return fields
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package editarea
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/line"
+)
+
+// defaultTextWidth is the width lines are reflowed to if textwidth isn't set
+const defaultTextWidth = 79
+
+var (
+	// commentLeader matches the indentation of a line and the comment
+	// leader which starts each line of a comment, with the space after it
+	commentLeader = regexp.MustCompile(`^[ \t]*(//+|#+|\*)([ \t]+|$)`)
+	// listMarker matches a Markdown list marker, with the space after it
+	listMarker = regexp.MustCompile(`^[ \t]*([-*+]|\d+[.)])[ \t]+`)
+	// indent matches the indentation of a line
+	indent = regexp.MustCompile(`^[ \t]*`)
+)
+
+// leader is the part of a line before its text, which is kept when lines
+// are reflowed
+type leader struct {
+	// first starts the line, and next starts the lines it is wrapped onto.
+	// They differ after a list marker, which the next lines are indented to
+	// line up with.
+	first, next string
+	// comment is the comment leader, like "//", or "" if there isn't one
+	comment string
+	list    bool
+}
+
+// markdown returns whether the file being edited is Markdown
+func (e *EditArea) markdown() bool {
+	return strings.EqualFold(filepath.Ext(e.Filename), ".md")
+}
+
+// parseLeader returns the leader of s and the text after it. Markdown files
+// have list markers but not comment leaders, since # starts a heading.
+func (e *EditArea) parseLeader(s string) (leader, string) {
+	var l leader
+	markdown := e.markdown()
+	if m := commentLeader.FindStringSubmatch(s); m != nil && !markdown {
+		l.first, l.comment = m[0], m[1]
+	} else {
+		l.first = indent.FindString(s)
+	}
+	rest := s[len(l.first):]
+	if m := listMarker.FindString(rest); m != "" && (markdown || l.comment != "") {
+		l.list = true
+		l.next = l.first + strings.Repeat(" ", len([]rune(m)))
+		l.first += m
+	} else {
+		l.next = l.first
+	}
+	return l, s[len(l.first):]
+}
+
+// reflowWidth returns the width lines are reflowed to
+func (e *EditArea) reflowWidth() int {
+	if e.options.TextWidth > 0 {
+		return e.options.TextWidth
+	}
+	return defaultTextWidth
+}
+
+// stringWidth returns the number of columns s is displayed in
+func stringWidth(s string) int {
+	return len(expandTabs([]rune(s)))
+}
+
+// wrap fills lines no wider than width with words, starting the first with
+// first and the rest with next. A word too long to fit is put on a line of
+// its own.
+func wrap(words []string, first, next string, width int) []string {
+	var lines []string
+	current, empty := first, true
+	for _, word := range words {
+		if !empty && stringWidth(current)+1+stringWidth(word) > width {
+			lines = append(lines, current)
+			current, empty = next, true
+		}
+		if !empty {
+			current += " "
+		}
+		current += word
+		empty = false
+	}
+	return append(lines, current)
+}
+
+// Reflow rewraps the paragraphs in the rows from start to end so that each
+// line is as full as it can be without being wider than width, or textwidth
+// if width is 0. Paragraphs are separated by blank lines, and by list items,
+// which start paragraphs of their own. Markdown headings are left as they
+// are. Each line keeps the indentation and comment leader of its paragraph's
+// first line, and the lines of a list item line up after its marker. The
+// cursor moves to the start of the last line reflowed.
+func (e *EditArea) Reflow(start, end, width int) {
+	if width <= 0 {
+		width = e.reflowWidth()
+	}
+	if end >= e.text.Length() {
+		end = e.text.Length() - 1
+	}
+	var lines []string
+	var paragraph []string
+	var first leader
+	flush := func() {
+		if len(paragraph) > 0 {
+			lines = append(lines, wrap(paragraph, first.first, first.next, width)...)
+		}
+		paragraph = nil
+	}
+	for row := start; row <= end; row++ {
+		s := e.text.Line(row).String()
+		l, text := e.parseLeader(s)
+		words := strings.Fields(text)
+		switch {
+		case len(words) == 0, e.markdown() && strings.HasPrefix(words[0], "#"):
+			// Blank lines, comment leaders on their own and headings
+			// separate paragraphs
+			flush()
+			lines = append(lines, strings.TrimRight(s, " \t"))
+			continue
+		case len(paragraph) == 0, l.list, l.comment != first.comment:
+			flush()
+			first = l
+		}
+		paragraph = append(paragraph, words...)
+	}
+	flush()
+
+	for row := start; row <= end; row++ {
+		e.text = e.text.DeleteLine(start)
+	}
+	for i, l := range lines {
+		e.text = e.text.InsertLine(start+i, line.New(l))
+	}
+	e.beenEdited = true
+	e.beenSaved = false
+	last := start + len(lines) - 1
+	_, length := e.indentation(last)
+	e.SetCursor(area.Point{X: length, Y: last})
+}
+
+// autoWrap breaks the cursor's line at the last blank before textwidth, when
+// text typed in insert mode has taken it past textwidth. The text moved onto
+// the new line starts with the line's indentation and comment leader. Lines
+// are only wrapped if textwidth is set.
+func (e *EditArea) autoWrap() {
+	width := e.options.TextWidth
+	if width <= 0 || e.displayColumn(e.cursor.Y, e.cursor.X) <= width {
+		return
+	}
+	s := e.text.Line(e.cursor.Y).String()
+	l, _ := e.parseLeader(s)
+	runes := []rune(s)
+	leaderLength := len([]rune(l.first))
+	// Find the last blank which the text before fits in the width
+	brk := -1
+	for i := leaderLength; i < e.cursor.X && i < len(runes); i++ {
+		if (runes[i] == ' ' || runes[i] == '\t') && e.displayColumn(e.cursor.Y, i) <= width {
+			brk = i
+		}
+	}
+	if brk < 0 {
+		// The text is one long word
+		return
+	}
+	blank := brk
+	for blank > leaderLength && (runes[blank-1] == ' ' || runes[blank-1] == '\t') {
+		blank--
+	}
+	if blank == leaderLength {
+		return
+	}
+	next := brk + 1
+	for next < e.cursor.X && (runes[next] == ' ' || runes[next] == '\t') {
+		next++
+	}
+	row := e.cursor.Y
+	e.text = e.text.DeleteLine(row).
+		InsertLine(row, line.New(string(runes[:blank]))).
+		InsertLine(row+1, line.New(l.next+string(runes[next:])))
+	e.cursor = area.Point{X: len([]rune(l.next)) + e.cursor.X - next, Y: row + 1}
+	e.beenEdited = true
+	e.beenSaved = false
+	e.scrollToCursor()
+}
@@ -0,0 +1,161 @@
+package editarea
+
+import (
+	"strings"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/line"
+)
+
+// Region is a part of the text which an operator acts on. If Linewise is
+// set, it is the whole of the rows from Start.Y to End.Y. If Blockwise is
+// set, it is the columns from Start.X up to, but not including, End.X of the
+// rows from Start.Y to End.Y. Otherwise it is the text from Start up to, but
+// not including, End, and End may be at the end of a line, after its last
+// rune.
+type Region struct {
+	Start, End area.Point
+	Linewise   bool
+	Blockwise  bool
+}
+
+// after returns the position after the rune at p, which is at the start of
+// the next row if p is at the end of its row. At the end of the text, it is
+// the end of the last row.
+func (e *EditArea) after(p area.Point) area.Point {
+	length := e.text.LineLength(p.Y)
+	switch {
+	case p.X < length:
+		return area.Point{X: p.X + 1, Y: p.Y}
+	case p.Y+1 < e.text.Length():
+		return area.Point{X: 0, Y: p.Y + 1}
+	}
+	return area.Point{X: length, Y: p.Y}
+}
+
+// clampRegion returns r, moved inside the text
+func (e *EditArea) clampRegion(r Region) Region {
+	clamp := func(p area.Point) area.Point {
+		if p.Y >= e.text.Length() {
+			p.Y = e.text.Length() - 1
+		}
+		if p.Y < 0 {
+			p.Y = 0
+		}
+		if length := e.text.LineLength(p.Y); p.X > length {
+			p.X = length
+		}
+		if p.X < 0 {
+			p.X = 0
+		}
+		return p
+	}
+	if r.Blockwise {
+		// The columns of a block may be past the end of some of its rows
+		if r.End.X < r.Start.X {
+			r.Start.X, r.End.X = r.End.X, r.Start.X
+		}
+		if r.End.Y < r.Start.Y {
+			r.Start.Y, r.End.Y = r.End.Y, r.Start.Y
+		}
+		r.Start.Y, r.End.Y = clamp(r.Start).Y, clamp(r.End).Y
+		if r.Start.X < 0 {
+			r.Start.X = 0
+		}
+		return r
+	}
+	r.Start, r.End = clamp(r.Start), clamp(r.End)
+	if r.End.Y < r.Start.Y || (r.End.Y == r.Start.Y && r.End.X < r.Start.X) {
+		r.Start, r.End = r.End, r.Start
+	}
+	return r
+}
+
+// blockColumns returns the columns of the block r which are on row, which
+// may be fewer than the width of the block if the row is short
+func (e *EditArea) blockColumns(r Region, row int) (start, end int) {
+	length := e.text.LineLength(row)
+	start, end = r.Start.X, r.End.X
+	if start > length {
+		start = length
+	}
+	if end > length {
+		end = length
+	}
+	return start, end
+}
+
+// RegionText returns the text in r. The text of a linewise region ends with
+// a newline, and the rows of a blockwise region are separated by newlines.
+func (e *EditArea) RegionText(r Region) string {
+	r = e.clampRegion(r)
+	var lines []string
+	for row := r.Start.Y; row <= r.End.Y; row++ {
+		lines = append(lines, e.text.Line(row).String())
+	}
+	if r.Blockwise {
+		for i := range lines {
+			start, end := e.blockColumns(r, r.Start.Y+i)
+			lines[i] = string([]rune(lines[i])[start:end])
+		}
+		return strings.Join(lines, "\n")
+	}
+	if r.Linewise {
+		return strings.Join(lines, "\n") + "\n"
+	}
+	last := len(lines) - 1
+	lines[last] = string([]rune(lines[last])[:r.End.X])
+	lines[0] = string([]rune(lines[0])[r.Start.X:])
+	return strings.Join(lines, "\n")
+}
+
+// ReplaceRegion replaces the text in r with s, as a single edit, and moves
+// the cursor to the start of r. If r is linewise, s should end with a
+// newline, like the text returned by RegionText, and an empty s deletes the
+// rows of r. If r is blockwise, the block is deleted, and s is inserted at
+// its start.
+func (e *EditArea) ReplaceRegion(r Region, s string) {
+	r = e.clampRegion(r)
+	e.beenEdited = true
+	e.beenSaved = false
+	if r.Blockwise {
+		for row := r.Start.Y; row <= r.End.Y; row++ {
+			start, end := e.blockColumns(r, row)
+			runes := []rune(e.text.Line(row).String())
+			l := line.New(string(runes[:start]) + string(runes[end:]))
+			e.text = e.text.DeleteLine(row).InsertLine(row, l)
+		}
+		start, _ := e.blockColumns(r, r.Start.Y)
+		if s != "" {
+			e.text = e.text.InsertString(r.Start.Y, start, s)
+		}
+		e.SetCursor(area.Point{X: start, Y: r.Start.Y})
+		return
+	}
+	if r.Linewise {
+		for row := r.Start.Y; row <= r.End.Y; row++ {
+			e.text = e.text.DeleteLine(r.Start.Y)
+		}
+		if s != "" {
+			for i, l := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+				e.text = e.text.InsertLine(r.Start.Y+i, line.New(l))
+			}
+		}
+		if e.text.Length() == 0 {
+			e.text = e.text.InsertLine(0, line.New(""))
+		}
+		e.SetCursor(area.Point{X: 0, Y: r.Start.Y})
+		return
+	}
+
+	before := []rune(e.text.Line(r.Start.Y).String())[:r.Start.X]
+	after := []rune(e.text.Line(r.End.Y).String())[r.End.X:]
+	for row := r.Start.Y; row <= r.End.Y; row++ {
+		e.text = e.text.DeleteLine(r.Start.Y)
+	}
+	e.text = e.text.InsertLine(r.Start.Y, line.New(string(before)+string(after)))
+	if s != "" {
+		e.text = e.text.InsertString(r.Start.Y, r.Start.X, s)
+	}
+	e.SetCursor(r.Start)
+}
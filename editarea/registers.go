@@ -0,0 +1,362 @@
+package editarea
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/clipboard"
+	"github.com/jamesroutley/fuji/line"
+	"github.com/jamesroutley/fuji/text"
+)
+
+// Register is text which has been yanked or deleted, and can be put back into
+// the text. Linewise and Blockwise describe the shape of the text, like the
+// Region it came from.
+type Register struct {
+	Text      string
+	Linewise  bool
+	Blockwise bool
+}
+
+// yankRingSize is the number of yanks and deletes kept in the yank ring
+const yankRingSize = 20
+
+// Registers are shared by every EditArea, so text can be yanked from one file
+// and put into another
+var (
+	registers = make(map[rune]Register)
+	// yankRing holds the text of recent yanks and deletes, newest first
+	yankRing []Register
+	// lastInserted and lastCommandLine are the text of the . and :
+	// registers
+	lastInserted    string
+	lastCommandLine string
+	// lastMacro is the name of the register last replayed as a macro
+	lastMacro rune
+	// copied holds the text last copied into the + and * registers, so its
+	// shape is known if it is pasted back unchanged
+	copied = make(map[rune]Register)
+)
+
+// RegisterNames lists the names of the registers in the order they are shown
+// in, which is the order vim shows them in
+const RegisterNames = `"0123456789abcdefghijklmnopqrstuvwxyz-.:%*+`
+
+// lastPut records the last put, so it can be replaced by cycling through the
+// yank ring
+type lastPut struct {
+	// before and after are the text before and after the put
+	before, after *text.Text
+	cursor        area.Point
+	putBefore     bool
+	count         int
+	// ring is the index in the yank ring of the text which was put, or -1
+	ring int
+}
+
+// RegisterName returns the name of the register typed before the running
+// command. If there wasn't one, it is '"', the unnamed register, or the
+// clipboard register chosen by the clipboard option.
+func (e *EditArea) RegisterName() rune {
+	if e.running.register != 0 {
+		return e.running.register
+	}
+	if name := e.clipboardRegister(); name != 0 {
+		return name
+	}
+	return '"'
+}
+
+// clipboardRegister returns the name of the clipboard register which the
+// clipboard option makes the default, or 0 if there isn't one
+func (e *EditArea) clipboardRegister() rune {
+	switch e.options.Clipboard {
+	case "unnamedplus":
+		return '+'
+	case "unnamed":
+		return '*'
+	}
+	return 0
+}
+
+// clipboardSelection returns the clipboard behind the register called name
+func clipboardSelection(name rune) clipboard.Selection {
+	if name == '*' {
+		return clipboard.Primary
+	}
+	return clipboard.Clipboard
+}
+
+// paste returns the contents of the clipboard register called name
+func (e *EditArea) paste(name rune) (Register, error) {
+	p, err := clipboard.Lookup(e.options.ClipboardProvider)
+	if err != nil {
+		return Register{}, err
+	}
+	s, err := p.Paste(clipboardSelection(name))
+	if err != nil {
+		return Register{}, err
+	}
+	if r := copied[name]; r.Text == s {
+		return r, nil
+	}
+	// Like vim, text from elsewhere is put linewise if it ends in a newline
+	return Register{Text: s, Linewise: strings.HasSuffix(s, "\n")}, nil
+}
+
+// copy copies r into the clipboard register called name
+func (e *EditArea) copy(name rune, r Register) error {
+	p, err := clipboard.Lookup(e.options.ClipboardProvider)
+	if err != nil {
+		return err
+	}
+	if err := p.Copy(clipboardSelection(name), r.Text); err != nil {
+		return err
+	}
+	copied[name] = r
+	return nil
+}
+
+// Register returns the contents of the register called name. ok is false if
+// the register is empty, or there's no such register.
+func (e *EditArea) Register(name rune) (r Register, ok bool) {
+	switch name {
+	case '%':
+		r.Text = e.Filename
+	case '.':
+		r.Text = lastInserted
+	case ':':
+		r.Text = lastCommandLine
+	case '_':
+	case '+', '*':
+		var err error
+		if r, err = e.paste(name); err != nil {
+			e.SetMessage("%s", err)
+		}
+	default:
+		r = registers[unicode.ToLower(name)]
+	}
+	return r, r.Text != ""
+}
+
+// SetRegister writes r into the register called name. An upper case name
+// appends to the register with the lower case name. Text written to the black
+// hole register, _, is discarded.
+func (e *EditArea) SetRegister(name rune, r Register) error {
+	switch {
+	case name == '_':
+		return nil
+	case name == '.' || name == ':' || name == '%':
+		return fmt.Errorf("register %c is read-only", name)
+	case name == '+' || name == '*':
+		return e.copy(name, r)
+	case name >= 'A' && name <= 'Z':
+		name = unicode.ToLower(name)
+		old, ok := registers[name]
+		if !ok {
+			break
+		}
+		switch {
+		case old.Linewise || r.Linewise:
+			r.Text = withNewline(old.Text) + withNewline(r.Text)
+			r.Linewise = true
+		default:
+			r.Text = old.Text + r.Text
+		}
+		r.Blockwise = old.Blockwise && r.Blockwise
+	case !strings.ContainsRune(RegisterNames, name):
+		return fmt.Errorf("invalid register name: %c", name)
+	}
+	registers[name] = r
+	return nil
+}
+
+// withNewline returns s, ending in a newline
+func withNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+// Yank copies the text in region r into the register typed before the
+// running command, and the 0 register
+func (e *EditArea) Yank(r Region) {
+	e.store(r, '0')
+}
+
+// StoreDeleted copies the text in region r, which is about to be deleted,
+// into the register typed before the running command. Like vim, deletes of
+// whole lines or more than a line shift the numbered registers along and go
+// into the 1 register, and smaller deletes go into the - register.
+func (e *EditArea) StoreDeleted(r Region) {
+	r = e.clampRegion(r)
+	if r.Linewise || r.Start.Y != r.End.Y {
+		e.store(r, '1')
+		return
+	}
+	e.store(r, '-')
+}
+
+// store copies the text in r into the register typed before the running
+// command, the unnamed register and the yank ring. If no register was typed,
+// it is also copied into the numbered or small delete register numbered, and
+// the clipboard if the clipboard option is set.
+func (e *EditArea) store(r Region, numbered rune) {
+	reg := Register{Text: e.RegionText(r), Linewise: r.Linewise, Blockwise: r.Blockwise}
+	name := e.running.register
+	switch name {
+	case '_':
+		return
+	case 0, '"':
+		if c := e.clipboardRegister(); c != 0 {
+			if err := e.SetRegister(c, reg); err != nil {
+				e.SetMessage("%s", err)
+			}
+		}
+	default:
+		if err := e.SetRegister(name, reg); err != nil {
+			e.SetMessage("%s", err)
+			return
+		}
+		registers['"'] = reg
+		if name >= 'A' && name <= 'Z' {
+			registers['"'] = registers[unicode.ToLower(name)]
+		}
+		addToYankRing(reg)
+		return
+	}
+	if numbered == '1' {
+		for n := '9'; n > '1'; n-- {
+			if old, ok := registers[n-1]; ok {
+				registers[n] = old
+			}
+		}
+	}
+	registers[numbered] = reg
+	registers['"'] = reg
+	addToYankRing(reg)
+}
+
+// addToYankRing adds r to the front of the yank ring
+func addToYankRing(r Register) {
+	if len(yankRing) > 0 && yankRing[0] == r {
+		return
+	}
+	yankRing = append([]Register{r}, yankRing...)
+	if len(yankRing) > yankRingSize {
+		yankRing = yankRing[:yankRingSize]
+	}
+}
+
+// Put puts the text of r into the text count times, after the cursor, or
+// before it if before is set. Linewise text is put on new lines below or
+// above the cursor's, and blockwise text is put in the following columns of
+// the cursor's row and the rows below it.
+func (e *EditArea) Put(r Register, before bool, count int) {
+	ring := -1
+	for i, yanked := range yankRing {
+		if yanked == r {
+			ring = i
+			break
+		}
+	}
+	put := &lastPut{before: e.text, cursor: e.cursor, putBefore: before, count: count, ring: ring}
+	e.put(r, before, count)
+	put.after = e.text
+	e.lastPut = put
+}
+
+// put puts r into the text, without recording the put
+func (e *EditArea) put(r Register, before bool, count int) {
+	if r.Text == "" || count < 1 {
+		return
+	}
+	e.beenEdited = true
+	e.beenSaved = false
+	row, col := e.cursor.Y, e.cursorColumn()
+	if !before && e.text.LineLength(row) > 0 {
+		col++
+	}
+	switch {
+	case r.Linewise:
+		lines := strings.Split(strings.TrimSuffix(r.Text, "\n"), "\n")
+		if !before {
+			row++
+		}
+		for i := 0; i < count; i++ {
+			for j, l := range lines {
+				e.text = e.text.InsertLine(row+i*len(lines)+j, line.New(l))
+			}
+		}
+		e.cursor = area.Point{X: firstNonBlank(e.text, row), Y: row}
+	case r.Blockwise:
+		lines := strings.Split(r.Text, "\n")
+		width := 0
+		for _, l := range lines {
+			if n := utf8.RuneCountInString(l); n > width {
+				width = n
+			}
+		}
+		for i, l := range lines {
+			y := row + i
+			if y == e.text.Length() {
+				e.text = e.text.InsertLine(y, line.New(""))
+			}
+			length := e.text.LineLength(y)
+			if length < col {
+				e.text = e.text.InsertString(y, length, strings.Repeat(" ", col-length))
+			}
+			if length > col {
+				// Keep the text after the block lined up
+				l += strings.Repeat(" ", width-utf8.RuneCountInString(l))
+			}
+			e.text = e.text.InsertString(y, col, strings.Repeat(l, count))
+		}
+		e.cursor = area.Point{X: col, Y: row}
+	default:
+		s := strings.Repeat(r.Text, count)
+		e.text = e.text.InsertString(row, col, s)
+		e.cursor = area.Point{X: col, Y: row}
+		if !strings.Contains(s, "\n") {
+			e.cursor.X += utf8.RuneCountInString(s) - 1
+		}
+	}
+	e.scrollToCursor()
+}
+
+// CyclePut replaces the text put by the last put with the next older text in
+// the yank ring, or if delta is negative, the next newer. It fails if the
+// text has changed since the put.
+func (e *EditArea) CyclePut(delta int) error {
+	p := e.lastPut
+	if p == nil || p.after != e.text {
+		return fmt.Errorf("the last command was not a put")
+	}
+	i := p.ring + delta
+	if i < 0 || i >= len(yankRing) {
+		return fmt.Errorf("no more text in the yank ring")
+	}
+	if e.history.head.text == p.after && e.history.head.prev != nil {
+		// Replace the put in the history, so it is undone in one step
+		e.history.undo()
+	}
+	e.text, e.cursor = p.before, p.cursor
+	e.put(yankRing[i], p.putBefore, p.count)
+	p.after, p.ring = e.text, i
+	return nil
+}
+
+// firstNonBlank returns the column of the first non-blank rune on row, or 0
+// if the row is blank
+func firstNonBlank(t *text.Text, row int) int {
+	for i, r := range []rune(t.Line(row).String()) {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return 0
+}
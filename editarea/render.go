@@ -0,0 +1,99 @@
+package editarea
+
+import "github.com/jamesroutley/fuji/syntax"
+
+// tabWidth is the number of columns a tab is displayed as
+const tabWidth = 4
+
+// drawnRow records the styled runes drawn on a row of the display
+type drawnRow struct {
+	gutter []syntax.StyledRune
+	runes  []syntax.StyledRune
+	valid  bool
+}
+
+// Invalidate forces the whole EditArea to be redrawn by the next call to
+// Draw. It should be called when something else has drawn over the area.
+func (e *EditArea) Invalidate() {
+	for i := range e.drawn {
+		e.drawn[i].valid = false
+	}
+}
+
+// drawnValid returns whether every row of the display has been drawn
+func (e *EditArea) drawnValid() bool {
+	for _, row := range e.drawn {
+		if !row.valid {
+			return false
+		}
+	}
+	return true
+}
+
+// drawRow draws gutter followed by content on row y of the screen, clearing
+// the rest of the row
+func (e *EditArea) drawRow(y int, gutter, content []syntax.StyledRune) {
+	a := e.drawnArea
+	x := a.Start.X
+	for _, runes := range [][]syntax.StyledRune{gutter, content} {
+		for _, sr := range runes {
+			if x < a.End.X {
+				e.screen.SetContent(x, y, sr.Rune, nil, sr.Style)
+			}
+			x++
+		}
+	}
+	background := syntax.Background()
+	for ; x < a.End.X; x++ {
+		e.screen.SetContent(x, y, ' ', nil, background)
+	}
+}
+
+// displayColumn returns the column of the display that the rune at col on
+// row is drawn in, accounting for tabs
+func (e *EditArea) displayColumn(row, col int) int {
+	var runes []rune
+	if row < e.text.Length() {
+		runes = []rune(e.text.Line(row).String())
+	}
+	x := 0
+	for i := 0; i < col; i++ {
+		if i < len(runes) && runes[i] == '\t' {
+			x += tabWidth
+		} else {
+			x++
+		}
+	}
+	return x
+}
+
+// textColumn returns the column of row drawn in the column x of the display,
+// accounting for tabs. It is the inverse of displayColumn.
+func (e *EditArea) textColumn(row, x int) int {
+	runes := []rune(e.text.Line(row).String())
+	col := 0
+	for ; col < len(runes); col++ {
+		width := 1
+		if runes[col] == '\t' {
+			width = tabWidth
+		}
+		if x < width {
+			break
+		}
+		x -= width
+	}
+	return col
+}
+
+// sameStyledRunes returns whether a and b are identical
+func sameStyledRunes(a, b []syntax.StyledRune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
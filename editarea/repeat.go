@@ -0,0 +1,177 @@
+package editarea
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/text"
+)
+
+// change is a normal mode command which changed the text, recorded so that
+// it can be repeated with RepeatChange. Any normal mode command which
+// changes the text or enters insert mode is a change, so commands added with
+// AddNormalModeCommand can be repeated without doing anything themselves.
+type change struct {
+	register rune
+	// count is the count typed before the command, with the counts before
+	// an operator and its motion multiplied together, or 0 if there wasn't
+	// one
+	count int
+	// keys are the keys of the command, without its count or register, and
+	// inserted the keys typed in the insert mode it entered, if any
+	keys, inserted []*tcell.EventKey
+	// visual is the size of the selection a command run in visual mode
+	// acted on, or nil
+	visual *visualExtent
+}
+
+// visualExtent is the size of a selection, which is selected again from the
+// cursor to repeat a change made in visual mode
+type visualExtent struct {
+	kind SelectionKind
+	rows int
+	// cols is the width of the selection if it is on one row or a block,
+	// and otherwise the column it ends at
+	cols int
+}
+
+// changeStart is the state of the EditArea when a normal mode command
+// started, to tell whether the command changed the text
+type changeStart struct {
+	text    *text.Text
+	changes int
+	visual  *visualExtent
+}
+
+var (
+	// lastChange is the last change made, which . repeats
+	lastChange *change
+	// changes counts the changes made, so commands which run other changes,
+	// like @, aren't recorded themselves
+	changes int
+)
+
+// startChange records the state of the EditArea as a new normal mode
+// command starts
+func (e *EditArea) startChange() {
+	e.undid = false
+	s := &changeStart{text: e.text, changes: changes}
+	if e.Mode == ModeVisual {
+		anchor, cursor := e.selection.anchor, e.cursorColumn()
+		rows, cols := e.cursor.Y-anchor.Y, cursor-anchor.X
+		if rows < 0 {
+			rows = -rows
+		}
+		if cols < 0 {
+			cols = -cols
+		}
+		if rows > 0 && e.selection.kind == SelectCharacters {
+			// The selection ends at the column of its later end
+			cols = cursor
+			if anchor.Y > e.cursor.Y {
+				cols = anchor.X
+			}
+		}
+		s.visual = &visualExtent{kind: e.selection.kind, rows: rows, cols: cols}
+	}
+	e.changeStart = s
+}
+
+// endChange records the command which has just been run as the last change,
+// if it changed the text. If it entered insert mode, the change is recorded
+// when insert mode is left, with the keys typed.
+func (e *EditArea) endChange() {
+	s := e.changeStart
+	if s == nil || e.pending != (command{}) || e.charCommand != nil {
+		// The command hasn't finished
+		return
+	}
+	e.changeStart = nil
+	if e.undid || changes != s.changes || (e.text == s.text && !e.inserting()) {
+		return
+	}
+	c := &change{register: e.running.register, visual: s.visual}
+	if e.HasCount() {
+		c.count = e.Count()
+	}
+	keys := e.running.keys
+	if e.running.operator != "" && s.visual == nil {
+		keys = e.running.operator + keys
+	}
+	c.keys = ParseKeys(keys)
+	if e.running.char != 0 {
+		c.keys = append(c.keys, tcell.NewEventKey(tcell.KeyRune, e.running.char, tcell.ModNone))
+	}
+	if e.inserting() {
+		e.insertChange = c
+		return
+	}
+	lastChange = c
+	changes++
+}
+
+// recordInsert records a key typed in insert mode as part of the change
+// which entered insert mode
+func (e *EditArea) recordInsert(ev *tcell.EventKey) {
+	if e.insertChange != nil {
+		e.insertChange.inserted = append(e.insertChange.inserted, ev)
+	}
+}
+
+// endInsertChange records the change which entered insert mode, which has
+// just been left
+func (e *EditArea) endInsertChange() {
+	if e.insertChange == nil {
+		return
+	}
+	lastChange, e.insertChange = e.insertChange, nil
+	changes++
+}
+
+// RepeatChange repeats the last change at the cursor. If count isn't 0, it
+// replaces the change's count. Changes made in visual mode act on as much
+// text as they did before, from the cursor. Like vim, a change which put
+// from a numbered register puts from the next one when it is repeated.
+func (e *EditArea) RepeatChange(count int) error {
+	c := lastChange
+	if c == nil {
+		return fmt.Errorf("no change to repeat")
+	}
+	if count == 0 {
+		count = c.count
+	}
+	var keys []*tcell.EventKey
+	runes := func(s string) {
+		for _, r := range s {
+			keys = append(keys, tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		}
+	}
+	if register := c.register; register != 0 {
+		if register >= '1' && register < '9' {
+			register++
+		}
+		runes(`"` + string(register))
+	}
+	if count > 0 {
+		runes(strconv.Itoa(count))
+	}
+	keys = append(keys, c.keys...)
+	keys = append(keys, c.inserted...)
+	if v := c.visual; v != nil {
+		start := e.cursor
+		e.EnterVisualMode(v.kind)
+		end := start
+		end.Y += v.rows
+		end.X = v.cols
+		if v.rows == 0 || v.kind == SelectBlock {
+			end.X = e.cursorColumn() + v.cols
+		}
+		e.SetCursor(end)
+	}
+	e.typeahead = append(keys, e.typeahead...)
+	if e.replaying {
+		return nil
+	}
+	return e.replay()
+}
@@ -0,0 +1,140 @@
+package editarea
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/line"
+)
+
+// appended is recorded in place of the rune overwritten in replace mode when
+// a rune is typed past the end of its line, so backspace deletes it
+const appended rune = -1
+
+// inserting returns whether text is being typed into the EditArea, in insert
+// or replace mode
+func (e *EditArea) inserting() bool {
+	return e.Mode == ModeInsert || e.Mode == ModeReplace
+}
+
+// handleReplaceKey handles the keys which behave differently in replace mode
+// and insert mode. It returns false for other keys, which are handled by the
+// insert mode commands.
+func (e *EditArea) handleReplaceKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyRune:
+		e.Overwrite(ev.Rune())
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		e.restore()
+	case tcell.KeyEnter:
+		// Like vim, line breaks are inserted rather than replacing a rune
+		e.LineBreak()
+		e.replaced = append(e.replaced, '\n')
+	default:
+		// The cursor may move, so the runes replaced can't be restored
+		e.replaced = nil
+		return false
+	}
+	return true
+}
+
+// Overwrite replaces the rune under the cursor with r, or adds r to the end
+// of the line if the cursor is past its end, and moves the cursor right
+func (e *EditArea) Overwrite(r rune) {
+	row, col := e.cursor.Y, e.cursor.X
+	old := appended
+	if runes := []rune(e.text.Line(row).String()); col < len(runes) {
+		old = runes[col]
+		e.text = e.text.Delete(row, col)
+	}
+	e.replaced = append(e.replaced, old)
+	e.text = e.text.Insert(row, col, r)
+	e.cursor.X = col + 1
+	e.beenEdited = true
+	e.beenSaved = false
+}
+
+// restore moves the cursor left in replace mode, restoring the rune which
+// was overwritten there. Before where replace mode was entered, the cursor
+// only moves.
+func (e *EditArea) restore() {
+	n := len(e.replaced)
+	if n == 0 {
+		if e.cursor.X > 0 {
+			e.cursor.X--
+		}
+		return
+	}
+	old := e.replaced[n-1]
+	e.replaced = e.replaced[:n-1]
+	if old == '\n' {
+		e.Backspace()
+		return
+	}
+	e.cursor.X--
+	e.Delete()
+	if old != appended {
+		e.text = e.text.Insert(e.cursor.Y, e.cursor.X, old)
+	}
+}
+
+// ReplaceChars replaces the count runes from the cursor with r, leaving the
+// cursor on the last one. Like vim, nothing is replaced if there are fewer
+// than count runes from the cursor to the end of the line, and the command
+// fails. If r is a carriage return, the runes are replaced by a single line
+// break, and the cursor moves to the start of the new line.
+func (e *EditArea) ReplaceChars(r rune, count int) bool {
+	row, col := e.cursor.Y, e.cursorColumn()
+	runes := []rune(e.text.Line(row).String())
+	if col+count > len(runes) {
+		e.failed = true
+		return false
+	}
+	if r == '\r' {
+		s := string(runes[:col]) + string(runes[col+count:])
+		e.text = e.text.DeleteLine(row).InsertLine(row, line.New(s))
+		e.cursor.X = col
+		e.LineBreak()
+		// The new line's indentation is kept, as insert mode isn't entered
+		e.autoIndented = false
+		return true
+	}
+	for i := col; i < col+count; i++ {
+		runes[i] = r
+	}
+	e.text = e.text.DeleteLine(row).InsertLine(row, line.New(string(runes)))
+	e.cursor.X = col + count - 1
+	e.beenEdited = true
+	e.beenSaved = false
+	return true
+}
+
+// ReplaceSelection replaces every rune in the selection with r, and leaves
+// visual mode with the cursor at the start of the selection
+func (e *EditArea) ReplaceSelection(r rune) {
+	sel := e.clampRegion(e.Selection())
+	e.ExitVisualMode()
+	e.beenEdited = true
+	e.beenSaved = false
+	for row := sel.Start.Y; row <= sel.End.Y; row++ {
+		runes := []rune(e.text.Line(row).String())
+		start, end := 0, len(runes)
+		switch {
+		case sel.Blockwise:
+			start, end = e.blockColumns(sel, row)
+		case !sel.Linewise && row == sel.Start.Y && row == sel.End.Y:
+			start, end = sel.Start.X, sel.End.X
+		case !sel.Linewise && row == sel.Start.Y:
+			start = sel.Start.X
+		case !sel.Linewise && row == sel.End.Y:
+			end = sel.End.X
+		}
+		for i := start; i < end && i < len(runes); i++ {
+			runes[i] = r
+		}
+		e.text = e.text.DeleteLine(row).InsertLine(row, line.New(string(runes)))
+	}
+	start := sel.Start
+	if sel.Linewise {
+		start.X = 0
+	}
+	e.SetCursor(start)
+}
@@ -0,0 +1,174 @@
+package editarea
+
+import (
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/syntax"
+	"github.com/jamesroutley/fuji/theme"
+)
+
+// SelectionKind is the kind of text selected in visual mode
+type SelectionKind uint8
+
+const (
+	// SelectCharacters selects the text from one position to another, like v
+	SelectCharacters SelectionKind = iota
+	// SelectLines selects whole lines, like V
+	SelectLines
+	// SelectBlock selects a rectangular block of text, like <C-v>
+	SelectBlock
+)
+
+// selection is the text selected in visual mode, from anchor to the cursor.
+// lineEnd is set when the cursor was moved to the end of its line by a
+// motion like $, so that the line break is selected too.
+type selection struct {
+	anchor  area.Point
+	kind    SelectionKind
+	lineEnd bool
+}
+
+var visualModeCommands = make(map[string]NormalModeCommand)
+
+// AddVisualModeCommand adds a new visual mode command. Motions and text
+// objects can also be used in visual mode, and operators act on the
+// selection.
+func AddVisualModeCommand(name string, behaviour NormalModeCommand) {
+	visualModeCommands[name] = behaviour
+}
+
+// EnterVisualMode switches the EditArea into visual mode, selecting text of
+// the given kind from the cursor. If the EditArea is already in visual mode,
+// the selection is kept and only its kind changes.
+func (e *EditArea) EnterVisualMode(kind SelectionKind) {
+	if e.Mode != ModeVisual {
+		e.selection.anchor = area.Point{X: e.cursorColumn(), Y: e.cursor.Y}
+		e.selection.lineEnd = false
+		e.Mode = ModeVisual
+	}
+	e.selection.kind = kind
+}
+
+// SelectionKind returns the kind of text selected in visual mode
+func (e *EditArea) SelectionKind() SelectionKind {
+	return e.selection.kind
+}
+
+// ExitVisualMode switches the EditArea from visual mode to normal mode
+func (e *EditArea) ExitVisualMode() {
+	if e.Mode == ModeVisual {
+		e.Mode = ModeNormal
+	}
+}
+
+// SwapSelectionEnds moves the cursor to the other end of the selection
+func (e *EditArea) SwapSelectionEnds() {
+	e.selection.lineEnd = false
+	e.cursor, e.selection.anchor = e.selection.anchor, area.Point{X: e.cursorColumn(), Y: e.cursor.Y}
+	e.scrollToCursor()
+}
+
+// Selection returns the region selected in visual mode. Outside visual mode
+// it returns the zero Region.
+func (e *EditArea) Selection() Region {
+	if e.Mode != ModeVisual {
+		return Region{}
+	}
+	cursor := area.Point{X: e.cursorColumn(), Y: e.cursor.Y}
+	start, end := e.selection.anchor, cursor
+	if e.selection.kind == SelectBlock {
+		if end.X < start.X {
+			start.X, end.X = end.X, start.X
+		}
+		if end.Y < start.Y {
+			start.Y, end.Y = end.Y, start.Y
+		}
+		return Region{Start: start, End: area.Point{X: end.X + 1, Y: end.Y}, Blockwise: true}
+	}
+	if end.Y < start.Y || (end.Y == start.Y && end.X < start.X) {
+		start, end = end, start
+	}
+	if e.selection.kind == SelectLines {
+		return Region{Start: start, End: end, Linewise: true}
+	}
+	if e.selection.lineEnd && end == cursor {
+		end.X = e.text.LineLength(end.Y)
+	}
+	return Region{Start: start, End: e.after(end)}
+}
+
+// selectRegion selects r in visual mode
+func (e *EditArea) selectRegion(r Region) {
+	e.selection.kind = SelectCharacters
+	if r.Linewise {
+		e.selection.kind = SelectLines
+	}
+	e.selection.anchor = r.Start
+	e.selection.lineEnd = false
+	end := r.End
+	if !r.Linewise && (end.X > r.Start.X || end.Y > r.Start.Y) {
+		// The selection includes the rune under the cursor
+		if end.X > 0 {
+			end.X--
+		} else {
+			end = area.Point{X: e.text.LineLength(end.Y - 1), Y: end.Y - 1}
+		}
+	}
+	e.cursor = end
+	e.scrollToCursor()
+}
+
+// operateOnSelection runs the operator op on the selection, leaving visual
+// mode
+func (e *EditArea) operateOnSelection(op string) {
+	r := e.Selection()
+	e.running, e.pending = e.pending, command{}
+	e.running.operator = op
+	e.Mode = ModeNormal
+	e.operate(r)
+}
+
+// highlightSelection highlights the selected text in content, which is drawn
+// for segment s
+func (e *EditArea) highlightSelection(s segment, content []syntax.StyledRune, r Region) []syntax.StyledRune {
+	if r == (Region{}) || s.row < r.Start.Y || s.row > r.End.Y {
+		return content
+	}
+	start, end := 0, e.text.LineLength(s.row)+1
+	switch {
+	case r.Blockwise:
+		start, end = r.Start.X, r.End.X
+		if length := e.text.LineLength(s.row); end > length {
+			end = length
+		}
+	case !r.Linewise:
+		if s.row == r.Start.Y {
+			start = r.Start.X
+		}
+		if s.row == r.End.Y {
+			end = r.End.X
+		}
+	}
+	// Columns are converted to columns of the display row
+	from := e.displayColumn(s.row, start) - s.start + s.prefix
+	to := e.displayColumn(s.row, end) - s.start + s.prefix
+	if end > e.text.LineLength(s.row) {
+		// The line break is shown as a single selected column
+		to = e.displayColumn(s.row, e.text.LineLength(s.row)) - s.start + s.prefix + 1
+	}
+	if from < s.prefix {
+		from = s.prefix
+	}
+	if to <= from {
+		return content
+	}
+	content = append([]syntax.StyledRune(nil), content...)
+	background := syntax.Background()
+	for len(content) < to && len(content) < e.textWidth() {
+		content = append(content, syntax.StyledRune{Rune: ' ', Style: background})
+	}
+	_, bg, _ := theme.UI(theme.Selection).Decompose()
+	for x := from; x < to && x < len(content); x++ {
+		content[x].Style = content[x].Style.Background(bg)
+	}
+	return content
+}
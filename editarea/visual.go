@@ -0,0 +1,197 @@
+package editarea
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/syntax"
+	"github.com/jamesroutley/fuji/theme"
+)
+
+// indentGuide is the glyph drawn for indent guides
+const indentGuide = '│'
+
+// listChars are the glyphs used to show whitespace. A zero rune means that
+// kind of whitespace isn't shown.
+type listChars struct {
+	tab                [2]rune
+	trail, nbsp, space rune
+}
+
+// parseListChars parses the listchars option
+func parseListChars(s string) (lc listChars, err error) {
+	if s == "" {
+		return lc, nil
+	}
+	for _, setting := range strings.Split(s, ",") {
+		i := strings.Index(setting, ":")
+		if i < 0 {
+			return lc, fmt.Errorf("missing ':' in %q", setting)
+		}
+		name, glyphs := setting[:i], []rune(setting[i+1:])
+		switch {
+		case name == "tab" && len(glyphs) == 2:
+			lc.tab = [2]rune{glyphs[0], glyphs[1]}
+		case name == "trail" && len(glyphs) == 1:
+			lc.trail = glyphs[0]
+		case name == "nbsp" && len(glyphs) == 1:
+			lc.nbsp = glyphs[0]
+		case name == "space" && len(glyphs) == 1:
+			lc.space = glyphs[0]
+		default:
+			return lc, fmt.Errorf("invalid setting %q", setting)
+		}
+	}
+	return lc, nil
+}
+
+// parseColorColumn parses the colorcolumn option into 0-based columns
+func parseColorColumn(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var columns []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid column %q", field)
+		}
+		columns = append(columns, n-1)
+	}
+	return columns, nil
+}
+
+// shiftWidth returns the number of columns in each level of indentation
+func (e *EditArea) shiftWidth() int {
+	if e.options.ShiftWidth > 0 {
+		return e.options.ShiftWidth
+	}
+	return tabWidth
+}
+
+// cells returns styled as it is displayed, one styled rune for each column.
+// Tabs are expanded, and whitespace and indent guides are drawn if the
+// options ask for them.
+func (e *EditArea) cells(styled []syntax.StyledRune) []syntax.StyledRune {
+	var lc listChars
+	if e.options.List {
+		// The option is checked when it is set
+		lc, _ = parseListChars(e.options.ListChars)
+	}
+	whitespace := theme.UI(theme.Whitespace)
+	glyph := func(r rune, style tcell.Style) syntax.StyledRune {
+		_, bg, _ := style.Decompose()
+		return syntax.StyledRune{Rune: r, Style: whitespace.Background(bg)}
+	}
+
+	// trail is the index of the first trailing space
+	trail := len(styled)
+	for trail > 0 && isBlank(styled[trail-1].Rune) {
+		trail--
+	}
+	c := make([]syntax.StyledRune, 0, len(styled))
+	for i, sr := range styled {
+		switch {
+		case sr.Rune == '\t':
+			for j := 0; j < tabWidth; j++ {
+				switch {
+				case lc.tab[0] == 0:
+					c = append(c, syntax.StyledRune{Rune: ' ', Style: sr.Style})
+				case j == 0:
+					c = append(c, glyph(lc.tab[0], sr.Style))
+				default:
+					c = append(c, glyph(lc.tab[1], sr.Style))
+				}
+			}
+		case sr.Rune == ' ' && i >= trail && lc.trail != 0:
+			c = append(c, glyph(lc.trail, sr.Style))
+		case sr.Rune == ' ' && lc.space != 0:
+			c = append(c, glyph(lc.space, sr.Style))
+		case sr.Rune == '\u00a0' && lc.nbsp != 0:
+			c = append(c, glyph(lc.nbsp, sr.Style))
+		default:
+			c = append(c, sr)
+		}
+	}
+
+	if e.options.IndentGuides {
+		indent := 0
+		for indent < len(styled) && isBlank(styled[indent].Rune) {
+			indent++
+		}
+		guide := theme.UI(theme.IndentGuide)
+		columns := e.displayColumnOf(styled, indent)
+		for col := 0; col < columns && col < len(c); col += e.shiftWidth() {
+			if c[col].Rune == ' ' {
+				_, bg, _ := c[col].Style.Decompose()
+				c[col] = syntax.StyledRune{Rune: indentGuide, Style: guide.Background(bg)}
+			}
+		}
+	}
+	return c
+}
+
+// displayColumnOf returns the display column of the rune at i in styled
+func (e *EditArea) displayColumnOf(styled []syntax.StyledRune, i int) int {
+	col := 0
+	for _, sr := range styled[:i] {
+		if sr.Rune == '\t' {
+			col += tabWidth
+		} else {
+			col++
+		}
+	}
+	return col
+}
+
+// isBlank returns whether r is a space or tab
+func isBlank(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// decorate pads content, drawn for segment s, to the width of the text, and
+// highlights the cursor line, cursor column and colour columns in it.
+// cursorX is the column of the display row the cursor is in.
+func (e *EditArea) decorate(s segment, content []syntax.StyledRune, cursorX int) []syntax.StyledRune {
+	if !e.options.CursorLine && !e.options.CursorColumn && e.options.ColorColumn == "" {
+		return content
+	}
+	width := e.textWidth()
+	background := syntax.Background()
+	padded := make([]syntax.StyledRune, width)
+	copy(padded, content)
+	for x := len(content); x < width; x++ {
+		padded[x] = syntax.StyledRune{Rune: ' ', Style: background}
+	}
+
+	highlight := func(x int, element theme.Element) {
+		if x < 0 || x >= width {
+			return
+		}
+		_, bg, _ := theme.UI(element).Decompose()
+		padded[x].Style = padded[x].Style.Background(bg)
+	}
+	// column returns the column of the display row which shows the column
+	// col of the text
+	column := func(col int) int {
+		return col - s.start + s.prefix
+	}
+
+	if e.options.CursorLine && s.row == e.cursor.Y {
+		for x := range padded {
+			highlight(x, theme.CursorLine)
+		}
+	}
+	columns, _ := parseColorColumn(e.options.ColorColumn)
+	for _, col := range columns {
+		if x := column(col); x >= s.prefix {
+			highlight(x, theme.ColorColumn)
+		}
+	}
+	if e.options.CursorColumn && s.row != e.cursor.Y && cursorX >= s.prefix {
+		highlight(cursorX, theme.CursorLine)
+	}
+	return padded
+}
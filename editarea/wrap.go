@@ -0,0 +1,281 @@
+package editarea
+
+import (
+	"github.com/jamesroutley/fuji/syntax"
+	"github.com/jamesroutley/fuji/theme"
+)
+
+// segment is the part of a row of the text which is shown on one row of the
+// display. start and end are display columns of the row, so tabs count as
+// tabWidth columns.
+type segment struct {
+	row        int
+	start, end int
+	// first is true for the first segment of a row, and false for the
+	// continuations of a wrapped row
+	first bool
+	// prefix is the number of columns drawn before the segment's text, for
+	// the break indicator and indent of continuations
+	prefix int
+}
+
+// textWidth returns the number of columns available to show the text in
+func (e *EditArea) textWidth() int {
+	width := e.displayWidth - e.gutterWidth()
+	if width < 1 {
+		width = 1
+	}
+	return width
+}
+
+// layout splits row into the segments it is displayed in
+func (e *EditArea) layout(row int) []segment {
+	runes := expandTabs([]rune(e.text.Line(row).String()))
+	width := e.textWidth()
+	if !e.options.Wrap {
+		return []segment{{row: row, start: e.leftcol, end: e.leftcol + width, first: true}}
+	}
+
+	prefix := e.breakPrefix(runes, width)
+	var segments []segment
+	start := 0
+	for {
+		s := segment{row: row, start: start, first: start == 0}
+		w := width
+		if !s.first {
+			s.prefix = prefix
+			w -= prefix
+		}
+		if len(runes)-start <= w {
+			s.end = len(runes)
+			return append(segments, s)
+		}
+		s.end = start + w
+		if e.options.LineBreak {
+			// Break after the last space which fits, if there is one
+			for end := s.end; end > start+1; end-- {
+				if runes[end-1] == ' ' {
+					s.end = end
+					break
+				}
+			}
+		}
+		segments = append(segments, s)
+		start = s.end
+	}
+}
+
+// breakPrefix returns the number of columns before the text of the
+// continuation of a wrapped row made of runes
+func (e *EditArea) breakPrefix(runes []rune, width int) int {
+	prefix := len([]rune(e.options.ShowBreak))
+	if e.options.BreakIndent {
+		indent := 0
+		for indent < len(runes) && runes[indent] == ' ' {
+			indent++
+		}
+		prefix += indent
+	}
+	// Leave at least half of the width for the text
+	if prefix > width/2 {
+		prefix = width / 2
+	}
+	return prefix
+}
+
+// expandTabs returns runes as they are displayed, with tabs expanded to
+// spaces
+func expandTabs(runes []rune) []rune {
+	expanded := make([]rune, 0, len(runes))
+	for _, r := range runes {
+		if r != '\t' {
+			expanded = append(expanded, r)
+			continue
+		}
+		for i := 0; i < tabWidth; i++ {
+			expanded = append(expanded, ' ')
+		}
+	}
+	return expanded
+}
+
+// displayRows returns the segments shown on each row of the display
+func (e *EditArea) displayRows() []segment {
+	var rows []segment
+	for row := e.lineno; row < e.text.Length() && len(rows) < e.displayLen; row++ {
+		rows = append(rows, e.layout(row)...)
+	}
+	if len(rows) > e.displayLen {
+		rows = rows[:e.displayLen]
+	}
+	return rows
+}
+
+// segmentContent returns what is drawn for s, given the cells of its row
+func (e *EditArea) segmentContent(s segment, cells []syntax.StyledRune) []syntax.StyledRune {
+	start, end := s.start, s.end
+	if end > len(cells) {
+		end = len(cells)
+	}
+	if start > end {
+		start = end
+	}
+	if s.prefix == 0 {
+		return cells[start:end]
+	}
+	content := make([]syntax.StyledRune, 0, s.prefix+end-start)
+	showBreak := theme.UI(theme.Gutter)
+	for _, r := range e.options.ShowBreak {
+		if len(content) < s.prefix {
+			content = append(content, syntax.StyledRune{Rune: r, Style: showBreak})
+		}
+	}
+	background := syntax.Background()
+	for len(content) < s.prefix {
+		content = append(content, syntax.StyledRune{Rune: ' ', Style: background})
+	}
+	return append(content, cells[start:end]...)
+}
+
+// scrollDownToCursor scrolls down, when lines are wrapped, until the rows
+// from the first displayed row to margin rows past the cursor fit on the
+// display
+func (e *EditArea) scrollDownToCursor(margin int) {
+	last := e.cursor.Y + margin
+	if last >= e.text.Length() {
+		last = e.text.Length() - 1
+	}
+	if e.lineno > e.cursor.Y || e.lineno < 0 {
+		return
+	}
+	heights := make([]int, 0, last-e.lineno+1)
+	total := 0
+	for row := e.lineno; row <= last; row++ {
+		heights = append(heights, len(e.layout(row)))
+		total += heights[len(heights)-1]
+	}
+	for i := 0; total > e.displayLen && e.lineno < e.cursor.Y; i++ {
+		total -= heights[i]
+		e.lineno++
+	}
+}
+
+// scrollSideways scrolls horizontally, when lines aren't wrapped, so that
+// the cursor is at least sidescrolloff columns from the sides of the display
+func (e *EditArea) scrollSideways() {
+	if e.options.Wrap {
+		e.leftcol = 0
+		return
+	}
+	if e.displayWidth == 0 {
+		return
+	}
+	width := e.textWidth()
+	margin := e.sideScrollMargin()
+	col := e.displayColumn(e.cursor.Y, e.cursorColumn())
+	if col-margin < e.leftcol {
+		e.leftcol = col - margin
+	}
+	if col+margin >= e.leftcol+width {
+		e.leftcol = col + margin - width + 1
+	}
+	if e.leftcol < 0 {
+		e.leftcol = 0
+	}
+}
+
+// sideScrollMargin returns the number of columns kept visible either side of
+// the cursor
+func (e *EditArea) sideScrollMargin() int {
+	margin := e.options.SideScrollOff
+	if m := (e.textWidth() - 1) / 2; m < margin {
+		margin = m
+	}
+	return margin
+}
+
+// ScrollRight scrolls the text one column to the right, when lines aren't
+// wrapped, moving the cursor if it would go off the display
+func (e *EditArea) ScrollRight() {
+	if e.options.Wrap {
+		return
+	}
+	e.leftcol++
+	min := e.leftcol + e.sideScrollMargin()
+	if e.displayColumn(e.cursor.Y, e.cursorColumn()) < min {
+		e.cursor.X = e.textColumn(e.cursor.Y, min)
+		e.cursor.X = e.cursorColumn()
+	}
+}
+
+// ScrollLeft scrolls the text one column to the left, when lines aren't
+// wrapped, moving the cursor if it would go off the display
+func (e *EditArea) ScrollLeft() {
+	if e.options.Wrap || e.leftcol == 0 {
+		return
+	}
+	e.leftcol--
+	max := e.leftcol + e.textWidth() - 1 - e.sideScrollMargin()
+	if e.displayColumn(e.cursor.Y, e.cursorColumn()) > max {
+		e.cursor.X = e.textColumn(e.cursor.Y, max)
+	}
+}
+
+// DisplayDown moves the cursor down one row of the display, which is within
+// the same line if the line is wrapped
+func (e *EditArea) DisplayDown() {
+	segments := e.layout(e.cursor.Y)
+	i, offset := e.cursorSegment(segments)
+	if i+1 < len(segments) {
+		e.moveToSegment(segments[i+1], offset)
+		return
+	}
+	if e.cursor.Y >= e.text.Length()-1 {
+		return
+	}
+	e.moveToSegment(e.layout(e.cursor.Y + 1)[0], offset)
+	e.scrollToCursor()
+}
+
+// DisplayUp moves the cursor up one row of the display, which is within the
+// same line if the line is wrapped
+func (e *EditArea) DisplayUp() {
+	segments := e.layout(e.cursor.Y)
+	i, offset := e.cursorSegment(segments)
+	if i > 0 {
+		e.moveToSegment(segments[i-1], offset)
+		return
+	}
+	if e.cursor.Y == 0 {
+		return
+	}
+	above := e.layout(e.cursor.Y - 1)
+	e.moveToSegment(above[len(above)-1], offset)
+	e.scrollToCursor()
+}
+
+// cursorSegment returns the index of the segment the cursor is in, and the
+// column of the display row the cursor is in, counted from the start of the
+// text
+func (e *EditArea) cursorSegment(segments []segment) (i, offset int) {
+	col := e.displayColumn(e.cursor.Y, e.cursorColumn())
+	for i < len(segments)-1 && col >= segments[i].end {
+		i++
+	}
+	return i, col - segments[i].start + segments[i].prefix
+}
+
+// moveToSegment moves the cursor to the column offset of the display row
+// showing s
+func (e *EditArea) moveToSegment(s segment, offset int) {
+	col := s.start + offset - s.prefix
+	if col < s.start {
+		col = s.start
+	}
+	if col >= s.end && s.end > s.start {
+		col = s.end - 1
+	}
+	e.cursor.Y = s.row
+	e.cursor.X = e.textColumn(s.row, col)
+	e.cursor.X = e.cursorColumn()
+}
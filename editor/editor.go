@@ -3,9 +3,11 @@ package editor
 import (
 	"github.com/gdamore/tcell"
 	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/jobs"
 	"github.com/jamesroutley/fuji/logger"
 	"github.com/jamesroutley/fuji/pane"
 	"github.com/jamesroutley/fuji/syntax"
+	"github.com/jamesroutley/fuji/theme"
 )
 
 // Editor implements the main editor
@@ -22,26 +24,70 @@ func (e *Editor) Start(filename string) {
 		panic(err)
 	}
 	defer screen.Fini()
+	screen.EnableMouse()
+	enableBracketedPaste()
+	defer disableBracketedPaste()
+	theme.SetColors(screen.Colors())
+	themeErr := theme.LoadDir(theme.Dir())
+	jobs.SetPost(Post)
+	syntax.SetPost(Post)
 
-	xMax, yMax := screen.Size()
-	// TODO: yMax - 1 because of tmux - find some way to fix this
-	area := area.Area{
-		area.Point{X: 0, Y: 0}, area.Point{X: xMax, Y: yMax - 1},
+	a := screenArea(screen)
+	editpane := pane.NewEditPane(filename, screen, a)
+	if themeErr != nil {
+		logger.L.Print(themeErr)
+		editpane.SetMessage("%s", themeErr)
 	}
-	editpane := pane.NewEditPane(filename, screen, area)
-
-	editpane.Draw()
 
-	for {
-		ev := screen.PollEvent()
+	paste := &pasteDecoder{}
+	handle := func(ev tcell.Event) {
 		switch ev := ev.(type) {
 		case *tcell.EventKey:
-			editpane.HandleEvent(ev)
+			evs, pasted, ok := paste.feed(ev)
+			for _, ev := range evs {
+				if p := topPopup(); p != nil {
+					if p.HandleEvent(ev) {
+						closeTopPopup()
+						editpane.Invalidate()
+					}
+					continue
+				}
+				editpane.HandleEvent(ev)
+			}
+			if ok && topPopup() == nil {
+				editpane.HandlePaste(pasted)
+			}
+		case *tcell.EventMouse:
+			if topPopup() == nil {
+				editpane.HandleMouse(ev)
+			}
+		case *tcell.EventResize:
+			a = screenArea(screen)
+			editpane.Resize(a)
+			screen.Sync()
 		default:
 			// do something
 		}
-		screen.Fill(' ', syntax.Background())
-		editpane.Draw()
+	}
+	draw := func() []area.Area {
+		damage := editpane.Draw()
+		if p := topPopup(); p != nil {
+			p.Draw(screen, a)
+			screen.HideCursor()
+			damage = append(damage, a)
+		}
 		screen.Show()
+		return damage
+	}
+	newLoop(screen, handle, draw).run()
+}
+
+// screenArea returns the area of the screen available to the editor
+func screenArea(screen tcell.Screen) area.Area {
+	xMax, yMax := screen.Size()
+	// TODO: yMax - 1 because of tmux - find some way to fix this
+	return area.Area{
+		Start: area.Point{X: 0, Y: 0},
+		End:   area.Point{X: xMax, Y: yMax - 1},
 	}
 }
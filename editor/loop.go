@@ -0,0 +1,201 @@
+package editor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+)
+
+// frameInterval is the minimum time between two redraws of the screen
+const frameInterval = time.Second / 60
+
+// posted holds the callbacks waiting to be run on the editor's goroutine
+var posted = newQueue()
+
+// Post queues f to be run on the editor's goroutine, between terminal events.
+// It is safe to call from any goroutine, and is the only safe way for
+// background work to change the state of the editor. The screen is redrawn
+// after f has run.
+func Post(f func()) {
+	posted.push(f)
+}
+
+// Redraw asks the editor to redraw the screen
+func Redraw() {
+	Post(func() {})
+}
+
+// AfterFunc waits for d to pass and then runs f on the editor's goroutine.
+// The returned timer can be used to cancel the call.
+func AfterFunc(d time.Duration, f func()) *time.Timer {
+	return time.AfterFunc(d, func() { Post(f) })
+}
+
+// Every runs f on the editor's goroutine every d until stop is called.
+func Every(d time.Duration, f func()) (stop func()) {
+	ticker := time.NewTicker(d)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				Post(f)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// Debounce returns a function which, once it hasn't been called for d, runs f
+// on the editor's goroutine. It's useful for work which should happen when
+// the user stops typing.
+func Debounce(d time.Duration, f func()) func() {
+	var mu sync.Mutex
+	var timer *time.Timer
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = AfterFunc(d, f)
+	}
+}
+
+// Go runs work on a new goroutine. When it finishes, the function it returns,
+// if any, is run on the editor's goroutine. Use it for jobs whose results
+// need to be shown in the UI.
+func Go(work func() func()) {
+	go func() {
+		if f := work(); f != nil {
+			Post(f)
+		}
+	}()
+}
+
+// queue is a goroutine safe, unbounded queue of callbacks
+type queue struct {
+	mu    sync.Mutex
+	funcs []func()
+	// wake receives a value when funcs becomes non-empty
+	wake chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{wake: make(chan struct{}, 1)}
+}
+
+// push adds f to the queue
+func (q *queue) push(f func()) {
+	q.mu.Lock()
+	q.funcs = append(q.funcs, f)
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// drain empties the queue, returning its contents
+func (q *queue) drain() []func() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	funcs := q.funcs
+	q.funcs = nil
+	return funcs
+}
+
+// loop multiplexes terminal events with posted callbacks, and coalesces the
+// redraws they cause into frames
+type loop struct {
+	screen tcell.Screen
+	events chan tcell.Event
+	queue  *queue
+	handle func(tcell.Event)
+	draw   func() []area.Area
+
+	dirty    bool
+	waiting  bool
+	lastDraw time.Time
+}
+
+func newLoop(screen tcell.Screen, handle func(tcell.Event), draw func() []area.Area) *loop {
+	return &loop{
+		screen: screen,
+		events: make(chan tcell.Event, 64),
+		queue:  posted,
+		handle: handle,
+		draw:   draw,
+	}
+}
+
+// run runs the loop until the screen stops producing events
+func (l *loop) run() {
+	go l.poll()
+	l.redraw()
+	for {
+		select {
+		case ev, ok := <-l.events:
+			if !ok {
+				return
+			}
+			l.handle(ev)
+		case <-l.queue.wake:
+			for _, f := range l.queue.drain() {
+				f()
+			}
+		}
+		l.dirty = true
+		l.schedule()
+	}
+}
+
+// poll forwards terminal events to l.events
+func (l *loop) poll() {
+	for {
+		ev := l.screen.PollEvent()
+		if ev == nil {
+			close(l.events)
+			return
+		}
+		l.events <- ev
+	}
+}
+
+// schedule redraws the screen if it is dirty, unless there are more terminal
+// events waiting to be handled or the last frame was drawn too recently, in
+// which case the redraw is put off.
+func (l *loop) schedule() {
+	if !l.dirty || len(l.events) > 0 {
+		return
+	}
+	wait := frameInterval - time.Since(l.lastDraw)
+	if wait <= 0 {
+		l.redraw()
+		return
+	}
+	if !l.waiting {
+		l.waiting = true
+		time.AfterFunc(wait, func() {
+			l.queue.push(func() { l.waiting = false })
+		})
+	}
+}
+
+func (l *loop) redraw() {
+	start := time.Now()
+	damage := l.draw()
+	l.dirty = false
+	l.lastDraw = time.Now()
+	frameStats.record(l.lastDraw.Sub(start), damage)
+}
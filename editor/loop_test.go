@@ -0,0 +1,71 @@
+package editor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+	q := newQueue()
+	var calls []int
+	q.push(func() { calls = append(calls, 1) })
+	q.push(func() { calls = append(calls, 2) })
+	<-q.wake
+	for _, f := range q.drain() {
+		f()
+	}
+	assert.Equal(t, []int{1, 2}, calls)
+	assert.Empty(t, q.drain())
+}
+
+func TestLoopRunsPostedCallbacksAndRedraws(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	draws := make(chan struct{}, 16)
+	l := newLoop(screen, func(tcell.Event) {}, func() []area.Area {
+		draws <- struct{}{}
+		return nil
+	})
+	done := make(chan struct{})
+	go func() {
+		l.run()
+		close(done)
+	}()
+	<-draws
+
+	ran := make(chan struct{})
+	Post(func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("posted callback wasn't run")
+	}
+	select {
+	case <-draws:
+	case <-time.After(time.Second):
+		t.Fatal("screen wasn't redrawn")
+	}
+	screen.Fini()
+	<-done
+}
+
+func TestDebounce(t *testing.T) {
+	calls := 0
+	f := Debounce(10*time.Millisecond, func() { calls++ })
+	for i := 0; i < 5; i++ {
+		f()
+	}
+	time.Sleep(50 * time.Millisecond)
+	for _, f := range posted.drain() {
+		f()
+	}
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,61 @@
+package editor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/logger"
+)
+
+// frameBudget is how long a frame should take to draw. Slower frames are
+// logged.
+const frameBudget = time.Millisecond
+
+// FrameStats records how long frames take to draw
+type FrameStats struct {
+	// Frames is the number of frames drawn
+	Frames int
+	// Last, Mean and Max are the time taken by the last frame, the mean of
+	// recent frames and the slowest frame
+	Last, Mean, Max time.Duration
+	// Cells is the number of cells redrawn by the last frame
+	Cells int
+}
+
+// String returns a summary of the stats
+func (s FrameStats) String() string {
+	return fmt.Sprintf(
+		"%d frames, last %s (%d cells), mean %s, max %s",
+		s.Frames, s.Last, s.Cells, s.Mean, s.Max,
+	)
+}
+
+var frameStats FrameStats
+
+// Frames returns statistics about the frames drawn so far
+func Frames() FrameStats {
+	return frameStats
+}
+
+// record records a frame which took d to draw, and redrew damage
+func (s *FrameStats) record(d time.Duration, damage []area.Area) {
+	s.Frames++
+	s.Last = d
+	if d > s.Max {
+		s.Max = d
+	}
+	// An exponential moving average, so that the mean reflects recent frames
+	if s.Frames == 1 {
+		s.Mean = d
+	} else {
+		s.Mean += (d - s.Mean) / 8
+	}
+	s.Cells = 0
+	for _, a := range damage {
+		s.Cells += (a.End.X - a.Start.X) * (a.End.Y - a.Start.Y)
+	}
+	if d > frameBudget && logger.L != nil {
+		logger.L.Printf("slow frame: %s, %d cells", d, s.Cells)
+	}
+}
@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// tcell doesn't know about bracketed paste, so the start and end markers
+// (ESC[200~ and ESC[201~) reach us as an Alt-[ key event followed by the
+// runes of the rest of the marker.
+const (
+	pasteStart = "200~"
+	pasteEnd   = "201~"
+)
+
+// newlines normalises the line endings of pasted text
+var newlines = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// enableBracketedPaste asks the terminal to wrap pasted text in markers, so
+// that it can be told apart from typed text
+func enableBracketedPaste() {
+	fmt.Fprint(os.Stdout, "\x1b[?2004h")
+}
+
+// disableBracketedPaste turns bracketed paste back off
+func disableBracketedPaste() {
+	fmt.Fprint(os.Stdout, "\x1b[?2004l")
+}
+
+// pasteDecoder picks bracketed pastes out of a stream of key events
+type pasteDecoder struct {
+	// pending holds the events which might be the start of a marker
+	pending []*tcell.EventKey
+	pasting bool
+	pasted  strings.Builder
+}
+
+// feed passes ev to the decoder. It returns the key events which should be
+// handled as normal, and, if ev completes a paste, the pasted text.
+func (d *pasteDecoder) feed(ev *tcell.EventKey) (evs []*tcell.EventKey, paste string, ok bool) {
+	d.pending = append(d.pending, ev)
+
+	marker := pasteStart
+	if d.pasting {
+		marker = pasteEnd
+	}
+	switch {
+	case d.matches(marker) && len(d.pending) == len(marker)+1:
+		d.pending = nil
+		if !d.pasting {
+			d.pasting = true
+			return nil, "", false
+		}
+		d.pasting = false
+		paste = newlines.Replace(d.pasted.String())
+		d.pasted.Reset()
+		return nil, paste, true
+	case d.matches(marker):
+		return nil, "", false
+	}
+
+	evs = d.pending
+	d.pending = nil
+	if !d.pasting {
+		return evs, "", false
+	}
+	for _, ev := range evs {
+		d.pasted.WriteString(pastedText(ev))
+	}
+	return nil, "", false
+}
+
+// matches returns whether the pending events are a prefix of marker
+func (d *pasteDecoder) matches(marker string) bool {
+	first := d.pending[0]
+	if first.Key() != tcell.KeyRune || first.Rune() != '[' || first.Modifiers()&tcell.ModAlt == 0 {
+		return false
+	}
+	rest := d.pending[1:]
+	if len(rest) > len(marker) {
+		return false
+	}
+	for i, ev := range rest {
+		if ev.Key() != tcell.KeyRune || ev.Rune() != rune(marker[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// pastedText returns the text that a key event inside a paste stands for
+func pastedText(ev *tcell.EventKey) string {
+	switch ev.Key() {
+	case tcell.KeyRune:
+		return string(ev.Rune())
+	case tcell.KeyEnter:
+		return "\r"
+	case tcell.KeyLF:
+		return "\n"
+	case tcell.KeyTab:
+		return "\t"
+	default:
+		return ""
+	}
+}
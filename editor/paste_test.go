@@ -0,0 +1,84 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasteDecoder(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		input    []*tcell.EventKey
+		expected string
+		keys     int
+	}{
+		{
+			name:     "typed keys are passed through",
+			input:    runeEvents("ab"),
+			expected: "",
+			keys:     2,
+		},
+		{
+			name: "paste is collected",
+			input: concat(
+				marker(pasteStart),
+				runeEvents("a"),
+				[]*tcell.EventKey{tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)},
+				runeEvents("b"),
+				[]*tcell.EventKey{tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone)},
+				marker(pasteEnd),
+			),
+			expected: "a\nb\t",
+			keys:     0,
+		},
+		{
+			name: "partial marker is passed through",
+			input: concat(
+				[]*tcell.EventKey{tcell.NewEventKey(tcell.KeyRune, '[', tcell.ModAlt)},
+				runeEvents("2x"),
+			),
+			expected: "",
+			keys:     3,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &pasteDecoder{}
+			var pasted string
+			keys := 0
+			for _, ev := range tc.input {
+				evs, paste, ok := d.feed(ev)
+				keys += len(evs)
+				if ok {
+					pasted = paste
+				}
+			}
+			assert.Equal(t, tc.expected, pasted)
+			assert.Equal(t, tc.keys, keys)
+		})
+	}
+}
+
+func runeEvents(s string) []*tcell.EventKey {
+	var evs []*tcell.EventKey
+	for _, r := range s {
+		evs = append(evs, tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	return evs
+}
+
+func marker(m string) []*tcell.EventKey {
+	evs := []*tcell.EventKey{tcell.NewEventKey(tcell.KeyRune, '[', tcell.ModAlt)}
+	return append(evs, runeEvents(m)...)
+}
+
+func concat(evs ...[]*tcell.EventKey) []*tcell.EventKey {
+	var result []*tcell.EventKey
+	for _, e := range evs {
+		result = append(result, e...)
+	}
+	return result
+}
@@ -0,0 +1,26 @@
+package editor
+
+import "github.com/jamesroutley/fuji/pane"
+
+// popups is the stack of open popups. The last popup is drawn on top, and
+// receives key events.
+var popups []pane.Popup
+
+// Open opens the popup p over the edit pane. It must be called on the
+// editor's goroutine.
+func Open(p pane.Popup) {
+	popups = append(popups, p)
+}
+
+// topPopup returns the popup on top of the stack, or nil if none are open
+func topPopup() pane.Popup {
+	if len(popups) == 0 {
+		return nil
+	}
+	return popups[len(popups)-1]
+}
+
+// closeTopPopup closes the popup on top of the stack
+func closeTopPopup() {
+	popups = popups[:len(popups)-1]
+}
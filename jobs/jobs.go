@@ -0,0 +1,222 @@
+// Package jobs runs external processes, such as builds, tests and linters, in
+// the background.
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxOutput is the number of lines of output kept for each job
+const maxOutput = 500
+
+// post runs callbacks on the editor's goroutine. It is set with SetPost.
+var post = func(f func()) { f() }
+
+// SetPost sets the function used to run Handlers. It should run functions on
+// the goroutine which owns the editor's state.
+func SetPost(f func(func())) {
+	post = f
+}
+
+var (
+	mu     sync.Mutex
+	jobs   []*Job
+	nextID = 1
+)
+
+// Handlers receive a job's output and exit status. Any of them may be nil.
+// They are run on the editor's goroutine.
+type Handlers struct {
+	// Stdout is called with each line the job writes to stdout
+	Stdout func(line string)
+	// Stderr is called with each line the job writes to stderr
+	Stderr func(line string)
+	// Exit is called when the job exits, with the error returned by the
+	// process, if any
+	Exit func(err error)
+}
+
+// Job is an external process running in the background
+type Job struct {
+	ID      int
+	Name    string
+	Started time.Time
+
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	running  bool
+	killed   bool
+	err      error
+	finished time.Time
+	output   []string
+}
+
+// Start starts running the command name with arguments args in the
+// background
+func Start(name string, args []string, h Handlers) (*Job, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, name, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	mu.Lock()
+	j := &Job{
+		ID:      nextID,
+		Name:    strings.Join(append([]string{name}, args...), " "),
+		Started: time.Now(),
+		cmd:     cmd,
+		cancel:  cancel,
+		running: true,
+	}
+	nextID++
+	jobs = append(jobs, j)
+	mu.Unlock()
+
+	go j.wait(stdout, stderr, h)
+	return j, nil
+}
+
+// StartLine starts running the command line line, split on whitespace, in
+// the background
+func StartLine(line string, h Handlers) (*Job, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, exec.ErrNotFound
+	}
+	return Start(fields[0], fields[1:], h)
+}
+
+// wait streams the output of j to h, and waits for it to exit
+func (j *Job) wait(stdout, stderr io.Reader, h Handlers) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go j.stream(stdout, h.Stdout, &wg)
+	go j.stream(stderr, h.Stderr, &wg)
+	wg.Wait()
+
+	err := j.cmd.Wait()
+	j.cancel()
+
+	j.mu.Lock()
+	j.running = false
+	j.err = err
+	j.finished = time.Now()
+	j.mu.Unlock()
+
+	post(func() {
+		if h.Exit != nil {
+			h.Exit(err)
+		}
+	})
+}
+
+// stream reads r line by line, recording each line and passing it to handle
+func (j *Job) stream(r io.Reader, handle func(string), wg *sync.WaitGroup) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		j.mu.Lock()
+		j.output = append(j.output, line)
+		if len(j.output) > maxOutput {
+			j.output = j.output[len(j.output)-maxOutput:]
+		}
+		j.mu.Unlock()
+		post(func() {
+			if handle != nil {
+				handle(line)
+			}
+		})
+	}
+}
+
+// Kill kills the job, if it is running
+func (j *Job) Kill() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.running {
+		return
+	}
+	j.killed = true
+	j.cancel()
+}
+
+// Running returns whether the job is still running
+func (j *Job) Running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.running
+}
+
+// Err returns the error the job exited with, if any
+func (j *Job) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Output returns the most recent lines the job has written to stdout and
+// stderr
+func (j *Job) Output() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	output := make([]string, len(j.output))
+	copy(output, j.output)
+	return output
+}
+
+// Status returns a short description of the job's state
+func (j *Job) Status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case j.running:
+		return "running " + time.Since(j.Started).Round(time.Second).String()
+	case j.killed:
+		return "killed"
+	case j.err != nil:
+		return j.err.Error()
+	default:
+		return "done " + j.finished.Sub(j.Started).Round(time.Millisecond).String()
+	}
+}
+
+// List returns all of the jobs that have been started, oldest first
+func List() []*Job {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]*Job, len(jobs))
+	copy(list, jobs)
+	return list
+}
+
+// Running returns the jobs which are still running
+func Running() []*Job {
+	var running []*Job
+	for _, j := range List() {
+		if j.Running() {
+			running = append(running, j)
+		}
+	}
+	return running
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartStreamsOutput(t *testing.T) {
+	t.Parallel()
+	var stdout, stderr []string
+	exited := make(chan error)
+	j, err := Start("sh", []string{"-c", "echo a; echo b; echo c >&2"}, Handlers{
+		Stdout: func(line string) { stdout = append(stdout, line) },
+		Stderr: func(line string) { stderr = append(stderr, line) },
+		Exit:   func(err error) { exited <- err },
+	})
+	assert.NoError(t, err)
+
+	select {
+	case err := <-exited:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("job didn't exit")
+	}
+	assert.Equal(t, []string{"a", "b"}, stdout)
+	assert.Equal(t, []string{"c"}, stderr)
+	assert.False(t, j.Running())
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, j.Output())
+}
+
+func TestKill(t *testing.T) {
+	t.Parallel()
+	exited := make(chan error)
+	j, err := Start("sleep", []string{"10"}, Handlers{
+		Exit: func(err error) { exited <- err },
+	})
+	assert.NoError(t, err)
+	assert.True(t, j.Running())
+	assert.Contains(t, Running(), j)
+
+	j.Kill()
+	select {
+	case err := <-exited:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("job wasn't killed")
+	}
+	assert.Equal(t, "killed", j.Status())
+	assert.NotContains(t, Running(), j)
+}
+
+func TestStartLineWithEmptyLine(t *testing.T) {
+	t.Parallel()
+	_, err := StartLine("  ", Handlers{})
+	assert.Error(t, err)
+}
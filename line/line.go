@@ -18,20 +18,27 @@ type Line struct {
 // New returns an initialised Line
 func New(text string) *Line {
 	length := utf8.RuneCountInString(text)
-	buf := make([]rune, defaultBufferSize)
+	size := defaultBufferSize
+	if length >= size {
+		size = length + defaultBufferSize
+	}
+	buf := make([]rune, size)
 	copy(buf, []rune(text))
 
 	return &Line{
 		buf:   buf,
 		start: length,
-		end:   defaultBufferSize,
-		size:  defaultBufferSize,
+		end:   size,
+		size:  size,
 	}
 }
 
 // Insert inserts rune r at position y
 func (l Line) Insert(r rune, i int) *Line {
 	new := l.moveGap(i)
+	if new.start == new.end {
+		new.growGap()
+	}
 	new.buf[i] = r
 	new.start++
 	return new
@@ -53,11 +60,9 @@ func (l Line) String() string {
 	return string(l.buf[:l.start]) + string(l.buf[l.end:])
 }
 
-// Length returns the length of the gap buffer text
-// TODO: rewrite this to count runes
-// TODO: does this support unicode?
+// Length returns the number of runes in the gap buffer text
 func (l Line) Length() int {
-	return len(l.String())
+	return l.start + l.size - l.end
 }
 
 // Split splits the line at position i
@@ -104,6 +109,17 @@ func (l Line) moveGap(i int) (newl *Line) {
 	return
 }
 
+// growGap makes room for defaultBufferSize more runes in l's gap.
+// It is not immutable, and should not be called directly
+func (l *Line) growGap() {
+	buf := make([]rune, l.size+defaultBufferSize)
+	copy(buf, l.buf[:l.start])
+	copy(buf[l.end+defaultBufferSize:], l.buf[l.end:])
+	l.buf = buf
+	l.end += defaultBufferSize
+	l.size += defaultBufferSize
+}
+
 // moveGapLeft moves l's gap one space left.
 // It is not immutable, and should not be called directly
 func (l *Line) moveGapLeft() {
@@ -23,6 +23,23 @@ func TestString(t *testing.T) {
 	}
 }
 
+func TestLength(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"Hello", 5},
+		{"héllo wörld", 11},
+		{"世界", 2},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, New(tc.text).Length())
+		assert.Equal(t, tc.expected+1, New(tc.text).Insert('x', 0).Length())
+	}
+}
+
 func TestInsert(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -43,6 +60,16 @@ func TestInsert(t *testing.T) {
 	}
 }
 
+func TestInsertGrowsGap(t *testing.T) {
+	t.Parallel()
+	l := New("end")
+	for i := 0; i < defaultBufferSize*2; i++ {
+		l = l.Insert('x', i)
+	}
+	assert.Equal(t, strings.Repeat("x", defaultBufferSize*2)+"end", l.String())
+	assert.Equal(t, defaultBufferSize*2+3, l.Length())
+}
+
 func TestDelete(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -224,3 +251,11 @@ func (l *Line) stringTrue() string {
 	}
 	return s
 }
+
+func TestNewWithLongText(t *testing.T) {
+	t.Parallel()
+	text := strings.Repeat("a", defaultBufferSize*2)
+	l := New(text)
+	assert.Equal(t, text, l.String())
+	assert.Equal(t, "b"+text, l.Insert('b', 0).String())
+}
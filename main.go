@@ -20,7 +20,12 @@ func main() {
 
 	e := editor.Editor{}
 	registerNormalModeCommands()
+	registerVisualModeCommands()
+	registerOperators()
+	registerTextObjects()
+	registerMotions()
 	registerInsertModeCommands()
+	registerExCommands()
 	registerStatuses()
 	e.Start(filename())
 }
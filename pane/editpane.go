@@ -2,6 +2,7 @@ package pane
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/gdamore/tcell"
 	"github.com/jamesroutley/fuji/area"
@@ -15,6 +16,9 @@ type EditPane struct {
 	area      area.Area
 	statusbar *statusbar.StatusBar
 	editarea  *editarea.EditArea
+	// buffers holds the EditArea of each file which has been opened, by
+	// absolute path
+	buffers map[string]*editarea.EditArea
 }
 
 // NewEditPane initialises and returns a new EditPane
@@ -24,32 +28,104 @@ func NewEditPane(filename string, screen tcell.Screen, area area.Area) *EditPane
 		panic("cannot open file: " + filename)
 	}
 	defer file.Close()
-	editarea := editarea.New(screen, filename, file)
+	e := editarea.New(screen, filename, file)
 	statusbar := statusbar.New(screen)
-	return &EditPane{
+	ep := &EditPane{
 		screen:    screen,
 		area:      area,
-		editarea:  editarea,
+		editarea:  e,
 		statusbar: statusbar,
+		buffers:   make(map[string]*editarea.EditArea),
 	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		ep.buffers[abs] = e
+	}
+	editarea.SetOpen(ep.open)
+	editarea.SetBuffers(ep.openBuffers)
+	return ep
+}
+
+// openBuffers returns the EditArea of each file which has been opened
+func (ep *EditPane) openBuffers() []*editarea.EditArea {
+	var open []*editarea.EditArea
+	for _, e := range ep.buffers {
+		open = append(open, e)
+	}
+	return open
+}
+
+// open shows the file filename in the pane, opening it if it hasn't been
+// opened already, and returns its EditArea
+func (ep *EditPane) open(filename string) (*editarea.EditArea, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := ep.buffers[abs]
+	if !ok {
+		file, err := os.Open(abs)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		e = editarea.New(ep.screen, abs, file)
+		ep.buffers[abs] = e
+	}
+	ep.editarea = e
+	ep.Invalidate()
+	return e, nil
 }
 
-// Draw draws the contents of the edit pane
-func (ep *EditPane) Draw() {
-	ep.editarea.Draw(area.Area{
+// Draw draws the parts of the edit pane which have changed since it was
+// last drawn, and returns the areas of the screen that were drawn to
+func (ep *EditPane) Draw() []area.Area {
+	damage := ep.editarea.Draw(area.Area{
 		Start: ep.area.Start,
 		End:   area.Point{X: ep.area.End.X, Y: ep.area.End.Y},
 	})
-	ep.statusbar.Draw(
+	return append(damage, ep.statusbar.Draw(
 		ep.editarea,
 		area.Area{
 			Start: area.Point{X: ep.area.Start.X, Y: ep.area.End.Y},
 			End:   ep.area.End,
 		},
-	)
+	)...)
+}
+
+// Resize moves the edit pane to the area a of the screen
+func (ep *EditPane) Resize(a area.Area) {
+	ep.area = a
+	ep.Invalidate()
+}
+
+// Invalidate forces the whole edit pane to be redrawn by the next call to
+// Draw
+func (ep *EditPane) Invalidate() {
+	ep.editarea.Invalidate()
+	ep.statusbar.Invalidate()
+}
+
+// SetMessage shows a message on the status bar until the next key press
+func (ep *EditPane) SetMessage(format string, a ...interface{}) {
+	ep.editarea.SetMessage(format, a...)
 }
 
 // HandleEvent handles the tcell event ev
 func (ep *EditPane) HandleEvent(ev *tcell.EventKey) {
 	ep.editarea.HandleEvent(ev)
 }
+
+// HandlePaste handles text pasted into the terminal
+func (ep *EditPane) HandlePaste(s string) {
+	ep.editarea.HandlePaste(s)
+}
+
+// HandleMouse handles the mouse event ev. Clicking on the text moves the
+// cursor there.
+func (ep *EditPane) HandleMouse(ev *tcell.EventMouse) {
+	if ev.Buttons()&tcell.Button1 == 0 {
+		return
+	}
+	x, y := ev.Position()
+	ep.editarea.Click(area.Point{X: x, Y: y})
+}
@@ -0,0 +1,99 @@
+package pane
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/jobs"
+	"github.com/jamesroutley/fuji/theme"
+)
+
+// JobsPane lists the background jobs, and lets the user inspect and kill
+// them. j and k select a job, x kills it, and q or ESC closes the pane. The
+// output of the selected job is shown beneath the list.
+type JobsPane struct {
+	selected int
+}
+
+// NewJobsPane initialises and returns a new JobsPane
+func NewJobsPane() *JobsPane {
+	return &JobsPane{selected: len(jobs.List()) - 1}
+}
+
+// Draw draws the list of jobs, and the output of the selected job
+func (p *JobsPane) Draw(screen tcell.Screen, a area.Area) {
+	list := jobs.List()
+	p.clampSelection(list)
+
+	style := theme.UI(theme.Popup)
+	for y := a.Start.Y; y < a.End.Y; y++ {
+		fill(screen, a, y, style)
+	}
+	y := a.Start.Y
+	if len(list) == 0 {
+		drawString(screen, a, a.Start.X+1, y, "No jobs", style)
+		return
+	}
+	for i, j := range list {
+		if y >= a.End.Y {
+			return
+		}
+		lineStyle := style
+		if i == p.selected {
+			lineStyle = style.Reverse(true)
+		}
+		fill(screen, a, y, lineStyle)
+		line := fmt.Sprintf("%3d  %-20s  %s", j.ID, j.Status(), j.Name)
+		drawString(screen, a, a.Start.X+1, y, line, lineStyle)
+		y++
+	}
+
+	y++
+	output := list[p.selected].Output()
+	// Show as much of the end of the output as fits
+	if room := a.End.Y - y; len(output) > room && room >= 0 {
+		output = output[len(output)-room:]
+	}
+	for _, line := range output {
+		if y >= a.End.Y {
+			return
+		}
+		drawString(screen, a, a.Start.X+1, y, line, style.Dim(true))
+		y++
+	}
+}
+
+// HandleEvent handles the tcell event ev
+func (p *JobsPane) HandleEvent(ev *tcell.EventKey) (closed bool) {
+	list := jobs.List()
+	switch {
+	case ev.Key() == tcell.KeyESC:
+		return true
+	case ev.Key() != tcell.KeyRune:
+		return false
+	}
+	switch ev.Rune() {
+	case 'q':
+		return true
+	case 'j':
+		p.selected++
+	case 'k':
+		p.selected--
+	case 'x':
+		if p.selected >= 0 && p.selected < len(list) {
+			list[p.selected].Kill()
+		}
+	}
+	p.clampSelection(list)
+	return false
+}
+
+func (p *JobsPane) clampSelection(list []*jobs.Job) {
+	if p.selected >= len(list) {
+		p.selected = len(list) - 1
+	}
+	if p.selected < 0 {
+		p.selected = 0
+	}
+}
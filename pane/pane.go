@@ -1,6 +1,43 @@
 package pane
 
+import (
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+)
+
 // Pane represents a drawable section of the UI
 type Pane interface {
-	Draw()
+	// Draw draws the parts of the pane which have changed since it was last
+	// drawn, and returns the areas of the screen that were drawn to
+	Draw() []area.Area
+}
+
+// Popup is a pane which is drawn over the edit pane, and takes all key
+// events until it is closed
+type Popup interface {
+	// Draw draws the popup in area a of the screen
+	Draw(screen tcell.Screen, a area.Area)
+	// HandleEvent handles the tcell event ev, and returns whether the popup
+	// should be closed
+	HandleEvent(ev *tcell.EventKey) (closed bool)
+}
+
+// drawString draws s at (x, y), clipped to the area a. It returns the x
+// coordinate after the last rune drawn.
+func drawString(screen tcell.Screen, a area.Area, x, y int, s string, style tcell.Style) int {
+	for _, r := range s {
+		if x >= a.End.X {
+			break
+		}
+		screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+	return x
+}
+
+// fill fills row y of area a with style
+func fill(screen tcell.Screen, a area.Area, y int, style tcell.Style) {
+	for x := a.Start.X; x < a.End.X; x++ {
+		screen.SetContent(x, y, ' ', nil, style)
+	}
 }
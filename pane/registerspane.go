@@ -0,0 +1,102 @@
+package pane
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/area"
+	"github.com/jamesroutley/fuji/editarea"
+	"github.com/jamesroutley/fuji/theme"
+)
+
+// RegistersPane lists the registers which aren't empty, and their contents,
+// as they were when it was opened. j and k scroll the list, and q or ESC
+// closes the pane.
+type RegistersPane struct {
+	lines []string
+	top   int
+}
+
+// NewRegistersPane initialises and returns a new RegistersPane, listing the
+// registers as they are seen from e
+func NewRegistersPane(e *editarea.EditArea) *RegistersPane {
+	return &RegistersPane{lines: registerLines(e)}
+}
+
+// registerLines returns a line describing each register which isn't empty.
+// The clipboard registers are read once, since reading them may run
+// external commands.
+func registerLines(e *editarea.EditArea) []string {
+	var lines []string
+	for _, name := range editarea.RegisterNames {
+		r, ok := e.Register(name)
+		if !ok {
+			continue
+		}
+		kind := "c"
+		switch {
+		case r.Linewise:
+			kind = "l"
+		case r.Blockwise:
+			kind = "b"
+		}
+		text := strings.Replace(r.Text, "\n", "^J", -1)
+		text = strings.Replace(text, "\t", "^I", -1)
+		lines = append(lines, fmt.Sprintf("  %s  \"%c   %s", kind, name, text))
+	}
+	return lines
+}
+
+// Draw draws the list of registers
+func (p *RegistersPane) Draw(screen tcell.Screen, a area.Area) {
+	style := theme.UI(theme.Popup)
+	for y := a.Start.Y; y < a.End.Y; y++ {
+		fill(screen, a, y, style)
+	}
+	lines := p.lines
+	p.clampTop()
+	y := a.Start.Y
+	drawString(screen, a, a.Start.X+1, y, "Type Name Content", style.Bold(true))
+	y++
+	if len(lines) == 0 {
+		drawString(screen, a, a.Start.X+1, y, "No registers", style)
+		return
+	}
+	for _, line := range lines[p.top:] {
+		if y >= a.End.Y {
+			return
+		}
+		drawString(screen, a, a.Start.X+1, y, line, style)
+		y++
+	}
+}
+
+// HandleEvent handles the tcell event ev
+func (p *RegistersPane) HandleEvent(ev *tcell.EventKey) (closed bool) {
+	switch {
+	case ev.Key() == tcell.KeyESC:
+		return true
+	case ev.Key() != tcell.KeyRune:
+		return false
+	}
+	switch ev.Rune() {
+	case 'q':
+		return true
+	case 'j':
+		p.top++
+	case 'k':
+		p.top--
+	}
+	p.clampTop()
+	return false
+}
+
+func (p *RegistersPane) clampTop() {
+	if p.top >= len(p.lines) {
+		p.top = len(p.lines) - 1
+	}
+	if p.top < 0 {
+		p.top = 0
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 func registerStatuses() {
 	statusbar.AddStatus(status.Mode)
+	statusbar.AddStatus(status.Recording)
 	statusbar.AddStatus(status.Filename)
 	statusbar.AddStatus(status.GitBranch)
+	statusbar.AddStatus(status.Jobs)
 }
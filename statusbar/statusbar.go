@@ -2,10 +2,12 @@ package statusbar
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell"
 	"github.com/jamesroutley/fuji/area"
 	"github.com/jamesroutley/fuji/editarea"
+	"github.com/jamesroutley/fuji/theme"
 )
 
 var statuses []func(*editarea.EditArea) string
@@ -19,6 +21,11 @@ func AddStatus(status func(*editarea.EditArea) string) {
 // The status bar is intended for information relevant to an edit area.
 type StatusBar struct {
 	screen tcell.Screen
+	// drawn is the content last drawn, drawnArea where it was drawn, and
+	// drawnTheme the version of the theme it was drawn in
+	drawn      string
+	drawnArea  area.Area
+	drawnTheme int
 }
 
 // New initialises and returns a new StatusBar
@@ -26,29 +33,65 @@ func New(screen tcell.Screen) *StatusBar {
 	return &StatusBar{screen: screen}
 }
 
-// Draw draws the status bar
-func (s *StatusBar) Draw(e *editarea.EditArea, area area.Area) {
+// Invalidate forces the status bar to be redrawn by the next call to Draw
+func (s *StatusBar) Invalidate() {
+	s.drawnArea = area.Area{}
+}
+
+// Draw draws the status bar if its content has changed, and returns the
+// areas of the screen that were drawn to
+func (s *StatusBar) Draw(e *editarea.EditArea, a area.Area) []area.Area {
 	statuses := getStatuses(e)
 	content := strings.Join(statuses, " / ")
+	if msg := e.Message(); msg != "" {
+		content = msg
+	}
+	if e.Mode == editarea.ModeCommand {
+		content = ":" + e.CommandLine()
+	}
+
+	if content == s.drawn && a == s.drawnArea && theme.Version() == s.drawnTheme {
+		s.showCursor(e, a)
+		return nil
+	}
+	s.drawn = content
+	s.drawnArea = a
+	s.drawnTheme = theme.Version()
 
-	style := tcell.StyleDefault.
-		Background(tcell.ColorDarkGray).
-		Foreground(tcell.ColorBlack)
+	style := theme.UI(theme.StatusBar)
 
-	for x := area.Start.X; x < area.End.X; x++ {
-		s.screen.SetContent(x, area.Start.Y, ' ', nil, style)
+	for x := a.Start.X; x < a.End.X; x++ {
+		s.screen.SetContent(x, a.Start.Y, ' ', nil, style)
 	}
 
 	offset := 1
-	for x, r := range content {
-		s.screen.SetContent(x+offset, area.Start.Y, r, nil, style)
+	x := a.Start.X + offset
+	for _, r := range content {
+		s.screen.SetContent(x, a.Start.Y, r, nil, style)
+		x++
 	}
+	s.showCursor(e, a)
+	return []area.Area{a}
+}
+
+// showCursor shows the cursor at the end of the command line, when one is
+// being typed
+func (s *StatusBar) showCursor(e *editarea.EditArea, a area.Area) {
+	if e.Mode != editarea.ModeCommand {
+		return
+	}
+	offset := 1
+	x := a.Start.X + offset + utf8.RuneCountInString(s.drawn)
+	s.screen.ShowCursor(x, a.Start.Y)
 }
 
+// getStatuses returns the non-empty statuses
 func getStatuses(e *editarea.EditArea) []string {
-	content := make([]string, len(statuses))
-	for i, status := range statuses {
-		content[i] = status(e)
+	var content []string
+	for _, status := range statuses {
+		if s := status(e); s != "" {
+			content = append(content, s)
+		}
 	}
 	return content
 }
@@ -1,12 +1,29 @@
 package status
 
 import (
+	"fmt"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/jamesroutley/fuji/editarea"
+	"github.com/jamesroutley/fuji/editor"
+	"github.com/jamesroutley/fuji/jobs"
 )
 
+// branchInterval is how long the git branch is cached for before it is
+// looked up again
+const branchInterval = 5 * time.Second
+
+// branch caches the current git branch. It is only used on the editor's
+// goroutine.
+var branch struct {
+	name       string
+	refreshed  time.Time
+	refreshing bool
+}
+
 // Mode returns the editor's mode
 func Mode(e *editarea.EditArea) string {
 	switch e.Mode {
@@ -14,11 +31,25 @@ func Mode(e *editarea.EditArea) string {
 		return "Normal"
 	case editarea.ModeInsert:
 		return "Insert"
+	case editarea.ModeCommand:
+		return "Command"
+	case editarea.ModeVisual:
+		return "Visual"
+	case editarea.ModeReplace:
+		return "Replace"
 	default:
 		return "Error: unimplemented"
 	}
 }
 
+// Recording shows the register keys are being recorded into, if any
+func Recording(e *editarea.EditArea) string {
+	if e.Recording() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("recording @%c", e.Recording())
+}
+
 // Filename return the name of the file being edited
 func Filename(e *editarea.EditArea) string {
 	_, fn := filepath.Split(e.Filename)
@@ -28,11 +59,35 @@ func Filename(e *editarea.EditArea) string {
 	return fn + " *"
 }
 
-// GitBranch returns the current git branch
+// GitBranch returns the current git branch. Running git is too slow to do
+// on every redraw, so the branch is looked up in the background, at most
+// once every branchInterval, and the last branch found is returned.
 func GitBranch(e *editarea.EditArea) string {
-	cmdOut, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
-	if err != nil {
+	if !branch.refreshing && time.Since(branch.refreshed) >= branchInterval {
+		branch.refreshing = true
+		editor.Go(func() func() {
+			name := ""
+			if out, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
+				name = strings.TrimSpace(string(out))
+			}
+			return func() {
+				branch.name = name
+				branch.refreshed = time.Now()
+				branch.refreshing = false
+			}
+		})
+	}
+	return branch.name
+}
+
+// Jobs returns the number of background jobs which are running
+func Jobs(e *editarea.EditArea) string {
+	running := len(jobs.Running())
+	switch running {
+	case 0:
 		return ""
+	case 1:
+		return "1 job"
 	}
-	return string(cmdOut)
+	return fmt.Sprintf("%d jobs", running)
 }
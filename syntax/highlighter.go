@@ -0,0 +1,310 @@
+package syntax
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/jamesroutley/fuji/line"
+	"github.com/jamesroutley/fuji/text"
+	"github.com/jamesroutley/fuji/theme"
+)
+
+const (
+	// margin is the number of lines tokenised past the last line asked for,
+	// so that tokens which span lines are lexed correctly
+	margin = 200
+	// lookback is the number of lines before the first changed line from
+	// which tokenising is restarted. chroma lexes multi-line comments and
+	// strings with a single regular expression, so an edit can change how a
+	// line before it is lexed, for example by closing a comment.
+	lookback = 50
+	// backgroundThreshold is the number of lines which would need to be
+	// tokenised to highlight the lines asked for, above which the text is
+	// tokenised in the background instead
+	backgroundThreshold = 5000
+)
+
+// post runs callbacks on the editor's goroutine. It is set with SetPost.
+var post = func(f func()) { f() }
+
+// SetPost sets the function used to deliver the results of background
+// highlighting. It should run functions on the goroutine which owns the
+// editor's state.
+func SetPost(f func(func())) {
+	post = f
+}
+
+// Highlighter highlights a text, caching the tokens of each line so that only
+// lines which have changed need to be tokenised again.
+//
+// chroma doesn't expose the state of its lexers, so the state at the start of
+// a line is taken to be the type of the token which ended the line before. A
+// line whose state is neither a comment nor a string is assumed to start in
+// the lexer's root state, and tokenising can be restarted from it.
+//
+// A Highlighter is not safe for concurrent use.
+type Highlighter struct {
+	lexer chroma.Lexer
+	lines []cachedLine
+	// valid is the number of lines at the start of lines which are known to
+	// be up to date
+	valid int
+	// version is incremented whenever the cached styles change
+	version int
+	// background is true while the text is being tokenised in the background
+	background bool
+	// theme is the version of the theme the cached styles came from
+	theme int
+}
+
+// cachedLine is the result of tokenising a line
+type cachedLine struct {
+	line  *line.Line
+	runes []StyledRune
+	// start is the state the line was tokenised in, and end the state it
+	// left the lexer in
+	start, end chroma.TokenType
+	// computed is false for lines which have never been tokenised
+	computed bool
+}
+
+// NewHighlighter returns a Highlighter for the file filename
+func NewHighlighter(filename string) *Highlighter {
+	lexer := lexers.Match(filename)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	return &Highlighter{lexer: chroma.Coalesce(lexer)}
+}
+
+// Version returns a number which changes whenever the styles returned by
+// Lines might have changed without the text changing, for example when
+// background highlighting finishes
+func (h *Highlighter) Version() int {
+	return h.version
+}
+
+// Lines returns the styled runes of rows [start, end) of t. Only lines which
+// have changed since the last call, and lines after them whose state has
+// changed, are tokenised again. Lines which can't be highlighted yet are
+// returned unstyled.
+func (h *Highlighter) Lines(t *text.Text, start, end int) [][]StyledRune {
+	if end > t.Length() {
+		end = t.Length()
+	}
+	h.update(t)
+	if end > h.valid {
+		h.tokenise(t, end)
+	}
+
+	result := make([][]StyledRune, 0, end-start)
+	for row := start; row < end; row++ {
+		if row < h.valid {
+			result = append(result, h.lines[row].runes)
+		} else {
+			result = append(result, plain(t.Line(row).String()))
+		}
+	}
+	return result
+}
+
+// update lines up the cache with t. Lines which have changed are marked as
+// not computed, and the valid prefix is cut back to the first of them.
+func (h *Highlighter) update(t *text.Text) {
+	if h.theme != theme.Version() {
+		// The cached styles are out of date, so start again
+		h.lines = nil
+		h.valid = 0
+		h.theme = theme.Version()
+	}
+	old := h.lines
+	n := t.Length()
+
+	// first is the first row which has changed
+	first := 0
+	for first < len(old) && first < n && old[first].line == t.Line(first) {
+		first++
+	}
+	if first == len(old) && first == n {
+		return
+	}
+	// suffix is the number of rows at the end which haven't changed
+	suffix := 0
+	for suffix < len(old)-first && suffix < n-first &&
+		old[len(old)-1-suffix].line == t.Line(n-1-suffix) {
+		suffix++
+	}
+
+	lines := make([]cachedLine, n)
+	copy(lines, old[:first])
+	for row := first; row < n-suffix; row++ {
+		lines[row] = cachedLine{line: t.Line(row)}
+	}
+	copy(lines[n-suffix:], old[len(old)-suffix:])
+	h.lines = lines
+	if first < h.valid {
+		h.valid = first
+	}
+}
+
+// tokenise extends the valid prefix of the cache to at least end rows, or
+// starts doing so in the background if that would mean tokenising too much
+func (h *Highlighter) tokenise(t *text.Text, end int) {
+	from := h.restartRow(h.valid - lookback)
+	if end-from > backgroundThreshold {
+		h.tokeniseInBackground(t)
+		return
+	}
+
+	// The lexer is given some lines past end, so that tokens which span
+	// lines are lexed correctly, but only lines up to end are cached
+	stop := end + margin
+	if stop > t.Length() {
+		stop = t.Length()
+	}
+	start := chroma.Text
+	if from > 0 {
+		start = h.lines[from-1].end
+	}
+	row := from
+	converged := false
+	err := tokeniseLines(h.lexer, source(t, from, stop), func(runes []StyledRune, state chroma.TokenType) bool {
+		old := h.lines[row]
+		if row >= h.valid && old.computed && old.start == start && old.line == t.Line(row) {
+			// The state has converged with what was cached, so the rest of
+			// the computed lines are still correct
+			converged = true
+			return false
+		}
+		h.lines[row] = cachedLine{line: t.Line(row), runes: runes, start: start, end: state, computed: true}
+		start = state
+		row++
+		return row < end
+	})
+	if err != nil {
+		// Fall back to plain text
+		for ; row < end; row++ {
+			h.lines[row] = cachedLine{
+				line: t.Line(row), runes: plain(t.Line(row).String()),
+				start: chroma.Text, end: chroma.Text, computed: true,
+			}
+		}
+	}
+	h.valid = row
+	if converged {
+		h.extendValid(row)
+	}
+	h.version++
+}
+
+// extendValid extends the valid prefix over the computed lines from row on
+func (h *Highlighter) extendValid(row int) {
+	for row < len(h.lines) && h.lines[row].computed {
+		row++
+	}
+	h.valid = row
+}
+
+// restartRow returns the last row before or at row from which tokenising can
+// be restarted
+func (h *Highlighter) restartRow(row int) int {
+	if row > h.valid {
+		row = h.valid
+	}
+	for ; row > 0; row-- {
+		if neutral(h.lines[row-1].end) {
+			return row
+		}
+	}
+	return 0
+}
+
+// tokeniseInBackground tokenises the whole of t on another goroutine, and
+// adopts the result when it's done
+func (h *Highlighter) tokeniseInBackground(t *text.Text) {
+	if h.background {
+		return
+	}
+	h.background = true
+	lexer := h.lexer
+	go func() {
+		n := t.Length()
+		lines := make([]cachedLine, 0, n)
+		start := chroma.Text
+		err := tokeniseLines(lexer, source(t, 0, n), func(runes []StyledRune, state chroma.TokenType) bool {
+			row := len(lines)
+			lines = append(lines, cachedLine{line: t.Line(row), runes: runes, start: start, end: state, computed: true})
+			start = state
+			return len(lines) < n
+		})
+		post(func() {
+			h.background = false
+			if err != nil || len(lines) != n || h.theme != theme.Version() {
+				return
+			}
+			// The text may have been edited since, so line the result up with
+			// the current text the next time Lines is called
+			h.lines = lines
+			h.valid = n
+			h.version++
+		})
+	}()
+}
+
+// tokeniseLines tokenises src with lexer, calling each with the styled runes
+// of each line and the type of the token which ended it, until each returns
+// false. Panics in the lexer are returned as errors.
+func tokeniseLines(lexer chroma.Lexer, src string, each func([]StyledRune, chroma.TokenType) bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("syntax: lexer panicked: %v", r)
+		}
+	}()
+	iterator, err := lexer.Tokenise(nil, src)
+	if err != nil {
+		return err
+	}
+	var current []StyledRune
+	for token := iterator(); token != nil; token = iterator() {
+		s := tcellStyle(token.Type)
+		for _, r := range token.Value {
+			if r != '\n' {
+				current = append(current, StyledRune{Rune: r, Style: s, Type: token.Type})
+				continue
+			}
+			if !each(current, token.Type) {
+				return nil
+			}
+			current = nil
+		}
+	}
+	return nil
+}
+
+// source returns rows [from, to) of t, each terminated by a newline
+func source(t *text.Text, from, to int) string {
+	var b strings.Builder
+	for row := from; row < to; row++ {
+		b.WriteString(t.Line(row).String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// neutral returns whether a line following a token of type t starts in the
+// lexer's root state
+func neutral(t chroma.TokenType) bool {
+	return StyledRune{Type: t}.IsCode()
+}
+
+// plain returns s as unhighlighted styled runes
+func plain(s string) []StyledRune {
+	runes := make([]StyledRune, 0, len(s))
+	style := tcellStyle(chroma.Text)
+	for _, r := range s {
+		runes = append(runes, StyledRune{Rune: r, Style: style, Type: chroma.Text})
+	}
+	return runes
+}
@@ -0,0 +1,74 @@
+package syntax
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+	"github.com/jamesroutley/fuji/text"
+	"github.com/stretchr/testify/assert"
+)
+
+const goSource = `package main
+
+// main is the entry point
+func main() {
+	s := "hello"
+	fmt.Println(s)
+}
+`
+
+func TestHighlighterMatchesHighlight(t *testing.T) {
+	t.Parallel()
+	h := NewHighlighter("main.go")
+	txt := text.New(bytes.NewBufferString(goSource))
+	expected := Highlight("main.go", txt.String())
+	assert.Equal(t, expected, h.Lines(txt, 0, txt.Length()))
+	assert.Equal(t, expected[2:4], h.Lines(txt, 2, 4))
+}
+
+func TestHighlighterRetokenisesEditedLines(t *testing.T) {
+	t.Parallel()
+	h := NewHighlighter("main.go")
+	txt := text.New(bytes.NewBufferString(goSource))
+	h.Lines(txt, 0, txt.Length())
+
+	// Opening a comment changes the highlighting of every line after it
+	txt = txt.InsertString(3, 0, "/*")
+	assert.Equal(t, Highlight("main.go", txt.String()), h.Lines(txt, 0, txt.Length()))
+
+	// Closing it again should put the old highlighting back
+	txt = txt.InsertString(4, 0, "*/")
+	assert.Equal(t, Highlight("main.go", txt.String()), h.Lines(txt, 0, txt.Length()))
+
+	txt = txt.DeleteLine(1)
+	assert.Equal(t, Highlight("main.go", txt.String()), h.Lines(txt, 0, txt.Length()))
+}
+
+func TestHighlighterOnlyTokenisesWhatIsAsked(t *testing.T) {
+	t.Parallel()
+	h := NewHighlighter("main.go")
+	txt := text.New(bytes.NewBufferString(goSource))
+	h.Lines(txt, 0, 2)
+	assert.Equal(t, 2, h.valid)
+
+	version := h.Version()
+	h.Lines(txt, 0, 2)
+	assert.Equal(t, version, h.Version())
+}
+
+func TestTokeniseLinesRecoversFromPanics(t *testing.T) {
+	t.Parallel()
+	err := tokeniseLines(panickingLexer{}, "a\n", func([]StyledRune, chroma.TokenType) bool {
+		return true
+	})
+	assert.Error(t, err)
+}
+
+type panickingLexer struct{}
+
+func (panickingLexer) Config() *chroma.Config { return &chroma.Config{} }
+
+func (panickingLexer) Tokenise(*chroma.TokeniseOptions, string) (chroma.Iterator, error) {
+	return func() *chroma.Token { panic("oops") }, nil
+}
@@ -1,50 +1,58 @@
 package syntax
 
 import (
+	"strings"
+
 	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/lexers"
-	"github.com/alecthomas/chroma/styles"
 	"github.com/gdamore/tcell"
+	"github.com/jamesroutley/fuji/theme"
 )
 
-var style = styles.Get("dracula")
-
 // StyledRune reprenents a rune and its syntax highlighting style
 type StyledRune struct {
 	Rune  rune
 	Style tcell.Style
+	// Type is the type of the token the rune is part of
+	Type chroma.TokenType
+}
+
+// IsCode returns whether the rune is part of the code, rather than a comment
+// or a string
+func (sr StyledRune) IsCode() bool {
+	return !sr.Type.InCategory(chroma.Comment) && !sr.Type.InSubCategory(chroma.LiteralString)
+}
+
+// InLineComment returns whether the rune is part of a comment which runs to
+// the end of the line
+func (sr StyledRune) InLineComment() bool {
+	return sr.Type.InCategory(chroma.Comment) && sr.Type != chroma.CommentMultiline
 }
 
 // Background returns the background style
 func Background() tcell.Style {
-	styleEntry := style.Get(chroma.Background)
-	return chromaStyleToTcellStyle(styleEntry)
+	return theme.Background()
 }
 
-// Highlight highlights a string
+// Highlight highlights a string. If the string can't be tokenised, it is
+// returned unhighlighted.
 func Highlight(filename, text string) [][]StyledRune {
 	lexer := lexers.Match(filename)
 	if lexer == nil {
 		lexer = lexers.Fallback
 	}
-	style := styles.Get("dracula")
-	if style == nil {
-		style = styles.Fallback
-	}
-	iterator, err := lexer.Tokenise(nil, text)
+	var lines [][]StyledRune
+	err := tokeniseLines(lexer, text+"\n", func(runes []StyledRune, _ chroma.TokenType) bool {
+		lines = append(lines, runes)
+		return true
+	})
 	if err != nil {
-		// TODO: this probably shouldn't panic - maybe return a
-		// non-highlighted string?
-		panic(err)
-	}
-
-	tokens := iterator.Tokens()
-	var styledRunes []StyledRune
-	for _, token := range tokens {
-		styledRunes = append(styledRunes, tokenToStyledRunes(token, style)...)
+		lines = nil
+		for _, l := range strings.Split(text, "\n") {
+			lines = append(lines, plain(l))
+		}
 	}
-
-	return split(styledRunes, '\n')
+	return lines
 }
 
 func split(styledRunes []StyledRune, sep rune) [][]StyledRune {
@@ -63,32 +71,48 @@ func split(styledRunes []StyledRune, sep rune) [][]StyledRune {
 	return result
 }
 
-func tokenToStyledRunes(t *chroma.Token, s *chroma.Style) []StyledRune {
-	styleEntry := s.Get(t.Type)
-	style := chromaStyleToTcellStyle(styleEntry)
-	// hacky
-	// TODO: getting the len of t.Value may not work for unicode
-	runes := make([]StyledRune, len(t.Value))
-	for i, r := range t.Value {
-		runes[i] = StyledRune{
-			Rune:  r,
-			Style: style,
-		}
-	}
-	return runes
+// tcellStyle returns the style tokens of type t are drawn in
+func tcellStyle(t chroma.TokenType) tcell.Style {
+	return theme.Token(t)
 }
 
-func chromaStyleToTcellStyle(se chroma.StyleEntry) (s tcell.Style) {
-	s = tcell.StyleDefault
-	s = s.Background(chromaToTcellColour(se.Background))
-	s = s.Foreground(chromaToTcellColour(se.Colour))
-	return
-}
+// lineCommentMarkers and blockCommentMarkers are the comment markers
+// CommentMarkers tries, in order
+var (
+	lineCommentMarkers  = []string{"//", "#", "--", ";", "%", "\"", "'"}
+	blockCommentMarkers = [][2]string{{"/*", "*/"}, {"<!--", "-->"}, {"(*", "*)"}, {"{-", "-}"}}
+)
 
-func chromaToTcellColour(c chroma.Colour) tcell.Color {
-	return tcell.NewRGBColor(
-		int32(c.Red()),
-		int32(c.Green()),
-		int32(c.Blue()),
-	)
+// CommentMarkers returns the markers which comment out a line in the
+// language of filename, found by asking its lexer which of the common
+// markers start comments. If the language has line comments, start is the
+// marker and end is empty, and otherwise they start and end a block comment.
+// ok is false if the language isn't known, or none of the markers work.
+func CommentMarkers(filename string) (start, end string, ok bool) {
+	if lexers.Match(filename) == nil {
+		return "", "", false
+	}
+	comment := func(s string) bool {
+		lines := Highlight(filename, s)
+		if len(lines) == 0 || len(lines[0]) == 0 {
+			return false
+		}
+		for _, sr := range lines[0] {
+			if !sr.Type.InCategory(chroma.Comment) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, marker := range lineCommentMarkers {
+		if comment(marker + " x") {
+			return marker, "", true
+		}
+	}
+	for _, markers := range blockCommentMarkers {
+		if comment(markers[0] + " x " + markers[1]) {
+			return markers[0], markers[1], true
+		}
+	}
+	return "", "", false
 }
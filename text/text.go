@@ -101,13 +101,16 @@ func (t Text) Insert(row, col int, r rune) *Text {
 // Delete deletes the rune at (row, col)
 func (t Text) Delete(row, col int) *Text {
 	new := t.duplicate()
-	new.buf[new.realIndex(row)] = new.buf[row].Delete(col)
+	new.buf[new.realIndex(row)] = new.Line(row).Delete(col)
 	return new
 }
 
 // InsertLine inserts l at col
 func (t Text) InsertLine(row int, l *line.Line) *Text {
 	new := t.moveGap(row)
+	if new.start == new.end {
+		new.growGap()
+	}
 	new.buf[row] = l
 	new.start++
 	return new
@@ -132,6 +135,25 @@ func (t Text) SplitLine(row, col int) *Text {
 	return new
 }
 
+// InsertString inserts the string s at (row, col). s may span multiple lines,
+// in which case the text after col is moved to the end of the last line of s.
+func (t Text) InsertString(row, col int, s string) *Text {
+	before, after := t.Line(row).Split(col)
+	lines := strings.Split(s, "\n")
+	last := len(lines) - 1
+
+	new := t.duplicate()
+	new.buf[new.realIndex(row)] = before.Append(line.New(lines[0]))
+	if last == 0 {
+		new.buf[new.realIndex(row)] = new.Line(row).Append(after)
+		return new
+	}
+	for i := 1; i < last; i++ {
+		new = new.InsertLine(row+i, line.New(lines[i]))
+	}
+	return new.InsertLine(row+last, line.New(lines[last]).Append(after))
+}
+
 // AppendLine appends line l to the line at (row)
 func (t Text) AppendLine(row int, l *line.Line) *Text {
 	new := t.duplicate()
@@ -169,6 +191,17 @@ func (t Text) moveGap(i int) (new *Text) {
 	return
 }
 
+// growGap makes room for defaultGapSize more lines in t's gap.
+// It is not immutable, and should not be called directly
+func (t *Text) growGap() {
+	buf := make([]*line.Line, t.size+defaultGapSize)
+	copy(buf, t.buf[:t.start])
+	copy(buf[t.end+defaultGapSize:], t.buf[t.end:])
+	t.buf = buf
+	t.end += defaultGapSize
+	t.size += defaultGapSize
+}
+
 func (t *Text) moveGapLeft() {
 	if t.start == 0 {
 		return
@@ -89,6 +89,18 @@ world`
 	assert.Equal(t, "lo\nworld", text.String())
 }
 
+func TestDeleteAfterInsertLine(t *testing.T) {
+	t.Parallel()
+	source := `aaa
+bbb
+ccc
+ddd`
+	text := newTextFromString(source)
+	text = text.InsertLine(0, line.New("new"))
+	text = text.Delete(3, 0)
+	assert.Equal(t, "new\naaa\nbbb\ncc\nddd", text.String())
+}
+
 func TestInsertLine(t *testing.T) {
 	t.Parallel()
 	source := `hello
@@ -121,6 +133,40 @@ llo`
 	assert.Equal(t, expected, text.String())
 }
 
+func TestInsertString(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		source   string
+		row, col int
+		s        string
+		expected string
+	}{
+		{"hello\nworld", 0, 2, "XY", "heXYllo\nworld"},
+		{"hello\nworld", 0, 2, "X\nY", "heX\nYllo\nworld"},
+		{"hello\nworld", 1, 5, "\na\nb\n", "hello\nworld\na\nb\n"},
+		{"", 0, 0, "a\nb", "a\nb"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.s, func(t *testing.T) {
+			text := newTextFromString(tc.source)
+			text = text.InsertString(tc.row, tc.col, tc.s)
+			assert.Equal(t, tc.expected, text.String())
+		})
+	}
+}
+
+func TestInsertLineGrowsGap(t *testing.T) {
+	t.Parallel()
+	text := newTextFromString("end")
+	var lines []string
+	for i := 0; i < defaultGapSize*2; i++ {
+		text = text.InsertLine(i, line.New("x"))
+		lines = append(lines, "x")
+	}
+	lines = append(lines, "end")
+	assert.Equal(t, strings.Join(lines, "\n"), text.String())
+}
+
 func TestLength(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -0,0 +1,139 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+)
+
+// themeFile is the format of a user's theme file, for example:
+//
+//	{
+//		"name": "midnight",
+//		"base": "monokai",
+//		"syntax": {"Keyword": "bold #ff79c6", "Comment": "#6272a4"},
+//		"ui": {"statusbar": "bg:#44475a #f8f8f2"}
+//	}
+//
+// base is an optional chroma style to start from. Entries use chroma's style
+// entry syntax, and syntax entries are keyed by chroma's token type names.
+type themeFile struct {
+	Name   string            `json:"name"`
+	Base   string            `json:"base"`
+	Syntax map[string]string `json:"syntax"`
+	UI     map[string]string `json:"ui"`
+}
+
+// tokenTypes maps the names of chroma's token types to the types
+var tokenTypes = func() map[string]chroma.TokenType {
+	types := make(map[string]chroma.TokenType)
+	for t := range chroma.StandardTypes {
+		types[t.String()] = t
+	}
+	return types
+}()
+
+// Load reads the theme file at path
+func Load(path string) (*Theme, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var tf themeFile
+	if err := json.NewDecoder(f).Decode(&tf); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	t, err := tf.theme()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+// LoadDir loads and registers every theme file in dir. A missing directory
+// isn't an error. Files which can't be loaded are skipped, and reported in
+// the error returned once the rest have been registered.
+func LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, path := range paths {
+		t, err := Load(path)
+		if err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		Register(t)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("cannot load themes: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Dir returns the directory the user's theme files are kept in
+func Dir() string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(config, "fuji", "themes")
+}
+
+func (tf themeFile) theme() (*Theme, error) {
+	if tf.Name == "" {
+		return nil, fmt.Errorf("theme has no name")
+	}
+	base := styles.Fallback
+	if tf.Base != "" {
+		var ok bool
+		if base, ok = styles.Registry[tf.Base]; !ok {
+			return nil, fmt.Errorf("unknown base style: %s", tf.Base)
+		}
+	}
+
+	builder := base.Builder()
+	for name, entry := range tf.Syntax {
+		t, ok := tokenTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown token type: %s", name)
+		}
+		builder.Add(t, entry)
+	}
+	syntax, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	syntax.Name = tf.Name
+
+	t := &Theme{Name: tf.Name, Syntax: syntax, UI: make(map[Element]chroma.StyleEntry)}
+	for name, entry := range tf.UI {
+		element := Element(name)
+		if !isElement(element) {
+			return nil, fmt.Errorf("unknown UI element: %s", name)
+		}
+		se, err := chroma.ParseStyleEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		t.UI[element] = se
+	}
+	return t, nil
+}
+
+func isElement(element Element) bool {
+	for _, e := range Elements {
+		if e == element {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+// Package theme implements fuji's colour schemes. A theme styles both the
+// syntax highlighted text and the rest of the editor's UI.
+package theme
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/gdamore/tcell"
+)
+
+// DefaultName is the name of the theme used when none has been chosen
+const DefaultName = "dracula"
+
+// Element is a part of the UI which can be styled by a theme
+type Element string
+
+const (
+	// StatusBar is the status bar at the bottom of an edit pane
+	StatusBar Element = "statusbar"
+	// Selection is selected text
+	Selection Element = "selection"
+	// CursorLine is the line the cursor is on
+	CursorLine Element = "cursorline"
+	// Gutter is the column of line numbers and signs
+	Gutter Element = "gutter"
+	// SearchMatch is text which matches the current search
+	SearchMatch Element = "search"
+	// Popup is a pane drawn over the edit pane
+	Popup Element = "popup"
+	// ColorColumn is a column highlighted to mark a line length
+	ColorColumn Element = "colorcolumn"
+	// Whitespace is the glyphs which make whitespace visible
+	Whitespace Element = "whitespace"
+	// IndentGuide is the lines drawn to show levels of indentation
+	IndentGuide Element = "indentguide"
+	// MatchParen is a pair of matching brackets
+	MatchParen Element = "matchparen"
+)
+
+// Elements are all of the elements a theme can style
+var Elements = []Element{
+	StatusBar, Selection, CursorLine, Gutter, SearchMatch, Popup,
+	ColorColumn, Whitespace, IndentGuide, MatchParen,
+}
+
+// Theme is a colour scheme
+type Theme struct {
+	Name string
+	// Syntax styles each type of token
+	Syntax *chroma.Style
+	// UI styles the other elements of the editor. Elements which are missing
+	// are derived from Syntax.
+	UI map[Element]chroma.StyleEntry
+}
+
+var (
+	// mu guards the current theme, which the syntax highlighter may use in
+	// the background
+	mu sync.Mutex
+	// themes are the themes loaded from the user's theme files
+	themes = make(map[string]*Theme)
+	// current is the theme in use
+	current = FromChroma(styles.Get(DefaultName))
+	// palette is the colours the terminal can display, or nil if it can
+	// display any colour
+	palette []tcell.Color
+	// version is incremented whenever the current theme changes
+	version int
+	// cache holds converted token styles for the current theme
+	cache = make(map[chroma.TokenType]tcell.Style)
+)
+
+// FromChroma returns a theme built from one of chroma's styles, with the UI
+// elements derived from it
+func FromChroma(style *chroma.Style) *Theme {
+	return &Theme{Name: style.Name, Syntax: style, UI: make(map[Element]chroma.StyleEntry)}
+}
+
+// Register makes t available to Set
+func Register(t *Theme) {
+	mu.Lock()
+	defer mu.Unlock()
+	themes[t.Name] = t
+}
+
+// Names returns the names of the available themes, sorted
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := styles.Names()
+	for name := range themes {
+		if _, ok := styles.Registry[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Current returns the theme in use
+func Current() *Theme {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
+
+// Set switches to the theme called name. Themes loaded from the user's theme
+// files take precedence over chroma's built in styles.
+func Set(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	t, ok := themes[name]
+	if !ok {
+		style, ok := styles.Registry[name]
+		if !ok {
+			return fmt.Errorf("unknown colour scheme: %s", name)
+		}
+		t = FromChroma(style)
+	}
+	use(t)
+	return nil
+}
+
+// SetColors sets the number of colours the terminal can display. Colours
+// are downsampled to the nearest the terminal supports.
+func SetColors(n int) {
+	mu.Lock()
+	defer mu.Unlock()
+	palette = nil
+	if n > 0 && n < 1<<24 {
+		if n > 256 {
+			n = 256
+		}
+		palette = make([]tcell.Color, n)
+		for i := range palette {
+			palette[i] = tcell.Color(i)
+		}
+	}
+	use(current)
+}
+
+// Version returns a number which changes whenever the current theme, or the
+// way its colours are displayed, changes. Anything which caches styles should
+// throw its cache away when it changes.
+func Version() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return version
+}
+
+// use switches to t. mu must be held.
+func use(t *Theme) {
+	current = t
+	cache = make(map[chroma.TokenType]tcell.Style)
+	version++
+}
+
+// Token returns the style tokens of type t are drawn in
+func Token(t chroma.TokenType) tcell.Style {
+	mu.Lock()
+	defer mu.Unlock()
+	if s, ok := cache[t]; ok {
+		return s
+	}
+	s := convert(current.Syntax.Get(t))
+	cache[t] = s
+	return s
+}
+
+// Background returns the style of the background of the text
+func Background() tcell.Style {
+	return Token(chroma.Background)
+}
+
+// UI returns the style element is drawn in
+func UI(element Element) tcell.Style {
+	mu.Lock()
+	defer mu.Unlock()
+	if entry, ok := current.UI[element]; ok {
+		return convert(entry.Inherit(current.Syntax.Get(chroma.Background)))
+	}
+	return convert(derive(current.Syntax, element))
+}
+
+// derive works out a style for element from a syntax style
+func derive(style *chroma.Style, element Element) chroma.StyleEntry {
+	background := style.Get(chroma.Background)
+	highlight := style.Get(chroma.LineHighlight)
+	if !style.Has(chroma.LineHighlight) || highlight.Background == background.Background {
+		highlight.Background = background.Background.Brighten(0.15)
+	}
+	switch element {
+	case StatusBar:
+		return chroma.StyleEntry{Colour: background.Colour, Background: highlight.Background.Brighten(0.1)}
+	case Selection, CursorLine, ColorColumn:
+		return chroma.StyleEntry{Colour: background.Colour, Background: highlight.Background}
+	case Gutter:
+		numbers := style.Get(chroma.LineNumbers)
+		if !numbers.Colour.IsSet() {
+			numbers.Colour = style.Get(chroma.Comment).Colour
+		}
+		return chroma.StyleEntry{Colour: numbers.Colour, Background: background.Background}
+	case MatchParen:
+		return chroma.StyleEntry{Colour: background.Colour, Background: highlight.Background.Brighten(0.2), Bold: chroma.Yes}
+	case Whitespace, IndentGuide:
+		return chroma.StyleEntry{Colour: style.Get(chroma.Comment).Colour, Background: background.Background}
+	case SearchMatch:
+		return chroma.StyleEntry{Colour: background.Background, Background: background.Colour}
+	default:
+		return background
+	}
+}
+
+// convert converts a chroma style entry to a tcell style, downsampling its
+// colours if the terminal doesn't support them
+func convert(se chroma.StyleEntry) tcell.Style {
+	s := tcell.StyleDefault.
+		Background(colour(se.Background)).
+		Foreground(colour(se.Colour))
+	if se.Bold == chroma.Yes {
+		s = s.Bold(true)
+	}
+	if se.Underline == chroma.Yes {
+		s = s.Underline(true)
+	}
+	return s
+}
+
+// colour converts a chroma colour to the nearest colour the terminal can
+// display
+func colour(c chroma.Colour) tcell.Color {
+	if !c.IsSet() {
+		return tcell.ColorDefault
+	}
+	rgb := tcell.NewRGBColor(int32(c.Red()), int32(c.Green()), int32(c.Blue()))
+	if palette == nil {
+		return rgb
+	}
+	return tcell.FindColor(rgb, palette)
+}
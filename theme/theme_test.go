@@ -0,0 +1,130 @@
+package theme
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+	"github.com/gdamore/tcell"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTheme(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "theme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeTheme(t, dir, "midnight.json", `{
+		"name": "midnight",
+		"base": "monokai",
+		"syntax": {"Keyword": "bold #ff0000"},
+		"ui": {"statusbar": "bg:#00ff00 #0000ff"}
+	}`)
+	theme, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "midnight", theme.Name)
+	keyword := theme.Syntax.Get(chroma.Keyword)
+	assert.Equal(t, chroma.Yes, keyword.Bold)
+	assert.Equal(t, chroma.MustParseColour("#ff0000"), keyword.Colour)
+	assert.Equal(t, chroma.MustParseColour("#00ff00"), theme.UI[StatusBar].Background)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "theme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := map[string]string{
+		"noname.json":  `{"syntax": {"Keyword": "#ff0000"}}`,
+		"base.json":    `{"name": "a", "base": "nonexistent"}`,
+		"token.json":   `{"name": "a", "syntax": {"Nonexistent": "#ff0000"}}`,
+		"element.json": `{"name": "a", "ui": {"nonexistent": "#ff0000"}}`,
+		"json.json":    `{"name": `,
+	}
+	for name, content := range tests {
+		_, err := Load(writeTheme(t, dir, name, content))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "theme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A bad file doesn't stop the others loading
+	writeTheme(t, dir, "a.json", `{"name": `)
+	writeTheme(t, dir, "b.json", `{"name": "loaded-from-dir"}`)
+	err = LoadDir(dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "a.json")
+	assert.Contains(t, Names(), "loaded-from-dir")
+
+	assert.NoError(t, LoadDir(filepath.Join(dir, "missing")))
+}
+
+// The remaining tests change the current theme, so can't run in parallel
+
+func TestSet(t *testing.T) {
+	defer Set(DefaultName)
+	before := Version()
+	assert.NoError(t, Set("monokai"))
+	assert.Equal(t, "monokai", Current().Name)
+	assert.NotEqual(t, before, Version())
+
+	assert.Error(t, Set("nonexistent"))
+	assert.Equal(t, "monokai", Current().Name)
+}
+
+func TestSetRegistered(t *testing.T) {
+	defer Set(DefaultName)
+	theme := FromChroma(chroma.MustNewStyle("registered", chroma.StyleEntries{
+		chroma.Background: "bg:#000000 #ffffff",
+	}))
+	theme.UI[Gutter] = chroma.StyleEntry{Colour: chroma.MustParseColour("#ff0000")}
+	Register(theme)
+	assert.Contains(t, Names(), "registered")
+
+	assert.NoError(t, Set("registered"))
+	fg, _, _ := Token(chroma.Keyword).Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0xff, 0xff, 0xff), fg)
+	fg, bg, _ := UI(Gutter).Decompose()
+	assert.Equal(t, tcell.NewRGBColor(0xff, 0, 0), fg)
+	assert.Equal(t, tcell.NewRGBColor(0, 0, 0), bg)
+}
+
+func TestSetColors(t *testing.T) {
+	defer SetColors(1 << 24)
+	SetColors(16)
+	for _, tt := range []chroma.TokenType{chroma.Keyword, chroma.Comment, chroma.Background} {
+		fg, bg, _ := Token(tt).Decompose()
+		for _, c := range []tcell.Color{fg, bg} {
+			if c != tcell.ColorDefault {
+				assert.True(t, c < 16, "%v isn't one of the first 16 colours", c)
+			}
+		}
+	}
+
+	SetColors(1 << 24)
+	fg, _, _ := Token(chroma.Keyword).Decompose()
+	assert.True(t, fg&tcell.ColorIsRGB != 0)
+}